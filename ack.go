@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// An Ack settles a message handled by HandleWithAck, which the Processor does not delete itself.
+// Messages that are never acked become visible again after their visibility timeout.
+type Ack struct {
+	queue   *Queue
+	message *sqs.Message
+}
+
+// Message returns the acknowledged message.
+func (ack *Ack) Message() *sqs.Message {
+	return ack.message
+}
+
+// Ack deletes the message from the queue.
+// After the visibility timeout the receipt handle may be expired, the AWS error is returned then, see IsReceiptHandleInvalid.
+func (ack *Ack) Ack() error {
+	_, err := ack.queue.DeleteMessageContext(aws.BackgroundContext(), ack.message)
+
+	return err
+}
+
+// Nack makes the message visible again immediately.
+func (ack *Ack) Nack() error {
+	return ack.NackWithDelay(0)
+}
+
+// NackWithDelay makes the message visible again after the delay, up to 12 hours.
+func (ack *Ack) NackWithDelay(delay time.Duration) error {
+	return ack.queue.ChangeMessageVisibilityContext(aws.BackgroundContext(), ack.message, delay)
+}
@@ -0,0 +1,136 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestHandleWithAckNackRedelivers(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "nacked", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("retried")
+
+	calls := 0
+	processor := &queue.Processor{
+		Queue: q,
+		HandleWithAck: func(ctx context.Context, body interface{}, ack *queue.Ack) error {
+			calls++
+			if calls == 1 {
+				if remaining := len(client.Messages(q.URL)); remaining != 1 {
+					t.Errorf("expected the message to stay in the queue before the nack, got %d", remaining)
+				}
+				return ack.Nack()
+			}
+			return ack.Ack()
+		},
+	}
+	// The message is only received again because the nack made it visible before the 30 seconds visibility timeout.
+	summary, err := processor.ProcessN(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 || summary.Processed != 2 {
+		t.Errorf("expected the nacked message to be handled again, got %d calls and %+v", calls, summary)
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 0 {
+		t.Errorf("expected the acked message to be deleted, got %d messages", remaining)
+	}
+}
+
+func TestNackWithDelayChangesVisibility(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("delayed", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("delayed")
+
+	processor := &queue.Processor{
+		Queue: q,
+		HandleWithAck: func(ctx context.Context, body interface{}, ack *queue.Ack) error {
+			return ack.NackWithDelay(time.Minute)
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := visibilityChanges(client)
+	if len(changes) != 1 || aws.Int64Value(changes[0].VisibilityTimeout) != 60 {
+		t.Fatalf("expected the visibility timeout to be changed to 60 seconds, got %v", changes)
+	}
+	if len(client.deleteInputs) != 0 || len(client.deleteBatchInputs) != 0 {
+		t.Error("expected the nacked message not to be deleted")
+	}
+}
+
+func TestHandleWithAckDoubleAck(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "acked", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("acked")
+	q.SendMessage("kept")
+
+	var first, second error
+	processor := &queue.Processor{
+		Queue: q,
+		HandleWithAck: func(ctx context.Context, body interface{}, ack *queue.Ack) error {
+			first = ack.Ack()
+			second = ack.Ack()
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if first != nil {
+		t.Fatal(first)
+	}
+	if !queue.IsReceiptHandleInvalid(second) {
+		t.Errorf("expected the second ack to fail with an invalid receipt handle, got %v", second)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 1 || remaining[0] != `"kept"` {
+		t.Errorf("expected only the acked message to be deleted, got %v", remaining)
+	}
+}
+
+func TestHandleWithAckWithoutAcking(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "unacked", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("unacked")
+
+	calls := 0
+	processor := &queue.Processor{
+		Queue: q,
+		HandleWithAck: func(ctx context.Context, body interface{}, ack *queue.Ack) error {
+			calls++
+			return nil
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 2 || calls != 2 {
+		t.Errorf("expected the unacked message to be redelivered after the visibility timeout, got %d calls and %+v", calls, summary)
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 1 {
+		t.Errorf("expected the unacked message to stay in the queue, got %d messages", remaining)
+	}
+}
@@ -0,0 +1,215 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// An Archiver keeps a copy of each message handled by a Processor, e.g. for audit.
+// It is called from the workers concurrently, implementations have to be threadsafe.
+type Archiver interface {
+	Archive(ctx context.Context, message *sqs.Message, outcome Outcome) error
+}
+
+// ArchiveMetrics is implemented by Metrics also counting the archiving failures of the Archiver of a Processor.
+type ArchiveMetrics interface {
+	// ArchiveFailed is called with the error of each message that could not be archived.
+	ArchiveFailed(err error)
+}
+
+// An Outcome is the result of handling a message.
+type Outcome struct {
+	// Err is the error of the handler, nil when the message was handled successfully.
+	Err        error
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+// Succeeded reports whether the message was handled successfully.
+func (outcome Outcome) Succeeded() bool {
+	return outcome.Err == nil
+}
+
+// An ArchiveRecord is the JSON envelope of an archived message, written by WriterArchiver and S3Archiver.
+type ArchiveRecord struct {
+	MessageID    string                                `json:"messageId"`
+	Queue        string                                `json:"queue,omitempty"`
+	Body         string                                `json:"body"`
+	Attributes   map[string]*sqs.MessageAttributeValue `json:"attributes,omitempty"`
+	ReceiveCount int                                   `json:"receiveCount,omitempty"`
+	Outcome      string                                `json:"outcome"`
+	Error        string                                `json:"error,omitempty"`
+	SentAt       *time.Time                            `json:"sentAt,omitempty"`
+	StartedAt    time.Time                             `json:"startedAt"`
+	FinishedAt   time.Time                             `json:"finishedAt"`
+	ArchivedAt   time.Time                             `json:"archivedAt"`
+}
+
+// Outcomes of an ArchiveRecord.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+)
+
+// NewArchiveRecord returns the envelope of the message handled within the context with the outcome.
+func NewArchiveRecord(ctx context.Context, message *sqs.Message, outcome Outcome) ArchiveRecord {
+	record := ArchiveRecord{
+		MessageID:  aws.StringValue(message.MessageId),
+		Body:       aws.StringValue(message.Body),
+		Attributes: message.MessageAttributes,
+		Outcome:    OutcomeSucceeded,
+		StartedAt:  outcome.StartedAt,
+		FinishedAt: outcome.FinishedAt,
+		ArchivedAt: time.Now(),
+	}
+	if queue, ok := QueueFromContext(ctx); ok {
+		record.Queue = queue.Name
+	}
+	record.ReceiveCount, _ = ReceiveCount(message)
+	if sentAt, ok := SentTime(message); ok {
+		record.SentAt = &sentAt
+	}
+	if !outcome.Succeeded() {
+		record.Outcome = OutcomeFailed
+		record.Error = outcome.Err.Error()
+	}
+
+	return record
+}
+
+// A WriterArchiver is an Archiver writing the ArchiveRecord of each message as a JSON line to the writer.
+type WriterArchiver struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewWriterArchiver returns an Archiver writing newline delimited JSON to the writer, e.g. a file.
+func NewWriterArchiver(writer io.Writer) *WriterArchiver {
+	return &WriterArchiver{writer: writer}
+}
+
+// Archive writes the ArchiveRecord of the message as a JSON line.
+func (archiver *WriterArchiver) Archive(ctx context.Context, message *sqs.Message, outcome Outcome) error {
+	line, err := json.Marshal(NewArchiveRecord(ctx, message, outcome))
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	_, err = archiver.writer.Write(line)
+
+	return err
+}
+
+// An S3Archiver is an Archiver writing the ArchiveRecord of each handling attempt to S3, partitioned by the handling date in UTC,
+// to prefix/{queue-name}/{yyyy}/{mm}/{dd}/{message-id}/{hhmmss.nanoseconds}-{outcome}.json of the end of the handling,
+// so the retries don't overwrite the failed attempts.
+type S3Archiver struct {
+	client s3iface.S3API
+	bucket string
+	prefix string
+}
+
+// NewS3Archiver returns an Archiver writing the messages to the bucket under the prefix.
+func NewS3Archiver(client s3iface.S3API, bucket, prefix string) *S3Archiver {
+	return &S3Archiver{client: client, bucket: bucket, prefix: prefix}
+}
+
+// Archive writes the ArchiveRecord of the message to S3.
+func (archiver *S3Archiver) Archive(ctx context.Context, message *sqs.Message, outcome Outcome) error {
+	record := NewArchiveRecord(ctx, message, outcome)
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	finishedAt := record.FinishedAt.UTC()
+	attempt := finishedAt.Format("150405.000000000") + "-" + record.Outcome + ".json"
+	key := path.Join(archiver.prefix, record.Queue, finishedAt.Format("2006/01/02"), record.MessageID, attempt)
+	_, err = archiver.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(archiver.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(append(recordJSON, '\n')),
+		ContentType: aws.String("application/json"),
+	})
+
+	return err
+}
+
+// archive passes the handled message to the Archiver of the processor and returns the archiving error.
+// Offloaded bodies are fetched from S3, so the archived message holds the body and not the pointer to it.
+func (processor *Processor) archive(ctx context.Context, message *sqs.Message, outcome Outcome) (err error) {
+	if processor.Archiver == nil {
+		return nil
+	}
+
+	archived, err := processor.archivedMessage(ctx, message)
+	if err == nil {
+		err = processor.Archiver.Archive(ctx, archived, outcome)
+	}
+	if err == nil {
+		return nil
+	}
+	if metrics, ok := processor.getMetrics().(ArchiveMetrics); ok {
+		metrics.ArchiveFailed(err)
+	}
+	processor.getLogger().Warn("Error archiving message", Fields{
+		"error":     err,
+		"messageID": message.MessageId,
+		"queueName": processor.Queue.Name,
+		"strict":    processor.StrictArchiving,
+	})
+
+	return err
+}
+
+// archivedMessage returns the message with the body fetched from S3 when it was offloaded, the message itself otherwise.
+// Encrypted bodies are archived as they are.
+func (processor *Processor) archivedMessage(ctx context.Context, message *sqs.Message) (*sqs.Message, error) {
+	if _, ok := message.MessageAttributes[LargePayloadSizeAttribute]; !ok {
+		return message, nil
+	}
+
+	body, err := processor.Queue.fetchMessageBody(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	archived := *message
+	archived.Body = aws.String(body)
+	archived.MessageAttributes = make(map[string]*sqs.MessageAttributeValue, len(message.MessageAttributes))
+	for name, value := range message.MessageAttributes {
+		if name != LargePayloadSizeAttribute {
+			archived.MessageAttributes[name] = value
+		}
+	}
+
+	return &archived, nil
+}
+
+// archiveBatch archives the messages of a handled batch with the outcome of each. It returns the succeeded messages
+// to delete, without the ones that could not be archived with StrictArchiving.
+func (processor *Processor) archiveBatch(ctx context.Context, messages []*sqs.Message, outcome func(message *sqs.Message) Outcome) (deletable []*sqs.Message) {
+	ctx = context.WithValue(ctx, queueContextKey{}, processor.Queue)
+	for _, message := range messages {
+		messageOutcome := outcome(message)
+		err := processor.archive(contextWithMessage(ctx, message), message, messageOutcome)
+		if messageOutcome.Succeeded() && (err == nil || !processor.StrictArchiving) {
+			deletable = append(deletable, message)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,270 @@
+package queue_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// failingArchiver is an Archiver failing every call, counting them.
+type failingArchiver struct {
+	mutex sync.Mutex
+	calls int
+}
+
+// Archive counts the call and fails.
+func (archiver *failingArchiver) Archive(ctx context.Context, message *sqs.Message, outcome queue.Outcome) error {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	archiver.calls++
+
+	return errors.New("archive unavailable")
+}
+
+// archiveTestProcessor returns a processor of the queue failing the messages with a "fail" body.
+func archiveTestProcessor(q *queue.Queue, archiver queue.Archiver, metrics queue.Metrics) *queue.Processor {
+	return &queue.Processor{
+		Queue:    q,
+		Archiver: archiver,
+		Metrics:  metrics,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			if body == "fail" {
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+	}
+}
+
+// readArchiveRecords returns the JSON lines of the writer archiver.
+func readArchiveRecords(t *testing.T, buffer *bytes.Buffer) (records []queue.ArchiveRecord) {
+	t.Helper()
+
+	scanner := bufio.NewScanner(buffer)
+	for scanner.Scan() {
+		var record queue.ArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid JSON line %s: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+
+	return
+}
+
+func TestWriterArchiverEnvelope(t *testing.T) {
+	q, err := memqueue.New("applications", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, _ := q.SendMessage("ok")
+	failed, _ := q.SendMessage("fail")
+
+	var buffer bytes.Buffer
+	before := time.Now()
+	if _, err := archiveTestProcessor(q, queue.NewWriterArchiver(&buffer), nil).ProcessN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readArchiveRecords(t, &buffer)
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	byID := map[string]queue.ArchiveRecord{}
+	for _, record := range records {
+		byID[record.MessageID] = record
+		if record.Queue != "applications" {
+			t.Errorf("expected the queue name, got %q", record.Queue)
+		}
+		if record.SentAt == nil || record.StartedAt.Before(before) || record.FinishedAt.Before(record.StartedAt) || record.ArchivedAt.Before(record.FinishedAt) {
+			t.Errorf("unexpected timestamps %+v", record)
+		}
+		if record.ReceiveCount != 1 {
+			t.Errorf("expected the receive count, got %d", record.ReceiveCount)
+		}
+	}
+	if record := byID[*ok.MessageId]; record.Body != `"ok"` || record.Outcome != queue.OutcomeSucceeded || record.Error != "" {
+		t.Errorf("unexpected record of the handled message %+v", record)
+	}
+	if record := byID[*failed.MessageId]; record.Body != `"fail"` || record.Outcome != queue.OutcomeFailed || record.Error != "handler failed" {
+		t.Errorf("unexpected record of the failed message %+v", record)
+	}
+}
+
+func TestArchiveFailureDeletesByDefault(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "applications", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("ok")
+
+	archiver := &failingArchiver{}
+	metrics := &queue.InMemoryMetrics{}
+	summary, err := archiveTestProcessor(q, archiver, metrics).ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 1 || archiver.calls != 1 {
+		t.Errorf("expected the message to be handled and archived, got %+v and %d calls", summary, archiver.calls)
+	}
+	if counts := metrics.Counts(); counts.ArchiveFailures != 1 || counts.Deleted != 1 {
+		t.Errorf("expected an archive failure and the message deleted, got %+v", counts)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 0 {
+		t.Errorf("expected the message to be deleted, %d left", len(bodies))
+	}
+}
+
+func TestStrictArchivingKeepsMessage(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "applications", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("ok")
+
+	archiver := &failingArchiver{}
+	metrics := &queue.InMemoryMetrics{}
+	processor := archiveTestProcessor(q, archiver, metrics)
+	processor.StrictArchiving = true
+	processor.WithDeduplication(10, time.Minute)
+	if _, err := processor.ProcessN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if archiver.calls != 2 {
+		t.Errorf("expected the redelivered message to be archived again, got %d calls", archiver.calls)
+	}
+	if counts := metrics.Counts(); counts.ArchiveFailures != 2 || counts.Deleted != 0 || counts.DuplicatesSkipped != 0 {
+		t.Errorf("expected 2 archive failures without deletes and skips, got %+v", counts)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 1 {
+		t.Errorf("expected the message to be kept, %d left", len(bodies))
+	}
+}
+
+func TestS3ArchiverKeysPerAttempt(t *testing.T) {
+	q, err := memqueue.New("applications", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, _ := q.SendMessage("fail")
+
+	s3Client := newMemoryS3()
+	if _, err := archiveTestProcessor(q, queue.NewS3Archiver(s3Client, "audit", "sqs"), nil).ProcessN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := s3Client.keys()
+	if len(keys) != 2 {
+		t.Fatalf("expected a record per attempt, got %v", keys)
+	}
+	prefix := "audit/sqs/applications/" + time.Now().UTC().Format("2006/01/02") + "/" + *sent.MessageId + "/"
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || !strings.HasSuffix(key, "-failed.json") {
+			t.Errorf("unexpected key %s, expected it under %s", key, prefix)
+		}
+		var record queue.ArchiveRecord
+		if err := json.Unmarshal(s3Client.object(key), &record); err != nil || record.Body != `"fail"` {
+			t.Errorf("unexpected record %s: %v", s3Client.object(key), err)
+		}
+	}
+}
+
+func TestArchiveOffloadedBody(t *testing.T) {
+	s3Client := newMemoryS3()
+	q, err := memqueue.New("applications", queue.WithReceiveWaitTime(0), queue.WithLargePayloads(queue.LargePayloadConfig{
+		S3Client:  s3Client,
+		Bucket:    "payloads",
+		Threshold: 10,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := strings.Repeat("a", 100)
+	q.SendMessage(body)
+
+	var buffer bytes.Buffer
+	if _, err := archiveTestProcessor(q, queue.NewWriterArchiver(&buffer), nil).ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	records := readArchiveRecords(t, &buffer)
+	if len(records) != 1 {
+		t.Fatalf("expected a record, got %d", len(records))
+	}
+	if records[0].Body != `"`+body+`"` {
+		t.Errorf("expected the offloaded body, got %s", records[0].Body)
+	}
+	if _, ok := records[0].Attributes[queue.LargePayloadSizeAttribute]; ok {
+		t.Error("expected the record without the offloading attribute")
+	}
+}
+
+func TestArchiveBatchWindow(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "applications", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{"first", "second"})
+
+	var buffer bytes.Buffer
+	ctx, cancel := context.WithCancel(context.Background())
+	processor := &queue.Processor{
+		Queue:    q,
+		Archiver: queue.NewWriterArchiver(&buffer),
+		HandleBatch: func(ctx context.Context, processor *queue.Processor, messages []*sqs.Message) error {
+			cancel()
+			return nil
+		},
+	}
+	processor.WithBatchWindow(2, 10*time.Millisecond)
+	processor.ProcessWithContext(ctx, nil)
+
+	if records := readArchiveRecords(t, &buffer); len(records) != 2 {
+		t.Errorf("expected a record per message of the batch, got %d", len(records))
+	}
+}
+
+func TestArchiveDecodedBatchStrict(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "applications", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{"first", "second"})
+
+	archiver := &failingArchiver{}
+	ctx, cancel := context.WithCancel(context.Background())
+	processor := &queue.Processor{
+		Queue:           q,
+		Archiver:        archiver,
+		StrictArchiving: true,
+		HandleDecodedBatch: func(ctx context.Context, messages []queue.DecodedMessage) ([]queue.Failed, error) {
+			cancel()
+			return nil, nil
+		},
+	}
+	processor.ProcessWithContext(ctx, nil)
+
+	if archiver.calls != 2 {
+		t.Errorf("expected both messages to be archived, got %d calls", archiver.calls)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 2 {
+		t.Errorf("expected the messages to be kept, %d left", len(bodies))
+	}
+}
@@ -0,0 +1,235 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MaxDelaySeconds is the maximum delay SQS allows for a queue or a message.
+const MaxDelaySeconds = 900
+
+// ErrNotFIFOQueue is returned for FIFO operations on a standard queue.
+var ErrNotFIFOQueue = errors.New("queue is not a FIFO queue")
+
+// A QueueType is the type of an SQS queue.
+type QueueType string
+
+// The SQS queue types.
+const (
+	QueueTypeStandard QueueType = "standard"
+	QueueTypeFIFO     QueueType = "fifo"
+)
+
+// ErrInvalidDelaySeconds is returned when the delay is outside the SQS range of 0 to 900 seconds.
+var ErrInvalidDelaySeconds = errors.New("delay seconds must be between 0 and 900")
+
+// GetDelaySeconds returns the DelaySeconds attribute of the queue.
+func (queue *Queue) GetDelaySeconds() (int64, error) {
+	return queue.getInt64Attribute(sqs.QueueAttributeNameDelaySeconds)
+}
+
+// SetDelaySeconds sets the DelaySeconds attribute of the queue.
+func (queue *Queue) SetDelaySeconds(seconds int64) error {
+	if seconds < 0 || seconds > MaxDelaySeconds {
+		return ErrInvalidDelaySeconds
+	}
+
+	return queue.setAttributesByQueueURL(aws.BackgroundContext(), queue.URL, map[string]*string{
+		sqs.QueueAttributeNameDelaySeconds: aws.String(strconv.FormatInt(seconds, 10)),
+	})
+}
+
+// GetQueueType returns whether the queue is a standard or a FIFO queue.
+func (queue *Queue) GetQueueType() (QueueType, error) {
+	resp, err := queue.GetAttributesByQueueURL(queue.URL, []*string{aws.String(sqs.QueueAttributeNameFifoQueue)})
+	if err != nil {
+		return "", err
+	}
+
+	if aws.StringValue(resp.Attributes[sqs.QueueAttributeNameFifoQueue]) == "true" {
+		return QueueTypeFIFO, nil
+	}
+
+	return QueueTypeStandard, nil
+}
+
+// SetHighThroughputMode enables or disables the high throughput mode of an existing FIFO queue.
+// ErrNotFIFOQueue is returned for standard queues.
+func (queue *Queue) SetHighThroughputMode(enabled bool) error {
+	queueType, err := queue.GetQueueType()
+	if err != nil {
+		return err
+	}
+	if queueType != QueueTypeFIFO {
+		return ErrNotFIFOQueue
+	}
+
+	attributes := map[string]*string{
+		queueAttributeNameDeduplicationScope:  aws.String(deduplicationScopeQueue),
+		queueAttributeNameFifoThroughputLimit: aws.String(fifoThroughputLimitPerQueue),
+	}
+	if enabled {
+		attributes[queueAttributeNameDeduplicationScope] = aws.String(deduplicationScopeMessageGroup)
+		attributes[queueAttributeNameFifoThroughputLimit] = aws.String(fifoThroughputLimitPerMessageGroupID)
+	}
+
+	return queue.setAttributesByQueueURL(aws.BackgroundContext(), queue.URL, attributes)
+}
+
+// QueueStats holds the approximate message counts of a queue.
+type QueueStats struct {
+	ApproximateNumberOfMessages           int64
+	ApproximateNumberOfMessagesNotVisible int64
+	ApproximateNumberOfMessagesDelayed    int64
+	// DeadLetterApproximateNumberOfMessages is the visible count of the dead letter queue, 0 without one.
+	DeadLetterApproximateNumberOfMessages int64
+}
+
+// ARN returns the ARN of the queue.
+func (queue *Queue) ARN() (string, error) {
+	resp, err := queue.GetAttributesByQueueURL(queue.URL, []*string{aws.String(sqs.QueueAttributeNameQueueArn)})
+	if err != nil {
+		return "", err
+	}
+
+	arn := aws.StringValue(resp.Attributes[sqs.QueueAttributeNameQueueArn])
+	if arn == "" {
+		return "", fmt.Errorf("queue attribute %s is missing", sqs.QueueAttributeNameQueueArn)
+	}
+
+	return arn, nil
+}
+
+// Stats returns the approximate message counts of the queue and it's dead letter queue.
+func (queue *Queue) Stats() (stats QueueStats, err error) {
+	return queue.StatsContext(aws.BackgroundContext())
+}
+
+// StatsContext returns the approximate message counts of the queue and it's dead letter queue within the context.
+func (queue *Queue) StatsContext(ctx context.Context) (stats QueueStats, err error) {
+	resp, err := queue.getAttributesByQueueURL(ctx, queue.URL, aws.StringSlice([]string{
+		sqs.QueueAttributeNameApproximateNumberOfMessages,
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible,
+		sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed,
+	}))
+	if err != nil {
+		return
+	}
+
+	if stats.ApproximateNumberOfMessages, err = parseInt64Attribute(resp.Attributes, sqs.QueueAttributeNameApproximateNumberOfMessages); err != nil {
+		return
+	}
+	if stats.ApproximateNumberOfMessagesNotVisible, err = parseInt64Attribute(resp.Attributes, sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible); err != nil {
+		return
+	}
+	if stats.ApproximateNumberOfMessagesDelayed, err = parseInt64Attribute(resp.Attributes, sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed); err != nil {
+		return
+	}
+
+	if queue.DeadLetterQueueURL == "" {
+		return
+	}
+	resp, err = queue.getAttributesByQueueURL(ctx, queue.DeadLetterQueueURL, []*string{aws.String(sqs.QueueAttributeNameApproximateNumberOfMessages)})
+	if err != nil {
+		return
+	}
+	stats.DeadLetterApproximateNumberOfMessages, err = parseInt64Attribute(resp.Attributes, sqs.QueueAttributeNameApproximateNumberOfMessages)
+
+	return
+}
+
+// SetAttributes sets attributes of the queue.
+func (queue *Queue) SetAttributes(attributes map[string]string) error {
+	return queue.SetAttributesContext(aws.BackgroundContext(), attributes)
+}
+
+// SetAttributesContext sets attributes of the queue within the context.
+func (queue *Queue) SetAttributesContext(ctx context.Context, attributes map[string]string) error {
+	return queue.setAttributesByQueueURL(ctx, queue.URL, aws.StringMap(attributes))
+}
+
+// SetRedrivePolicy sets the redrive policy of the queue, e.g. to attach a dead letter queue to an existing queue.
+func (queue *Queue) SetRedrivePolicy(policy RedrivePolicy) error {
+	policyString, err := policy.GetAsAWSString()
+	if err != nil {
+		return err
+	}
+
+	return queue.setAttributesByQueueURL(aws.BackgroundContext(), queue.URL, map[string]*string{
+		sqs.QueueAttributeNameRedrivePolicy: policyString,
+	})
+}
+
+// GetRedrivePolicy returns the redrive policy of the queue, nil when it has none.
+func (queue *Queue) GetRedrivePolicy() (*RedrivePolicy, error) {
+	resp, err := queue.GetAttributesByQueueURL(queue.URL, []*string{aws.String(sqs.QueueAttributeNameRedrivePolicy)})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseRedrivePolicy(aws.StringValue(resp.Attributes[sqs.QueueAttributeNameRedrivePolicy]))
+}
+
+// parseRedrivePolicy decodes the RedrivePolicy attribute, returning nil for a missing or empty policy.
+func parseRedrivePolicy(attribute string) (*RedrivePolicy, error) {
+	if attribute == "" {
+		return nil, nil
+	}
+
+	policy := new(RedrivePolicy)
+	if err := json.Unmarshal([]byte(attribute), policy); err != nil {
+		return nil, fmt.Errorf("parsing queue attribute %s: %w", sqs.QueueAttributeNameRedrivePolicy, err)
+	}
+
+	return policy, nil
+}
+
+// getInt64Attribute returns a numeric attribute of the queue.
+func (queue *Queue) getInt64Attribute(name string) (value int64, err error) {
+	resp, err := queue.GetAttributesByQueueURL(queue.URL, []*string{aws.String(name)})
+	if err != nil {
+		return
+	}
+
+	return parseInt64Attribute(resp.Attributes, name)
+}
+
+// parseInt64Attribute parses a numeric attribute, the error names the attribute.
+func parseInt64Attribute(attributes map[string]*string, name string) (value int64, err error) {
+	attribute, ok := attributes[name]
+	if !ok || attribute == nil {
+		return 0, fmt.Errorf("queue attribute %s is missing", name)
+	}
+	value, err = strconv.ParseInt(*attribute, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parsing queue attribute %s: %w", name, err)
+	}
+
+	return
+}
+
+// setAttributesByQueueURL sets queue attributes by it's URL within the context.
+func (queue *Queue) setAttributesByQueueURL(ctx context.Context, url string, attributes map[string]*string) (err error) {
+	client := queue.GetClient()
+	params := &sqs.SetQueueAttributesInput{
+		QueueUrl:   aws.String(url),
+		Attributes: attributes,
+	}
+	_, err = client.SetQueueAttributesWithContext(ctx, params)
+
+	if err != nil {
+		queue.GetLogger().Error("Setting queue attributes", Fields{
+			"queueName": queue.Name,
+			"queueUrl":  url,
+			"error":     err,
+		})
+	}
+
+	return
+}
@@ -0,0 +1,236 @@
+package queue_test
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// setAttributesClient records the inputs of setting the queue attributes.
+type setAttributesClient struct {
+	*memqueue.Client
+	inputs []*sqs.SetQueueAttributesInput
+}
+
+func (client *setAttributesClient) SetQueueAttributesWithContext(ctx aws.Context, input *sqs.SetQueueAttributesInput, opts ...request.Option) (*sqs.SetQueueAttributesOutput, error) {
+	client.inputs = append(client.inputs, input)
+	return client.Client.SetQueueAttributesWithContext(ctx, input, opts...)
+}
+
+func TestARN(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("described", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.attributes = map[string]map[string]string{
+		q.URL: {"QueueArn": "arn:aws:sqs:eu-central-1:123456789012:described"},
+	}
+
+	arn, err := q.ARN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arn != "arn:aws:sqs:eu-central-1:123456789012:described" {
+		t.Errorf("expected the ARN of the response, got %s", arn)
+	}
+
+	client.attributes[q.URL] = map[string]string{}
+	if _, err := q.ARN(); err == nil || !strings.Contains(err.Error(), "QueueArn") {
+		t.Errorf("expected an error naming the missing attribute, got %v", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("described", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.attributes = map[string]map[string]string{
+		q.URL: {
+			"ApproximateNumberOfMessages":           "12",
+			"ApproximateNumberOfMessagesNotVisible": "3",
+			"ApproximateNumberOfMessagesDelayed":    "1",
+		},
+		q.DeadLetterQueueURL: {"ApproximateNumberOfMessages": "7"},
+	}
+
+	stats, err := q.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := queue.QueueStats{
+		ApproximateNumberOfMessages:           12,
+		ApproximateNumberOfMessagesNotVisible: 3,
+		ApproximateNumberOfMessagesDelayed:    1,
+		DeadLetterApproximateNumberOfMessages: 7,
+	}
+	if stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestStatsInvalidAttribute(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("described", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.attributes = map[string]map[string]string{
+		q.URL: {
+			"ApproximateNumberOfMessages":           "12",
+			"ApproximateNumberOfMessagesNotVisible": "many",
+			"ApproximateNumberOfMessagesDelayed":    "1",
+		},
+	}
+
+	if _, err := q.Stats(); err == nil || !strings.Contains(err.Error(), "ApproximateNumberOfMessagesNotVisible") {
+		t.Errorf("expected an error naming the attribute, got %v", err)
+	}
+}
+
+func TestSetRedrivePolicy(t *testing.T) {
+	q, err := memqueue.New("redriven", queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy, err := q.GetRedrivePolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy != nil {
+		t.Fatalf("expected no redrive policy, got %+v", policy)
+	}
+
+	expected := queue.RedrivePolicy{MaxReceiveCount: 10, DeadLetterTargetArn: "arn:aws:sqs:eu-central-1:000000000000:shared-dlq"}
+	if err := q.SetRedrivePolicy(expected); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = q.GetRedrivePolicy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy == nil || *policy != expected {
+		t.Errorf("expected %+v, got %+v", expected, policy)
+	}
+}
+
+func TestGetRedrivePolicyEmptyAndInvalid(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("redriven", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client.attributes = map[string]map[string]string{q.URL: {"RedrivePolicy": ""}}
+	if policy, err := q.GetRedrivePolicy(); err != nil || policy != nil {
+		t.Errorf("expected no redrive policy for an empty attribute, got %+v %v", policy, err)
+	}
+	client.attributes[q.URL] = map[string]string{}
+	if policy, err := q.GetRedrivePolicy(); err != nil || policy != nil {
+		t.Errorf("expected no redrive policy for a missing attribute, got %+v %v", policy, err)
+	}
+	client.attributes[q.URL] = map[string]string{"RedrivePolicy": "{"}
+	if _, err := q.GetRedrivePolicy(); err == nil || !strings.Contains(err.Error(), "RedrivePolicy") {
+		t.Errorf("expected an error naming the attribute, got %v", err)
+	}
+}
+
+func TestSetAttributes(t *testing.T) {
+	q, err := memqueue.New("configured")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.SetAttributes(map[string]string{"DelaySeconds": "30"}); err != nil {
+		t.Fatal(err)
+	}
+	delay, err := q.GetDelaySeconds()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if delay != 30 {
+		t.Errorf("expected the delay to be set, got %d", delay)
+	}
+}
+
+func TestSetHighThroughputMode(t *testing.T) {
+	client := &setAttributesClient{Client: memqueue.NewClient()}
+	q, err := queue.New("orders", queue.WithClient(client), queue.WithFIFO(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[bool]map[string]string{
+		true:  {"DeduplicationScope": "messageGroup", "FifoThroughputLimit": "perMessageGroupId"},
+		false: {"DeduplicationScope": "queue", "FifoThroughputLimit": "perQueue"},
+	}
+	for _, enabled := range []bool{true, false} {
+		client.inputs = nil
+		if err := q.SetHighThroughputMode(enabled); err != nil {
+			t.Fatal(err)
+		}
+		if len(client.inputs) != 1 || aws.StringValue(client.inputs[0].QueueUrl) != q.URL {
+			t.Fatalf("expected the attributes of the queue to be set, got %v", client.inputs)
+		}
+		if attributes := aws.StringValueMap(client.inputs[0].Attributes); !reflect.DeepEqual(attributes, expected[enabled]) {
+			t.Errorf("expected %v when enabled is %t, got %v", expected[enabled], enabled, attributes)
+		}
+	}
+}
+
+func TestSetHighThroughputModeOfStandardQueue(t *testing.T) {
+	client := &setAttributesClient{Client: memqueue.NewClient()}
+	q, err := queue.New("orders", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.SetHighThroughputMode(true); err != queue.ErrNotFIFOQueue {
+		t.Errorf("expected ErrNotFIFOQueue, got %v", err)
+	}
+	if len(client.inputs) != 0 {
+		t.Errorf("expected no attributes to be set, got %v", client.inputs)
+	}
+}
+
+func TestSetDelaySeconds(t *testing.T) {
+	client := &setAttributesClient{Client: memqueue.NewClient()}
+	q, err := queue.New("delayed", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, seconds := range []int64{0, 900} {
+		client.inputs = nil
+		if err := q.SetDelaySeconds(seconds); err != nil {
+			t.Fatal(err)
+		}
+		if len(client.inputs) != 1 || aws.StringValue(client.inputs[0].QueueUrl) != q.URL {
+			t.Fatalf("expected the attributes of the queue to be set, got %v", client.inputs)
+		}
+		expected := map[string]string{"DelaySeconds": strconv.FormatInt(seconds, 10)}
+		if attributes := aws.StringValueMap(client.inputs[0].Attributes); !reflect.DeepEqual(attributes, expected) {
+			t.Errorf("expected %v, got %v", expected, attributes)
+		}
+	}
+
+	client.inputs = nil
+	for _, seconds := range []int64{-1, 901} {
+		if err := q.SetDelaySeconds(seconds); err != queue.ErrInvalidDelaySeconds {
+			t.Errorf("expected ErrInvalidDelaySeconds for %d, got %v", seconds, err)
+		}
+	}
+	if len(client.inputs) != 0 {
+		t.Errorf("expected no attributes to be set for an invalid delay, got %v", client.inputs)
+	}
+}
@@ -0,0 +1,106 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// AWSTraceHeaderAttribute is the message system attribute holding the X-Ray trace header of the producer.
+const AWSTraceHeaderAttribute = "AWSTraceHeader"
+
+// ErrInvalidTraceHeader is returned for an X-Ray trace header without a Root.
+var ErrInvalidTraceHeader = errors.New("invalid X-Ray trace header")
+
+// A TraceHeader is a parsed X-Ray trace header, e.g. Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1.
+type TraceHeader struct {
+	Root    string
+	Parent  string
+	Sampled string
+}
+
+// String returns the header in the X-Ray format.
+func (header TraceHeader) String() string {
+	s := "Root=" + header.Root
+	if header.Parent != "" {
+		s += ";Parent=" + header.Parent
+	}
+	if header.Sampled != "" {
+		s += ";Sampled=" + header.Sampled
+	}
+
+	return s
+}
+
+// ParseTraceHeader parses an X-Ray trace header, the unknown fields are ignored.
+func ParseTraceHeader(s string) (header TraceHeader, err error) {
+	for _, field := range strings.Split(s, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "Root":
+			header.Root = value
+		case "Parent":
+			header.Parent = value
+		case "Sampled":
+			header.Sampled = value
+		}
+	}
+	if header.Root == "" {
+		return header, ErrInvalidTraceHeader
+	}
+
+	return header, nil
+}
+
+// AWSTraceHeader returns the X-Ray trace header the message was sent with, e.g. as the parent of a handler subsegment.
+// It reports false when the message has no valid trace header.
+func AWSTraceHeader(message *sqs.Message) (header TraceHeader, ok bool) {
+	header, err := ParseTraceHeader(aws.StringValue(message.Attributes[AWSTraceHeaderAttribute]))
+
+	return header, err == nil
+}
+
+// WithAWSTraceHeader sends the messages with the AWSTraceHeader system attribute returned by header for the sending context,
+// e.g. the downstream header of the current X-Ray segment. No attribute is set when it returns an empty string.
+func WithAWSTraceHeader(header func(ctx context.Context) string) Option {
+	return func(queue *Queue) error {
+		queue.traceHeader = header
+		return nil
+	}
+}
+
+// setTraceHeader sets the AWSTraceHeader system attribute of the input from the sending context, unless it has one.
+func (queue *Queue) setTraceHeader(ctx context.Context, params *sqs.SendMessageInput) {
+	if queue.traceHeader == nil {
+		return
+	}
+	if _, ok := params.MessageSystemAttributes[AWSTraceHeaderAttribute]; ok {
+		return
+	}
+	header := queue.traceHeader(ctx)
+	if header == "" {
+		return
+	}
+
+	if params.MessageSystemAttributes == nil {
+		params.MessageSystemAttributes = make(map[string]*sqs.MessageSystemAttributeValue)
+	}
+	params.MessageSystemAttributes[AWSTraceHeaderAttribute] = &sqs.MessageSystemAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(header),
+	}
+}
+
+// withTraceHeaderAttribute returns the attribute names with AWSTraceHeader, unless all attributes are requested.
+func withTraceHeaderAttribute(names []string) []string {
+	for _, name := range names {
+		if name == sqs.QueueAttributeNameAll || name == AWSTraceHeaderAttribute {
+			return names
+		}
+	}
+
+	return append(names[:len(names):len(names)], AWSTraceHeaderAttribute)
+}
@@ -0,0 +1,77 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+)
+
+func TestParseTraceHeader(t *testing.T) {
+	header, err := queue.ParseTraceHeader("Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1;Lineage=a87bd80c:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Root != "1-5759e988-bd862e3fe1be46a994272793" || header.Parent != "53995c3f42cd8ad8" || header.Sampled != "1" {
+		t.Errorf("unexpected header %+v", header)
+	}
+	if s := header.String(); s != "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1" {
+		t.Errorf("unexpected string %s", s)
+	}
+
+	if _, err := queue.ParseTraceHeader("Parent=53995c3f42cd8ad8"); err != queue.ErrInvalidTraceHeader {
+		t.Errorf("expected ErrInvalidTraceHeader, got %v", err)
+	}
+}
+
+func TestWithAWSTraceHeader(t *testing.T) {
+	const header = "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+	q, err := memqueue.New("traced", queue.WithReceiveWaitTime(0), queue.WithAWSTraceHeader(func(ctx context.Context) string {
+		return header
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendMessage("traced"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessages([]interface{}{"batched"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := q.ReceiveMessages(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+	for _, message := range messages {
+		received, ok := queue.AWSTraceHeader(message)
+		if !ok || received.String() != header {
+			t.Errorf("message %s: expected the trace header, got %+v", *message.Body, received)
+		}
+	}
+}
+
+func TestWithAWSTraceHeaderEmpty(t *testing.T) {
+	q, err := memqueue.New("untraced", queue.WithReceiveWaitTime(0), queue.WithAWSTraceHeader(func(ctx context.Context) string {
+		return ""
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendMessage("untraced"); err != nil {
+		t.Fatal(err)
+	}
+	message, err := q.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := queue.AWSTraceHeader(message); ok {
+		t.Error("expected no trace header")
+	}
+}
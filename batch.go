@@ -0,0 +1,290 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MaxBatchSize is the maximum number of entries SQS accepts in one batch request.
+const MaxBatchSize = 10
+
+// MaxMessageSize is the maximum size in bytes of a message, and of all messages in one batch request.
+const MaxMessageSize = 256 * 1024
+
+// Default number of times failed batch entries are retried.
+const defaultBatchRetries = 2
+
+// ErrBatchFailed is returned when at least one entry of a batch could not be sent.
+// The failed entries are reported in the BatchResult slice.
+var ErrBatchFailed = errors.New("sending some batch entries failed")
+
+// ErrMessageTooLarge is returned for messages over the MaxMessageSize SQS limit.
+// The returned error is a *MessageTooLargeError holding the actual size.
+var ErrMessageTooLarge = errors.New("message exceeds the SQS size limit")
+
+// ErrBatchEntryUnmatched is reported for the batch entries missing from the response of the batch request.
+var ErrBatchEntryUnmatched = errors.New("batch response has no result for the entry")
+
+// A MessageTooLargeError reports the size of a message over the SQS limit.
+type MessageTooLargeError struct {
+	Size int
+}
+
+// Error returns the size of the message and the limit.
+func (err *MessageTooLargeError) Error() string {
+	return ErrMessageTooLarge.Error() + ": " + strconv.Itoa(err.Size) + " > " + strconv.Itoa(MaxMessageSize) + " bytes"
+}
+
+// Is makes errors.Is(err, ErrMessageTooLarge) true.
+func (err *MessageTooLargeError) Is(target error) bool {
+	return target == ErrMessageTooLarge
+}
+
+// messageSize returns the size of the message counted toward the SQS limit, the body and the message attributes.
+func messageSize(body string, attributes map[string]*sqs.MessageAttributeValue) int {
+	size := len(body)
+	for name, attribute := range attributes {
+		if attribute == nil {
+			continue
+		}
+		size += len(name) + len(aws.StringValue(attribute.DataType)) + len(aws.StringValue(attribute.StringValue)) + len(attribute.BinaryValue)
+	}
+
+	return size
+}
+
+// A BatchEntry is a message to be sent in a batch.
+type BatchEntry struct {
+	Body interface{}
+}
+
+// A BatchResult is the outcome of sending one BatchEntry.
+type BatchResult struct {
+	// Index is the position of the entry in the original slice.
+	Index     int
+	MessageID string
+	Err       error
+}
+
+// batchMessage is a prepared entry waiting to be sent.
+type batchMessage struct {
+	index int
+	input *sqs.SendMessageInput
+}
+
+// prepareBatchMessages marshals and prepares the bodies like single sends, with encryption, offloading and the hooks.
+// The bodies that could not be prepared get their error in results, the others are returned.
+func (queue *Queue) prepareBatchMessages(ctx context.Context, messageBodies []interface{}, results []BatchResult) (messages []batchMessage) {
+	messages = make([]batchMessage, 0, len(messageBodies))
+	for index, messageBody := range messageBodies {
+		results[index].Index = index
+		body, err := queue.marshalMessageBody(messageBody)
+		if err != nil {
+			results[index].Err = err
+			continue
+		}
+		input := &sqs.SendMessageInput{
+			MessageBody: aws.String(body),
+			QueueUrl:    aws.String(queue.URL),
+		}
+		if err := queue.prepareMessageInput(ctx, input); err != nil {
+			results[index].Err = err
+			continue
+		}
+		messages = append(messages, batchMessage{index: index, input: input})
+	}
+
+	return
+}
+
+// SendMessageBatchConcurrent sends the entries in chunks of MaxBatchSize, with up to concurrency chunks in flight.
+// The entries are prepared like SendMessage, with the hooks, encryption and offloading of the queue.
+// The results are in the order of the entries.
+func (queue *Queue) SendMessageBatchConcurrent(ctx context.Context, entries []BatchEntry, concurrency int) (results []BatchResult, err error) {
+	if queue.URL == "" {
+		return nil, ErrQueueNotInitialized
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results = make([]BatchResult, len(entries))
+	messageBodies := make([]interface{}, len(entries))
+	for index, entry := range entries {
+		messageBodies[index] = entry.Body
+	}
+	messages := queue.prepareBatchMessages(ctx, messageBodies, results)
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	chunks := chunkBatchMessages(messages)
+	for i, chunk := range chunks {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			for _, rest := range chunks[i:] {
+				for _, message := range rest {
+					results[message.index].Err = ctx.Err()
+				}
+			}
+			wg.Wait()
+			return results, ctx.Err()
+		}
+
+		wg.Add(1)
+		go func(chunk []batchMessage) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			queue.sendMessageBatchChunk(ctx, chunk, results)
+		}(chunk)
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		if result.Err != nil {
+			err = ErrBatchFailed
+			break
+		}
+	}
+
+	return
+}
+
+// SendMessages sends the bodies with as few batch requests as possible.
+// The bodies are prepared like SendMessage, with the hooks, encryption and offloading of the queue.
+// Entries failing on the SQS side are retried, the results are in the order of the bodies.
+func (queue *Queue) SendMessages(messageBodies []interface{}) (results []BatchResult, err error) {
+	if queue.URL == "" {
+		return nil, ErrQueueNotInitialized
+	}
+	ctx := aws.BackgroundContext()
+
+	results = make([]BatchResult, len(messageBodies))
+	messages := queue.prepareBatchMessages(ctx, messageBodies, results)
+
+	for attempt := 0; attempt <= queue.getBatchRetries() && len(messages) > 0; attempt++ {
+		var retry []batchMessage
+		for _, chunk := range chunkBatchMessages(messages) {
+			retry = append(retry, queue.sendMessageBatchChunk(ctx, chunk, results)...)
+		}
+		messages = retry
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			err = ErrBatchFailed
+			break
+		}
+	}
+
+	return
+}
+
+// getBatchRetries returns the number of times failed batch entries are retried.
+func (queue *Queue) getBatchRetries() int {
+	if queue.batchRetries == nil {
+		return defaultBatchRetries
+	}
+
+	return *queue.batchRetries
+}
+
+// chunkBatchMessages splits the prepared messages into chunks within the entry count and size limits of a batch request.
+func chunkBatchMessages(messages []batchMessage) (chunks [][]batchMessage) {
+	var chunk []batchMessage
+	chunkSize := 0
+	for _, message := range messages {
+		size := messageSize(aws.StringValue(message.input.MessageBody), message.input.MessageAttributes)
+		if len(chunk) == MaxBatchSize || chunkSize+size > MaxMessageSize {
+			chunks = append(chunks, chunk)
+			chunk = nil
+			chunkSize = 0
+		}
+		chunk = append(chunk, message)
+		chunkSize += size
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+
+	return
+}
+
+// sendMessageBatchChunk sends at most MaxBatchSize messages in one request and stores the outcome in results.
+// It returns the messages that failed on the SQS side and may succeed on retry.
+// Request errors are not retried, the retryer of the client already retried the transient ones.
+func (queue *Queue) sendMessageBatchChunk(ctx context.Context, chunk []batchMessage, results []BatchResult) (retry []batchMessage) {
+	params := &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(queue.URL),
+	}
+	pending := make(map[int]batchMessage, len(chunk))
+	for _, message := range chunk {
+		params.Entries = append(params.Entries, &sqs.SendMessageBatchRequestEntry{
+			Id:                      aws.String(strconv.Itoa(message.index)),
+			MessageBody:             message.input.MessageBody,
+			MessageAttributes:       message.input.MessageAttributes,
+			MessageSystemAttributes: message.input.MessageSystemAttributes,
+			MessageGroupId:          message.input.MessageGroupId,
+			MessageDeduplicationId:  message.input.MessageDeduplicationId,
+			DelaySeconds:            message.input.DelaySeconds,
+		})
+		pending[message.index] = message
+	}
+
+	client := queue.GetClient()
+	resp, err := client.SendMessageBatchWithContext(ctx, params)
+	if err != nil {
+		queue.GetLogger().Error("Sending message batch to queue", Fields{
+			"queueName": queue.Name,
+			"error":     err,
+		})
+		for _, message := range chunk {
+			results[message.index].Err = err
+		}
+		return nil
+	}
+
+	for _, entry := range resp.Successful {
+		index, err := strconv.Atoi(aws.StringValue(entry.Id))
+		message, ok := pending[index]
+		if err != nil || !ok {
+			queue.logUnmatchedBatchEntry(entry.Id)
+			continue
+		}
+		delete(pending, index)
+		results[message.index].MessageID = aws.StringValue(entry.MessageId)
+		results[message.index].Err = nil
+	}
+	for _, entry := range resp.Failed {
+		index, err := strconv.Atoi(aws.StringValue(entry.Id))
+		message, ok := pending[index]
+		if err != nil || !ok {
+			queue.logUnmatchedBatchEntry(entry.Id)
+			continue
+		}
+		delete(pending, index)
+		results[message.index].Err = errors.New(aws.StringValue(entry.Code) + ": " + aws.StringValue(entry.Message))
+		if !aws.BoolValue(entry.SenderFault) {
+			retry = append(retry, message)
+		}
+	}
+	for _, message := range pending {
+		results[message.index].Err = ErrBatchEntryUnmatched
+	}
+
+	return
+}
+
+// logUnmatchedBatchEntry warns about an entry of a batch response not matching any entry of the request.
+func (queue *Queue) logUnmatchedBatchEntry(id *string) {
+	queue.GetLogger().Warn("Batch response entry does not match the request", Fields{
+		"queueName": queue.Name,
+		"entryID":   aws.StringValue(id),
+	})
+}
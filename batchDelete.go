@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// A BatchDeleteResult is the outcome of deleting messages in batches.
+type BatchDeleteResult struct {
+	// Deleted holds the IDs of the deleted messages.
+	Deleted []string
+	// Failed holds the messages that were not deleted and should be retried.
+	Failed []BatchDeleteFailure
+}
+
+// A BatchDeleteFailure is a message that could not be deleted.
+type BatchDeleteFailure struct {
+	MessageID string
+	Message   *sqs.Message
+	Err       error
+}
+
+// DeleteMessages removes the messages from the Queue in batches of MaxBatchSize.
+// Messages with the same receipt handle are deleted once. ErrBatchFailed is returned when some deletions failed.
+func (queue *Queue) DeleteMessages(messages []*sqs.Message) (*BatchDeleteResult, error) {
+	return queue.DeleteMessagesContext(aws.BackgroundContext(), messages)
+}
+
+// DeleteMessagesContext removes the messages from the Queue in batches within the context.
+func (queue *Queue) DeleteMessagesContext(ctx context.Context, messages []*sqs.Message) (result *BatchDeleteResult, err error) {
+	result = new(BatchDeleteResult)
+
+	seen := make(map[string]bool, len(messages))
+	unique := make([]*sqs.Message, 0, len(messages))
+	for _, message := range messages {
+		receiptHandle := aws.StringValue(message.ReceiptHandle)
+		if seen[receiptHandle] {
+			continue
+		}
+		seen[receiptHandle] = true
+		unique = append(unique, message)
+	}
+
+	for start := 0; start < len(unique); start += MaxBatchSize {
+		end := start + MaxBatchSize
+		if end > len(unique) {
+			end = len(unique)
+		}
+		queue.deleteMessageBatchChunk(ctx, unique[start:end], result)
+	}
+
+	if len(result.Failed) > 0 {
+		err = ErrBatchFailed
+	}
+
+	return
+}
+
+// deleteMessageBatchChunk deletes at most MaxBatchSize messages in one request and adds the outcome to result.
+func (queue *Queue) deleteMessageBatchChunk(ctx context.Context, chunk []*sqs.Message, result *BatchDeleteResult) {
+	params := &sqs.DeleteMessageBatchInput{
+		QueueUrl: aws.String(queue.URL),
+	}
+	for index, message := range chunk {
+		params.Entries = append(params.Entries, &sqs.DeleteMessageBatchRequestEntry{
+			Id:            aws.String(strconv.Itoa(index)),
+			ReceiptHandle: message.ReceiptHandle,
+		})
+	}
+
+	client := queue.GetClient()
+	resp, err := client.DeleteMessageBatchWithContext(ctx, params)
+	if err != nil {
+		queue.GetLogger().Error("Deleting message batch from queue", Fields{
+			"queueName": queue.Name,
+			"error":     err,
+		})
+		for _, message := range chunk {
+			result.Failed = append(result.Failed, BatchDeleteFailure{
+				MessageID: aws.StringValue(message.MessageId),
+				Message:   message,
+				Err:       err,
+			})
+		}
+		return
+	}
+
+	pending := make(map[int]*sqs.Message, len(chunk))
+	for index, message := range chunk {
+		pending[index] = message
+	}
+	for _, entry := range resp.Successful {
+		index, err := strconv.Atoi(aws.StringValue(entry.Id))
+		message, ok := pending[index]
+		if err != nil || !ok {
+			queue.logUnmatchedBatchEntry(entry.Id)
+			continue
+		}
+		delete(pending, index)
+		result.Deleted = append(result.Deleted, aws.StringValue(message.MessageId))
+		queue.deleteLargePayload(ctx, message)
+	}
+	for _, entry := range resp.Failed {
+		index, err := strconv.Atoi(aws.StringValue(entry.Id))
+		message, ok := pending[index]
+		if err != nil || !ok {
+			queue.logUnmatchedBatchEntry(entry.Id)
+			continue
+		}
+		delete(pending, index)
+		result.Failed = append(result.Failed, BatchDeleteFailure{
+			MessageID: aws.StringValue(message.MessageId),
+			Message:   message,
+			Err:       errors.New(aws.StringValue(entry.Code) + ": " + aws.StringValue(entry.Message)),
+		})
+	}
+	// The messages are reported in the order of the chunk.
+	for index, message := range chunk {
+		if _, ok := pending[index]; ok {
+			result.Failed = append(result.Failed, BatchDeleteFailure{
+				MessageID: aws.StringValue(message.MessageId),
+				Message:   message,
+				Err:       ErrBatchEntryUnmatched,
+			})
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package queue_test
+
+import (
+	"fmt"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// failingDeleteClient fails the batch deletes of the given receipt handles and answers the entries of ids with other IDs.
+type failingDeleteClient struct {
+	*fakeClient
+	failing map[string]bool
+	ids     map[string]string
+}
+
+func (client *failingDeleteClient) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	client.deleteBatchInputs = append(client.deleteBatchInputs, input)
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		if client.failing[*entry.ReceiptHandle] {
+			output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{Id: entry.Id, Code: aws.String(sqs.ErrCodeReceiptHandleIsInvalid), Message: aws.String("expired"), SenderFault: aws.Bool(true)})
+			continue
+		}
+		id := entry.Id
+		if replaced, ok := client.ids[*id]; ok {
+			id = aws.String(replaced)
+		}
+		output.Successful = append(output.Successful, &sqs.DeleteMessageBatchResultEntry{Id: id})
+	}
+	return output, nil
+}
+
+// receivedMessages returns n messages with distinct IDs and receipt handles.
+func receivedMessages(n int) (messages []*sqs.Message) {
+	for i := 0; i < n; i++ {
+		messages = append(messages, &sqs.Message{
+			MessageId:     aws.String(fmt.Sprintf("message-%d", i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("receipt-%d", i)),
+		})
+	}
+	return
+}
+
+func TestDeleteMessagesChunks(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("delete", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := receivedMessages(12)
+	// A duplicate receipt handle is deleted once.
+	messages = append(messages, messages[0])
+	result, err := q.DeleteMessages(messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.deleteBatchInputs) != 2 || len(client.deleteBatchInputs[0].Entries) != 10 || len(client.deleteBatchInputs[1].Entries) != 2 {
+		t.Fatalf("expected batches of 10 and 2 entries, got %v", client.deleteBatchInputs)
+	}
+	if len(result.Deleted) != 12 || len(result.Failed) != 0 {
+		t.Errorf("expected 12 deleted messages, got %+v", result)
+	}
+}
+
+func TestDeleteMessagesPartialFailure(t *testing.T) {
+	client := &failingDeleteClient{fakeClient: newFakeClient(), failing: map[string]bool{"receipt-1": true, "receipt-11": true}}
+	q, err := queue.New("delete", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := receivedMessages(12)
+	result, err := q.DeleteMessages(messages)
+	if err != queue.ErrBatchFailed {
+		t.Fatalf("expected ErrBatchFailed, got %v", err)
+	}
+
+	if len(result.Deleted) != 10 {
+		t.Errorf("expected 10 deleted messages, got %v", result.Deleted)
+	}
+	if len(result.Failed) != 2 {
+		t.Fatalf("expected 2 failed messages, got %+v", result.Failed)
+	}
+	for i, expected := range []*sqs.Message{messages[1], messages[11]} {
+		failure := result.Failed[i]
+		if failure.MessageID != *expected.MessageId || failure.Message != expected || failure.Err == nil {
+			t.Errorf("expected the failure of %s, got %+v", *expected.MessageId, failure)
+		}
+	}
+}
+
+func TestDeleteMessagesUnmatchedEntries(t *testing.T) {
+	client := &failingDeleteClient{fakeClient: newFakeClient(), ids: map[string]string{"0": "first", "1": "-1"}}
+	q, err := queue.New("delete", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := receivedMessages(3)
+	result, err := q.DeleteMessages(messages)
+	if err != queue.ErrBatchFailed {
+		t.Fatalf("expected ErrBatchFailed, got %v", err)
+	}
+
+	if len(result.Deleted) != 1 || result.Deleted[0] != "message-2" {
+		t.Errorf("expected the matched message to be deleted, got %v", result.Deleted)
+	}
+	if len(result.Failed) != 2 {
+		t.Fatalf("expected 2 failed messages, got %+v", result.Failed)
+	}
+	for i, failure := range result.Failed {
+		if failure.Message != messages[i] || failure.Err != queue.ErrBatchEntryUnmatched {
+			t.Errorf("expected %s to fail unanswered, got %+v", *messages[i].MessageId, failure)
+		}
+	}
+}
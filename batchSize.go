@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// How long a delete waits for more deletes to send with it in one batch.
+const deleteBatchWindow = 50 * time.Millisecond
+
+// WithBatchSize makes each receive of ProcessWithContext ask for up to size messages, even with fewer idle workers.
+// The size is clamped to 1 to MaxBatchSize, the range ReceiveMessages accepts.
+// The messages wait for a worker, so their visibility timeout has to cover the wait too.
+// With a size over 1 the handled messages are deleted in batches. The default of 1 receives only as many as there are idle workers.
+func (processor *Processor) WithBatchSize(size int) *Processor {
+	switch {
+	case size < 1:
+		size = 1
+	case size > MaxBatchSize:
+		size = MaxBatchSize
+	}
+	processor.batchSize = size
+
+	return processor
+}
+
+// batchExtra returns how many messages to receive over the idle workers, for the batch size.
+// With a rate limit only the messages allowed right away are added.
+func (processor *Processor) batchExtra(idle int) (extra int) {
+	extra = processor.batchSize - idle
+	if extra <= 0 {
+		return 0
+	}
+
+	return processor.availableRate(idle, extra)
+}
+
+// deleteRequest is a message waiting to be deleted in a batch, the result is sent to done.
+type deleteRequest struct {
+	message *sqs.Message
+	done    chan error
+}
+
+// deleteBatcher collects the deletes of the workers into batches of up to MaxBatchSize.
+type deleteBatcher struct {
+	queue    *Queue
+	requests chan deleteRequest
+	stopping chan struct{}
+	stopped  chan struct{}
+}
+
+// startDeleteBatcher starts batching the deletes within the context, until stop is called.
+func (processor *Processor) startDeleteBatcher(ctx context.Context) (batcher *deleteBatcher, stop func()) {
+	batcher = &deleteBatcher{
+		queue:    processor.Queue,
+		requests: make(chan deleteRequest),
+		stopping: make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go batcher.run(ctx)
+
+	return batcher, func() {
+		close(batcher.stopping)
+		<-batcher.stopped
+	}
+}
+
+// delete deletes the message with the next batch and returns the result.
+// After the batcher stopped, e.g. for a handler outliving the drain timeout, the message is deleted alone.
+func (batcher *deleteBatcher) delete(ctx context.Context, message *sqs.Message) error {
+	done := make(chan error, 1)
+	select {
+	case batcher.requests <- deleteRequest{message: message, done: done}:
+		return <-done
+	case <-batcher.stopping:
+		_, err := batcher.queue.DeleteMessageContext(ctx, message)
+		return err
+	}
+}
+
+// run sends the collected deletes when a batch is full, the window is over or the batcher is stopped.
+func (batcher *deleteBatcher) run(ctx context.Context) {
+	defer close(batcher.stopped)
+
+	var pending []deleteRequest
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-batcher.stopping:
+			batcher.flush(ctx, pending)
+			return
+		case request := <-batcher.requests:
+			pending = append(pending, request)
+			if len(pending) == 1 {
+				flush = time.After(deleteBatchWindow)
+			}
+			if len(pending) < MaxBatchSize {
+				continue
+			}
+		case <-flush:
+		}
+
+		batcher.flush(ctx, pending)
+		pending, flush = nil, nil
+	}
+}
+
+// flush deletes the pending messages in one batch and sends each it's result.
+func (batcher *deleteBatcher) flush(ctx context.Context, pending []deleteRequest) {
+	if len(pending) == 0 {
+		return
+	}
+
+	messages := make([]*sqs.Message, len(pending))
+	for i, request := range pending {
+		messages[i] = request.message
+	}
+	result, _ := batcher.queue.DeleteMessagesContext(ctx, messages)
+
+	failed := make(map[*sqs.Message]error, len(result.Failed))
+	for _, failure := range result.Failed {
+		failed[failure.Message] = failure.Err
+	}
+	for _, request := range pending {
+		request.done <- failed[request.message]
+	}
+}
+
+// deleteMessage deletes the handled message, in a batch when the processor batches the deletes.
+func (processor *Processor) deleteMessage(ctx context.Context, message *sqs.Message) error {
+	if batchers := processor.getState().deletes.Load(); batchers != nil {
+		if batcher := (*batchers)[processor.Queue]; batcher != nil {
+			return batcher.delete(ctx, message)
+		}
+	}
+
+	_, err := processor.Queue.DeleteMessageContext(ctx, message)
+
+	return err
+}
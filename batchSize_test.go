@@ -0,0 +1,103 @@
+package queue_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestBatchSizeFeedsWorkersFromOneReceive(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("batched", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const workers, messages = 3, 6
+	for i := 0; i < messages; i++ {
+		q.SendMessage(i)
+	}
+
+	// The handlers of the first receive wait for each other, so they only finish if it fed all workers.
+	var mutex sync.Mutex
+	started := 0
+	allStarted := make(chan struct{})
+	processor := (&queue.Processor{
+		Queue:       q,
+		Concurrency: workers,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			mutex.Lock()
+			started++
+			if started == workers {
+				close(allStarted)
+			}
+			mutex.Unlock()
+
+			select {
+			case <-allStarted:
+				return nil
+			case <-time.After(time.Second):
+				t.Error("expected the workers to handle the messages of one receive in parallel")
+				return nil
+			}
+		},
+	}).WithBatchSize(messages).WithMaxMessages(messages)
+
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if len(client.receiveInputs) == 0 || *client.receiveInputs[0].MaxNumberOfMessages != messages {
+		t.Fatalf("expected the first receive to ask for %d messages, got %v", messages, client.receiveInputs)
+	}
+	if len(client.messages) != 0 || len(client.receiveInputs) != 1 {
+		t.Errorf("expected all messages to be received at once, got %d receives", len(client.receiveInputs))
+	}
+	deleted := 0
+	for _, input := range client.deleteBatchInputs {
+		deleted += len(input.Entries)
+	}
+	if deleted != messages || len(client.deleteInputs) != 0 {
+		t.Errorf("expected the messages to be deleted in batches, got %d in batches and %d alone", deleted, len(client.deleteInputs))
+	}
+}
+
+func TestBatchSizeClamped(t *testing.T) {
+	tests := []struct {
+		size        int
+		maxMessages int64
+	}{
+		{size: 20, maxMessages: 10},
+		{size: 0, maxMessages: 1},
+		{size: -5, maxMessages: 1},
+	}
+	for _, test := range tests {
+		client := newFakeClient()
+		q, err := queue.New("batched", queue.WithClient(client))
+		if err != nil {
+			t.Fatal(err)
+		}
+		q.SendMessage("body")
+
+		processor := &queue.Processor{Queue: q}
+		processor.HandleMessage = func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			processor.Stop()
+			return nil
+		}
+		processor.WithBatchSize(test.size)
+		if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+			t.Fatal(err)
+		}
+
+		client.mutex.Lock()
+		if len(client.receiveInputs) == 0 || *client.receiveInputs[0].MaxNumberOfMessages != test.maxMessages {
+			t.Errorf("expected a batch size of %d to receive up to %d messages, got %v", test.size, test.maxMessages, client.receiveInputs)
+		}
+		client.mutex.Unlock()
+	}
+}
@@ -0,0 +1,178 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// WithBatchWindow collects up to maxMessages messages, or as many as arrive within window after the first one,
+// and passes them to HandleBatch at once.
+func (processor *Processor) WithBatchWindow(maxMessages int, window time.Duration) *Processor {
+	processor.batchMaxMessages = maxMessages
+	processor.batchWindow = window
+
+	return processor
+}
+
+// processBatchWindows handles the queues of the poller in time windowed batches until the processor stops.
+// The batches are collected within ctx, then handled and deleted within handlerCtx, so a shutdown lets the last batch finish.
+// It returns the error of a missing queue.
+func (processor *Processor) processBatchWindows(ctx context.Context, handlerCtx context.Context, poller *queuePoller, queueDetails Fields) error {
+	for ctx.Err() == nil {
+		if processor.maxMessagesReached() {
+			processor.getLogger().Info("Processing queue stopped, maximum number of messages reached", queueDetails)
+			return nil
+		}
+
+		processor.waitForHealthyDependencies(ctx)
+		max := processor.waitForRate(ctx, processor.batchMaxMessages)
+		if max == 0 {
+			continue
+		}
+
+		received, messages, err := poller.poll(ctx, func(ctx context.Context, processor *Processor, waitTimeSeconds int64) ([]*sqs.Message, error) {
+			return processor.collectBatch(ctx, max, waitTimeSeconds)
+		})
+		if err != nil {
+			return err
+		}
+		if len(messages) > 0 {
+			processor.chargeRate(handlerCtx, len(messages))
+			received.handleBatchWindow(handlerCtx, messages)
+		}
+	}
+
+	return nil
+}
+
+// handleBatchWindow passes the collected messages to HandleBatch and deletes them when it succeeds.
+// It returns how many messages were handled successfully and how many failed.
+func (processor *Processor) handleBatchWindow(ctx context.Context, messages []*sqs.Message) (processed int, failed int) {
+	metrics := processor.getMetrics()
+	for _, message := range messages {
+		processor.recordReceived(message)
+	}
+	started := time.Now()
+	err := processor.HandleBatch(ctx, processor, messages)
+	outcome := Outcome{Err: err, StartedAt: started, FinishedAt: time.Now()}
+	deletable := processor.archiveBatch(ctx, messages, func(*sqs.Message) Outcome { return outcome })
+	if err != nil {
+		for range messages {
+			processor.recordFailed(err)
+		}
+		processor.getLogger().Warn("Error processing message batch", Fields{
+			"error":     err,
+			"messages":  len(messages),
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+		return 0, len(messages)
+	}
+	duration := outcome.FinishedAt.Sub(started)
+	for range messages {
+		metrics.MessageProcessed(duration)
+	}
+	processor.getState().processedMessages.Add(int64(len(messages)))
+	if len(deletable) == 0 {
+		return len(messages), 0
+	}
+	result, err := processor.Queue.DeleteMessagesContext(ctx, deletable)
+	for range result.Deleted {
+		processor.recordDeleted()
+	}
+	if err != nil {
+		for _, failure := range result.Failed {
+			processor.getLogger().Warn("Error deleting queue message", Fields{
+				"messageID": failure.MessageID,
+				"error":     failure.Err,
+				"queueName": processor.Queue.Name,
+				"queueURL":  processor.Queue.URL,
+			})
+		}
+	}
+
+	return len(messages), 0
+}
+
+// collectBatch polls for the first message for waitTimeSeconds, then collects more until the batch has max messages or the window is over.
+// Long polls last whole seconds, so the last second of the window is waited out and collected with a short poll.
+// Only the error of the first receive is returned, later errors end the batch early.
+func (processor *Processor) collectBatch(ctx context.Context, max int, waitTimeSeconds int64) (batch []*sqs.Message, err error) {
+	messages, err := processor.Queue.receiveMessages(ctx, batchReceiveSize(max, 0), waitTimeSeconds)
+	if err != nil || len(messages) == 0 {
+		return
+	}
+	batch = append(batch, messages...)
+
+	deadline := time.Now().Add(processor.batchWindow)
+	for len(batch) < max {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		last := remaining < time.Second
+		if last && aws.SleepWithContext(ctx, remaining) != nil {
+			break
+		}
+		waitTimeSeconds := int64(remaining / time.Second)
+		if waitTimeSeconds > processor.Queue.getWaitTimeSeconds() {
+			waitTimeSeconds = processor.Queue.getWaitTimeSeconds()
+		}
+
+		messages, receiveErr := processor.Queue.receiveMessages(ctx, batchReceiveSize(max, len(batch)), waitTimeSeconds)
+		if receiveErr != nil {
+			break
+		}
+		batch = append(batch, messages...)
+		if last {
+			break
+		}
+	}
+
+	return
+}
+
+// batchReceiveSize returns how many messages to receive in one call to fill a batch of max messages.
+func batchReceiveSize(max int, collected int) int64 {
+	size := max - collected
+	if size > MaxBatchSize {
+		size = MaxBatchSize
+	}
+
+	return int64(size)
+}
+
+// drainBatches runs the batch loop until it returns or the processor stops, then waits up to the drain timeout
+// for the batch being handled like ProcessWithContext, cancelling it's handler when it runs out.
+// The error of the loop, e.g. a missing queue, is returned over the one of draining.
+func (processor *Processor) drainBatches(ctx context.Context, cancelHandlers context.CancelFunc, queueDetails Fields, loop func() error) error {
+	var inFlight sync.WaitGroup
+	inFlight.Add(1)
+	finished := make(chan struct{})
+	var fatal error
+	go func() {
+		defer inFlight.Done()
+		defer close(finished)
+		fatal = loop()
+	}()
+
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+
+	err := processor.drain(&inFlight, cancelHandlers, queueDetails)
+	select {
+	case <-finished:
+		if fatal != nil {
+			return fatal
+		}
+	default:
+	}
+
+	return err
+}
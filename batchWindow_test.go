@@ -0,0 +1,126 @@
+package queue_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// countingClient is an in-memory client counting the receive calls.
+type countingClient struct {
+	*memqueue.Client
+
+	receives atomic.Int64
+}
+
+// ReceiveMessageWithContext counts the call and receives in memory.
+func (client *countingClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	client.receives.Add(1)
+
+	return client.Client.ReceiveMessageWithContext(ctx, input, opts...)
+}
+
+func TestBatchWindowDeletesAfterShutdown(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "batched", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{"first", "second"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	processor := &queue.Processor{
+		Queue: q,
+		HandleBatch: func(handlerCtx context.Context, processor *queue.Processor, messages []*sqs.Message) error {
+			cancel()
+			if handlerCtx.Err() != nil {
+				t.Error("expected the handler context to outlive the shutdown")
+			}
+			return nil
+		},
+	}
+	processor.WithBatchWindow(2, 10*time.Millisecond)
+	if err := processor.ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if bodies := client.Messages(q.URL); len(bodies) != 0 {
+		t.Errorf("expected the handled batch to be deleted, %d messages left", len(bodies))
+	}
+}
+
+func TestBatchWindowDrainTimeout(t *testing.T) {
+	q, err := memqueue.New("batched", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("first")
+
+	processor := &queue.Processor{
+		Queue:        q,
+		DrainTimeout: 50 * time.Millisecond,
+	}
+	cancelled := make(chan struct{})
+	processor.HandleBatch = func(ctx context.Context, processor *queue.Processor, messages []*sqs.Message) error {
+		processor.Stop()
+		<-ctx.Done()
+		close(cancelled)
+		return ctx.Err()
+	}
+	processor.WithBatchWindow(1, 10*time.Millisecond)
+
+	started := time.Now()
+	if err := processor.ProcessWithContext(context.Background(), nil); err != queue.ErrDrainTimeout {
+		t.Errorf("expected ErrDrainTimeout, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the shutdown within the drain timeout, took %s", elapsed)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Error("expected the handler context to be cancelled")
+	}
+}
+
+func TestBatchWindowLastSecond(t *testing.T) {
+	client := &countingClient{Client: memqueue.NewClient()}
+	q, err := queue.New("batched", queue.WithClient(client), queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("first")
+
+	var receives int64
+	var batchSize int
+	ctx, cancel := context.WithCancel(context.Background())
+	processor := &queue.Processor{
+		Queue: q,
+		HandleBatch: func(ctx context.Context, processor *queue.Processor, messages []*sqs.Message) error {
+			receives = client.receives.Load()
+			batchSize = len(messages)
+			cancel()
+			return nil
+		},
+	}
+	processor.WithBatchWindow(5, 300*time.Millisecond)
+	started := time.Now()
+	processor.ProcessWithContext(ctx, nil)
+
+	if batchSize != 1 {
+		t.Errorf("expected a batch of the message, got %d", batchSize)
+	}
+	if receives != 2 {
+		t.Errorf("expected the first receive and a short poll after the window, got %d receives", receives)
+	}
+	if elapsed := time.Since(started); elapsed < 300*time.Millisecond {
+		t.Errorf("expected the batch after the window, got it after %s", elapsed)
+	}
+}
@@ -0,0 +1,219 @@
+package queue_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// failingBatchClient fails the batch entries of the given indexes as many times as set, or the whole requests with requestErr.
+type failingBatchClient struct {
+	*fakeClient
+	failures    map[string]int
+	senderFault bool
+	requestErr  error
+	sizes       []int
+}
+
+func (client *failingBatchClient) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	client.sizes = append(client.sizes, len(input.Entries))
+	if client.requestErr != nil {
+		return nil, client.requestErr
+	}
+	var entries []*sqs.SendMessageBatchRequestEntry
+	var failed []*sqs.BatchResultErrorEntry
+	for _, entry := range input.Entries {
+		if client.failures[*entry.Id] > 0 {
+			client.failures[*entry.Id]--
+			failed = append(failed, &sqs.BatchResultErrorEntry{Id: entry.Id, Code: aws.String("InternalError"), Message: aws.String("failed"), SenderFault: aws.Bool(client.senderFault)})
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	output, err := client.fakeClient.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{QueueUrl: input.QueueUrl, Entries: entries}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	output.Failed = failed
+	return output, nil
+}
+
+// renumberingBatchClient answers the first entry of the batches with a malformed and the second with an unknown ID.
+type renumberingBatchClient struct {
+	*fakeClient
+}
+
+func (client *renumberingBatchClient) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	output, err := client.fakeClient.SendMessageBatchWithContext(ctx, input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	output.Successful[0].Id = aws.String("first")
+	output.Successful[1].Id = aws.String("42")
+	return output, nil
+}
+
+// batchSizes returns the number of entries of each batch request.
+func batchSizes(client *fakeClient) (sizes []int) {
+	for _, input := range client.sendBatchInputs {
+		sizes = append(sizes, len(input.Entries))
+	}
+	return
+}
+
+func TestSendMessagesChunksByCount(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("batch", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bodies := make([]interface{}, 11)
+	for i := range bodies {
+		bodies[i] = i
+	}
+	results, err := q.SendMessages(bodies)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sizes := batchSizes(client); len(sizes) != 2 || sizes[0] != 10 || sizes[1] != 1 {
+		t.Errorf("expected batches of 10 and 1 entries, got %v", sizes)
+	}
+	for i, result := range results {
+		if result.Index != i || result.MessageID == "" || result.Err != nil {
+			t.Errorf("expected entry %d to be sent, got %+v", i, result)
+		}
+	}
+}
+
+func TestSendMessagesChunksBySize(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("batch", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := strings.Repeat("a", 100*1024)
+	if _, err := q.SendMessages([]interface{}{body, body, body}); err != nil {
+		t.Fatal(err)
+	}
+
+	if sizes := batchSizes(client); len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 1 {
+		t.Errorf("expected batches of 2 and 1 entries within the size limit, got %v", sizes)
+	}
+}
+
+func TestSendMessagesOversizedMessage(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("batch", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := q.SendMessages([]interface{}{"small", strings.Repeat("a", queue.MaxMessageSize), "small"})
+	if err != queue.ErrBatchFailed {
+		t.Fatalf("expected ErrBatchFailed, got %v", err)
+	}
+
+	if !errors.Is(results[1].Err, queue.ErrMessageTooLarge) {
+		t.Errorf("expected ErrMessageTooLarge for the oversized message, got %v", results[1].Err)
+	}
+	if results[0].Err != nil || results[2].Err != nil {
+		t.Errorf("expected the other messages to be sent, got %+v", results)
+	}
+	if sizes := batchSizes(client); len(sizes) != 1 || sizes[0] != 2 {
+		t.Errorf("expected one batch without the oversized message, got %v", sizes)
+	}
+}
+
+func TestSendMessagesRetriesFailedEntries(t *testing.T) {
+	client := &failingBatchClient{fakeClient: newFakeClient(), failures: map[string]int{"1": 2, "3": 5}}
+	q, err := queue.New("batch", queue.WithClient(client), queue.WithBatchRetries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := q.SendMessages([]interface{}{0, 1, 2, 3})
+	if err != queue.ErrBatchFailed {
+		t.Fatalf("expected ErrBatchFailed, got %v", err)
+	}
+
+	if results[1].Err != nil || results[1].MessageID == "" {
+		t.Errorf("expected entry 1 to succeed on the last retry, got %+v", results[1])
+	}
+	if results[3].Index != 3 || results[3].Err == nil {
+		t.Errorf("expected entry 3 to fail after the retries, got %+v", results[3])
+	}
+	if sizes := client.sizes; len(sizes) != 3 || sizes[0] != 4 || sizes[1] != 2 || sizes[2] != 2 {
+		t.Errorf("expected the failed entries to be retried twice, got %v", sizes)
+	}
+}
+
+func TestSendMessagesDoesNotRetrySenderFaults(t *testing.T) {
+	client := &failingBatchClient{fakeClient: newFakeClient(), failures: map[string]int{"0": 1}, senderFault: true}
+	q, err := queue.New("batch", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := q.SendMessages([]interface{}{0, 1})
+	if err != queue.ErrBatchFailed || results[0].Err == nil {
+		t.Fatalf("expected entry 0 to fail, got %v %+v", err, results)
+	}
+	if len(client.sizes) != 1 {
+		t.Errorf("expected no retry of a sender fault, got %d batch requests", len(client.sizes))
+	}
+}
+
+func TestSendMessagesDoesNotRetryRequestErrors(t *testing.T) {
+	requestErr := awserr.New("AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", nil)
+	client := &failingBatchClient{fakeClient: newFakeClient(), requestErr: requestErr}
+	q, err := queue.New("batch", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := q.SendMessages([]interface{}{0, 1})
+	if err != queue.ErrBatchFailed {
+		t.Fatalf("expected ErrBatchFailed, got %v", err)
+	}
+
+	for _, result := range results {
+		if result.Err != requestErr {
+			t.Errorf("expected the request error, got %+v", result)
+		}
+	}
+	if len(client.sizes) != 1 {
+		t.Errorf("expected no retry of a request error, got %d batch requests", len(client.sizes))
+	}
+}
+
+func TestSendMessagesUnmatchedEntries(t *testing.T) {
+	client := &renumberingBatchClient{fakeClient: newFakeClient()}
+	q, err := queue.New("batch", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := q.SendMessages([]interface{}{0, 1, 2})
+	if err != queue.ErrBatchFailed {
+		t.Fatalf("expected ErrBatchFailed, got %v", err)
+	}
+
+	for _, result := range results[:2] {
+		if result.Err != queue.ErrBatchEntryUnmatched {
+			t.Errorf("expected the unanswered entry to fail, got %+v", result)
+		}
+	}
+	if results[2].Err != nil || results[2].MessageID == "" {
+		t.Errorf("expected the matched entry to succeed, got %+v", results[2])
+	}
+}
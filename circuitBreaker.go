@@ -0,0 +1,132 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// circuitBreaker counts the consecutive handler failures and holds the receiving while it is open.
+type circuitBreaker struct {
+	mutex    sync.Mutex
+	failures int
+	// openUntil is zero while the circuit is closed.
+	openUntil time.Time
+	// probe is closed when the single message received half-open is settled.
+	probe chan struct{}
+}
+
+// WithCircuitBreaker stops receiving after threshold consecutive handler failures, leaving the messages in the queue.
+// After the coolDown a single message probes the handler, on success the processor resumes, on failure it waits again.
+// Permanent errors don't count, they are caused by the message, not by the handler's dependencies.
+func (processor *Processor) WithCircuitBreaker(threshold int, coolDown time.Duration) *Processor {
+	processor.circuitBreakerThreshold = threshold
+	processor.circuitBreakerCoolDown = coolDown
+
+	return processor
+}
+
+// waitForCircuit waits while the circuit is open and returns how many messages may be received, up to max.
+// The half-open probe allows a single message, probing reports it, so it is released with releaseProbe.
+// It returns 0 when the context is done first.
+func (processor *Processor) waitForCircuit(ctx context.Context, max int) (allowed int, probing bool) {
+	if processor.circuitBreakerThreshold <= 0 {
+		return max, false
+	}
+
+	circuit := &processor.getState().circuit
+	for {
+		circuit.mutex.Lock()
+		switch {
+		case circuit.probe != nil:
+			probe := circuit.probe
+			circuit.mutex.Unlock()
+			select {
+			case <-probe:
+			case <-ctx.Done():
+				return 0, false
+			}
+		case circuit.openUntil.IsZero():
+			circuit.mutex.Unlock()
+			return max, false
+		case time.Now().Before(circuit.openUntil):
+			wait := time.Until(circuit.openUntil)
+			circuit.mutex.Unlock()
+			if aws.SleepWithContext(ctx, wait) != nil {
+				return 0, false
+			}
+		default:
+			circuit.probe = make(chan struct{})
+			circuit.mutex.Unlock()
+			processor.getLogger().Info("Circuit half-open, probing with a single message", Fields{
+				"queueName": processor.Queue.Name,
+			})
+			return 1, true
+		}
+	}
+}
+
+// releaseProbe lets the processor receive again after the half-open probe, e.g. when no message was received for it.
+func (processor *Processor) releaseProbe() {
+	circuit := &processor.getState().circuit
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+
+	if circuit.probe != nil {
+		close(circuit.probe)
+		circuit.probe = nil
+	}
+}
+
+// circuitOpen reports whether the circuit is open or half-open.
+func (processor *Processor) circuitOpen() bool {
+	circuit := &processor.getState().circuit
+	circuit.mutex.Lock()
+	defer circuit.mutex.Unlock()
+
+	return !circuit.openUntil.IsZero()
+}
+
+// recordCircuitResult counts the result of the handler, opening the circuit after too many consecutive failures.
+func (processor *Processor) recordCircuitResult(err error) {
+	if processor.circuitBreakerThreshold <= 0 || (err != nil && IsPermanentError(err)) {
+		return
+	}
+
+	circuit := &processor.getState().circuit
+	circuit.mutex.Lock()
+	if err == nil {
+		closed := !circuit.openUntil.IsZero()
+		circuit.failures = 0
+		circuit.openUntil = time.Time{}
+		circuit.mutex.Unlock()
+		if closed {
+			processor.getLogger().Info("Circuit closed, processing resumed", Fields{
+				"queueName": processor.Queue.Name,
+			})
+		}
+		return
+	}
+
+	circuit.failures++
+	failures := circuit.failures
+	halfOpen := !circuit.openUntil.IsZero()
+	if !halfOpen && failures < processor.circuitBreakerThreshold {
+		circuit.mutex.Unlock()
+		return
+	}
+	circuit.openUntil = time.Now().Add(processor.circuitBreakerCoolDown)
+	circuit.mutex.Unlock()
+
+	processor.getLogger().Warn("Circuit open, processing paused", Fields{
+		"queueName": processor.Queue.Name,
+		"failures":  failures,
+		"coolDown":  processor.circuitBreakerCoolDown,
+		"error":     err,
+	})
+	if processor.OnCircuitOpen != nil && !halfOpen {
+		processor.OnCircuitOpen(failures, err)
+	}
+}
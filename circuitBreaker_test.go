@@ -0,0 +1,106 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Cool down of the circuit breaker tests.
+const testCoolDown = 100 * time.Millisecond
+
+func TestCircuitBreakerOpensProbesAndCloses(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "guarded", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("retried")
+
+	// Two failures open the circuit, the first probe fails too, the second one succeeds.
+	const failures = 3
+	var calls []time.Time
+	var openDuringCall []bool
+	var opened []int
+	var processor *queue.Processor
+	processor = (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			calls = append(calls, time.Now())
+			openDuringCall = append(openDuringCall, processor.Stats().CircuitOpen)
+			if len(calls) <= failures {
+				return errors.New("database is down")
+			}
+			return nil
+		},
+		OnCircuitOpen: func(failures int, err error) {
+			opened = append(opened, failures)
+		},
+	}).WithCircuitBreaker(2, testCoolDown).WithMaxMessages(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := processor.ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calls) != failures+1 {
+		t.Fatalf("expected %d handler calls, got %d", failures+1, len(calls))
+	}
+	if len(opened) != 1 || opened[0] != 2 {
+		t.Errorf("expected the circuit to open once after 2 failures, got %v", opened)
+	}
+	if gap := calls[1].Sub(calls[0]); gap >= testCoolDown {
+		t.Errorf("expected no pause before the threshold, got %s", gap)
+	}
+	for i := 2; i < len(calls); i++ {
+		if gap := calls[i].Sub(calls[i-1]); gap < testCoolDown {
+			t.Errorf("expected probe %d after the cool down, got it after %s", i-1, gap)
+		}
+	}
+	expectedOpen := []bool{false, false, true, true}
+	for i := range expectedOpen {
+		if openDuringCall[i] != expectedOpen[i] {
+			t.Errorf("expected the circuit to be open during the probes only, got %v", openDuringCall)
+			break
+		}
+	}
+	if processor.Stats().CircuitOpen {
+		t.Error("expected the successful probe to close the circuit")
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 0 {
+		t.Errorf("expected the probed message to be deleted, got %d messages", remaining)
+	}
+}
+
+func TestCircuitBreakerIgnoresPermanentErrors(t *testing.T) {
+	q, err := memqueue.New("guarded", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		q.SendMessage(i)
+	}
+
+	opened := false
+	processor := (&queue.Processor{
+		Queue:         q,
+		HandleMessage: failWith(queue.PermanentError(errors.New("invalid message"))),
+		OnCircuitOpen: func(failures int, err error) {
+			opened = true
+		},
+	}).WithCircuitBreaker(1, time.Minute)
+	summary, err := processor.ProcessN(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Failed != 3 || opened {
+		t.Errorf("expected the permanent errors not to open the circuit, got %+v", summary)
+	}
+}
@@ -0,0 +1,146 @@
+package queue
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// ErrProfileNotFound is returned when the profile set with WithProfile is in neither shared AWS file.
+// The SDK would fall back to the instance credentials for it.
+var ErrProfileNotFound = errors.New("AWS profile not found")
+
+// WithCredentials makes the client of the queue use the credentials instead of the default credential chain.
+func WithCredentials(creds *credentials.Credentials) Option {
+	return func(queue *Queue) error {
+		queue.credentials = creds
+		return nil
+	}
+}
+
+// WithProfile makes the client of the queue use the named profile of the shared AWS config and credentials files.
+// When the profile can not be loaded, every call of the client returns the error, e.g. from Init,
+// ErrProfileNotFound for a profile missing from the shared files.
+func WithProfile(name string) Option {
+	return func(queue *Queue) error {
+		queue.profile = name
+		return nil
+	}
+}
+
+// WithAssumeRole makes the client of the queue assume the role, e.g. for a queue in another account.
+// The assumed role credentials are refreshed automatically before they expire.
+func WithAssumeRole(roleArn string, sessionName string) Option {
+	return func(queue *Queue) error {
+		queue.assumeRoleArn = roleArn
+		queue.assumeRoleSessionName = sessionName
+		return nil
+	}
+}
+
+// newClient returns an SQS client with the config and credentials of the queue.
+// The client fails every call when the session can not be created, instead of falling back to other credentials.
+func (queue *Queue) newClient() sqsiface.SQSAPI {
+	sess, err := queue.newSession()
+	if err != nil {
+		return errorClient(func(operation string) error {
+			return err
+		})
+	}
+	if queue.assumeRoleArn == "" {
+		return sqs.New(sess)
+	}
+
+	creds := stscreds.NewCredentials(sess, queue.assumeRoleArn, func(provider *stscreds.AssumeRoleProvider) {
+		if queue.assumeRoleSessionName != "" {
+			provider.RoleSessionName = queue.assumeRoleSessionName
+		}
+	})
+
+	return sqs.New(sess, &aws.Config{Credentials: creds})
+}
+
+// newSession returns an AWS session with the config of the queue, using the profile when there is one.
+func (queue *Queue) newSession() (*session.Session, error) {
+	if queue.profile == "" {
+		return session.New(queue.getConfig()), nil
+	}
+
+	err := checkProfileExists(queue.profile)
+	var sess *session.Session
+	if err == nil {
+		sess, err = session.NewSessionWithOptions(session.Options{
+			Config:            *queue.getConfig(),
+			Profile:           queue.profile,
+			SharedConfigState: session.SharedConfigEnable,
+		})
+	}
+	if err != nil {
+		queue.GetLogger().Error("Loading the AWS profile", Fields{
+			"queueName": queue.Name,
+			"profile":   queue.profile,
+			"error":     err,
+		})
+		return nil, fmt.Errorf("loading the AWS profile %s: %w", queue.profile, err)
+	}
+
+	return sess, nil
+}
+
+// checkProfileExists returns ErrProfileNotFound when neither the shared credentials nor the shared config file,
+// as located by the SDK, has a section for the profile.
+func checkProfileExists(profile string) error {
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		credentialsFile = defaults.SharedCredentialsFilename()
+	}
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		configFile = defaults.SharedConfigFilename()
+	}
+
+	for _, filename := range []string{credentialsFile, configFile} {
+		found, err := hasProfileSection(filename, profile)
+		if err != nil || found {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrProfileNotFound, profile)
+}
+
+// hasProfileSection reports whether the ini file has a [name] or [profile name] section, false when it does not exist.
+func hasProfileSection(filename string, profile string) (bool, error) {
+	file, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+			continue
+		}
+		section := strings.Fields(strings.Trim(line, "[]"))
+		if (len(section) == 1 && section[0] == profile) || (len(section) == 2 && section[0] == "profile" && section[1] == profile) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
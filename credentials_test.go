@@ -0,0 +1,78 @@
+package queue_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// withSharedConfig points the shared AWS config and credentials files to the content.
+func withSharedConfig(t *testing.T, config string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", path)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+}
+
+func TestWithCredentials(t *testing.T) {
+	creds := credentials.NewStaticCredentials("id", "secret", "")
+	q := &queue.Queue{Name: "credentials", Region: "eu-west-1"}
+	if err := queue.WithCredentials(creds)(q); err != nil {
+		t.Fatal(err)
+	}
+
+	client, ok := q.GetClient().(*sqs.SQS)
+	if !ok {
+		t.Fatalf("expected an SQS client, got %T", q.GetClient())
+	}
+	if client.Config.Credentials != creds {
+		t.Error("expected the client to use the credentials")
+	}
+}
+
+func TestWithProfile(t *testing.T) {
+	withSharedConfig(t, "[profile tools]\nregion = eu-west-1\naws_access_key_id = id\naws_secret_access_key = secret\n")
+	q := &queue.Queue{Name: "profile"}
+	if err := queue.WithProfile("tools")(q); err != nil {
+		t.Fatal(err)
+	}
+
+	client, ok := q.GetClient().(*sqs.SQS)
+	if !ok {
+		t.Fatalf("expected an SQS client, got %T", q.GetClient())
+	}
+	value, err := client.Config.Credentials.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.AccessKeyID != "id" {
+		t.Errorf("expected the credentials of the profile, got %s", value.AccessKeyID)
+	}
+}
+
+func TestWithProfileMissing(t *testing.T) {
+	withSharedConfig(t, "[default]\naws_access_key_id = default\naws_secret_access_key = secret\n")
+	q := &queue.Queue{Name: "profile", Region: "eu-west-1"}
+	if err := queue.WithProfile("missing")(q); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Init(); !errors.Is(err, queue.ErrProfileNotFound) {
+		t.Fatalf("expected ErrProfileNotFound instead of falling back to other credentials, got %v", err)
+	}
+	if _, err := q.GetClient().ListQueues(&sqs.ListQueuesInput{}); !errors.Is(err, queue.ErrProfileNotFound) {
+		t.Errorf("expected every call to fail with the profile error, got %v", err)
+	}
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"path"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ForwardDLQToS3 archives every message of the dead letter queue to S3 and deletes it from the dead letter queue.
+// Each message is written as JSON to prefix/{queue-name}/{yyyy-mm-dd}/{message-id}.json.
+// It returns the number of archived messages.
+func (queue *Queue) ForwardDLQToS3(ctx context.Context, s3Client s3iface.S3API, bucket, prefix string) (archived int, err error) {
+	if queue.DeadLetterQueueURL == "" {
+		return 0, ErrNoDeadLetterQueue
+	}
+
+	client := queue.GetClient()
+	for {
+		params := &sqs.ReceiveMessageInput{
+			QueueUrl:                    aws.String(queue.DeadLetterQueueURL),
+			MaxNumberOfMessages:         aws.Int64(MaxBatchSize),
+			WaitTimeSeconds:             aws.Int64(1),
+			MessageSystemAttributeNames: []*string{aws.String(sqs.QueueAttributeNameAll)},
+			MessageAttributeNames:       []*string{aws.String(sqs.QueueAttributeNameAll)},
+		}
+		resp, err := client.ReceiveMessageWithContext(ctx, params)
+		if err != nil {
+			queue.GetLogger().Error("Receiving message from dead letter queue", Fields{
+				"queueName": queue.Name,
+				"error":     err,
+			})
+			return archived, err
+		}
+		if len(resp.Messages) == 0 {
+			return archived, nil
+		}
+
+		for _, message := range resp.Messages {
+			if err = queue.archiveMessageToS3(ctx, s3Client, bucket, prefix, message); err != nil {
+				return archived, err
+			}
+
+			_, err = client.DeleteMessageWithContext(ctx, &sqs.DeleteMessageInput{
+				QueueUrl:      aws.String(queue.DeadLetterQueueURL),
+				ReceiptHandle: message.ReceiptHandle,
+			})
+			if err != nil {
+				queue.GetLogger().Error("Deleting archived message from dead letter queue", Fields{
+					"queueName": queue.Name,
+					"messageID": message.MessageId,
+					"error":     err,
+				})
+				return archived, err
+			}
+			archived++
+		}
+	}
+}
+
+// archiveMessageToS3 writes the message as a JSON line to S3.
+func (queue *Queue) archiveMessageToS3(ctx context.Context, s3Client s3iface.S3API, bucket, prefix string, message *sqs.Message) error {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	messageJSON = append(messageJSON, '\n')
+
+	key := path.Join(prefix, queue.Name, time.Now().UTC().Format("2006-01-02"), aws.StringValue(message.MessageId)+".json")
+	_, err = s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(messageJSON),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		queue.GetLogger().Error("Archiving dead letter message to S3", Fields{
+			"queueName": queue.Name,
+			"messageID": message.MessageId,
+			"bucket":    bucket,
+			"key":       key,
+			"error":     err,
+		})
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// deadLetterMessages returns a client with the messages of the IDs waiting in the dead letter queue.
+func deadLetterMessages(ids ...string) *fakeClient {
+	client := newFakeClient()
+	for _, id := range ids {
+		client.messages = append(client.messages, &sqs.Message{
+			MessageId:     aws.String(id),
+			ReceiptHandle: aws.String("receipt-" + id),
+			Body:          aws.String(`"` + id + `"`),
+		})
+	}
+	return client
+}
+
+func TestForwardDLQToS3(t *testing.T) {
+	client := deadLetterMessages("first", "second")
+	q, err := queue.New("applications", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3Client := newMemoryS3()
+
+	archived, err := q.ForwardDLQToS3(context.Background(), s3Client, "archive", "dlq")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if archived != 2 {
+		t.Errorf("expected 2 archived messages, got %d", archived)
+	}
+	day := time.Now().UTC().Format("2006-01-02")
+	for _, id := range []string{"first", "second"} {
+		body, ok := s3Client.objects["archive/dlq/applications/"+day+"/"+id+".json"]
+		if !ok {
+			t.Fatalf("expected %s to be archived by queue and day, got %v", id, s3Client.keys())
+		}
+		var message sqs.Message
+		if err := json.Unmarshal(body, &message); err != nil || aws.StringValue(message.MessageId) != id {
+			t.Errorf("expected the archived message %s, got %s and %v", id, body, err)
+		}
+	}
+	if len(client.deleteInputs) != 2 {
+		t.Fatalf("expected the archived messages to be deleted, got %v", client.deleteInputs)
+	}
+	for i, expected := range []string{"receipt-first", "receipt-second"} {
+		input := client.deleteInputs[i]
+		if aws.StringValue(input.QueueUrl) != q.DeadLetterQueueURL || aws.StringValue(input.ReceiptHandle) != expected {
+			t.Errorf("expected %s to be deleted from the dead letter queue, got %v", expected, input)
+		}
+	}
+}
+
+func TestForwardDLQToS3PutFailure(t *testing.T) {
+	client := deadLetterMessages("first")
+	q, err := queue.New("applications", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3Client := newMemoryS3()
+	s3Client.putErr = errors.New("access denied")
+
+	archived, err := q.ForwardDLQToS3(context.Background(), s3Client, "archive", "dlq")
+
+	if archived != 0 || err != s3Client.putErr {
+		t.Errorf("expected the put error, got %d archived and %v", archived, err)
+	}
+	if len(client.deleteInputs) != 0 {
+		t.Errorf("expected the message to stay in the dead letter queue, got %v", client.deleteInputs)
+	}
+}
+
+func TestForwardDLQToS3WithoutDeadLetterQueue(t *testing.T) {
+	q, err := queue.New("applications", queue.WithClient(newFakeClient()), queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.ForwardDLQToS3(context.Background(), newMemoryS3(), "archive", "dlq"); err != queue.ErrNoDeadLetterQueue {
+		t.Errorf("expected ErrNoDeadLetterQueue, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// DeadLetterInfo describes the failures of a dead-lettered message.
+type DeadLetterInfo struct {
+	// ReceiveCount is how many times the message was received, including the receives from the queue.
+	ReceiveCount int
+	// SentAt is when the message was originally sent.
+	SentAt time.Time
+}
+
+// DeadLetterHandlerFunc handles a decoded message of the dead letter queue, it is deleted when it returns nil.
+type DeadLetterHandlerFunc func(ctx context.Context, body interface{}, message *sqs.Message, info DeadLetterInfo) error
+
+// DeadLetterProcessor returns a Processor polling the dead letter queue of the queue, the messages are deleted from there.
+// ErrNoDeadLetterQueue is returned when the queue has none.
+func (queue *Queue) DeadLetterProcessor(handler DeadLetterHandlerFunc) (*Processor, error) {
+	if queue.DeadLetterQueueURL == "" {
+		return nil, ErrNoDeadLetterQueue
+	}
+
+	return &Processor{
+		Queue: queue.getDeadLetterQueue(),
+		handleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			var info DeadLetterInfo
+			info.ReceiveCount, _ = ReceiveCount(message)
+			info.SentAt, _ = SentTime(message)
+
+			return handler(ctx, body, message, info)
+		},
+	}, nil
+}
@@ -0,0 +1,88 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// deadLetter receives the only message of the queue twice without deleting it,
+// so the in-memory queue moves it to the dead letter queue with a maximum receive count of 1.
+func deadLetter(t *testing.T, q *queue.Queue) {
+	t.Helper()
+
+	for i := 0; i < 2; i++ {
+		if _, err := q.ReceiveMessage(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestDeadLetterProcessorEncrypted(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "encrypted",
+		queue.WithReceiveWaitTime(0),
+		queue.WithReceiveVisibilityTimeout(0),
+		queue.WithMaxReceiveCount(1),
+		queue.WithPayloadEncryption(encryptionTestKey),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage(encryptedTestMessage{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	deadLetter(t, q)
+	if bodies := client.Messages(q.DeadLetterQueueURL); len(bodies) != 1 {
+		t.Fatalf("expected the message in the dead letter queue, got %d", len(bodies))
+	}
+
+	var received encryptedTestMessage
+	var info queue.DeadLetterInfo
+	processor, err := q.DeadLetterProcessor(func(ctx context.Context, body interface{}, message *sqs.Message, deadLetterInfo queue.DeadLetterInfo) error {
+		email, _ := body.(map[string]interface{})["email"].(string)
+		received.Email = email
+		info = deadLetterInfo
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if processor.Queue.URL != q.DeadLetterQueueURL {
+		t.Errorf("expected the dead letter queue to be polled, got %s", processor.Queue.URL)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	summary, err := processor.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 1 || summary.Failed != 0 {
+		t.Errorf("expected the message to be handled, got %+v", summary)
+	}
+	if received.Email != "user@example.com" {
+		t.Errorf("expected the decrypted body, got %+v", received)
+	}
+	if info.ReceiveCount != 2 {
+		t.Errorf("expected the receive count of both queues, got %d", info.ReceiveCount)
+	}
+	if bodies := client.Messages(q.DeadLetterQueueURL); len(bodies) != 0 {
+		t.Errorf("expected the message to be deleted from the dead letter queue, %d left", len(bodies))
+	}
+}
+
+func TestDeadLetterProcessorWithoutDeadLetterQueue(t *testing.T) {
+	q, err := memqueue.New("alone", queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.DeadLetterProcessor(nil); err != queue.ErrNoDeadLetterQueue {
+		t.Errorf("expected ErrNoDeadLetterQueue, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// A DecodedMessage is a received message with it's decoded body.
+type DecodedMessage struct {
+	Body    interface{}
+	Message *sqs.Message
+}
+
+// Failed is a message of a batch that could not be handled, it is left in the queue for redelivery.
+type Failed struct {
+	Message *sqs.Message
+	Err     error
+}
+
+// processDecodedBatches passes the messages of each receive from the queues of the poller to HandleDecodedBatch until the processor stops.
+// The messages are received within ctx, then handled and deleted within handlerCtx, so a shutdown lets the last batch finish.
+// It returns the error of a missing queue.
+func (processor *Processor) processDecodedBatches(ctx context.Context, handlerCtx context.Context, poller *queuePoller, template interface{}, queueDetails Fields) error {
+	for ctx.Err() == nil {
+		if processor.maxMessagesReached() {
+			processor.getLogger().Info("Processing queue stopped, maximum number of messages reached", queueDetails)
+			return nil
+		}
+
+		processor.waitForHealthyDependencies(ctx)
+		max := processor.waitForRate(ctx, MaxBatchSize)
+		if max == 0 {
+			continue
+		}
+
+		received, messages, err := poller.poll(ctx, receiveUpTo(max))
+		if err != nil {
+			return err
+		}
+		if len(messages) > 0 {
+			processor.chargeRate(handlerCtx, len(messages))
+			received.handleDecodedBatch(handlerCtx, messages, template)
+		}
+	}
+
+	return nil
+}
+
+// handleDecodedBatch decodes the received messages and passes them to HandleDecodedBatch.
+// Messages that fail to decode are moved to the dead letter queue like in processMessage, without aborting the batch.
+// It returns how many messages were handled successfully and how many failed.
+func (processor *Processor) handleDecodedBatch(ctx context.Context, messages []*sqs.Message, template interface{}) (processed int, failed int) {
+	metrics := processor.getMetrics()
+	decoded := make([]DecodedMessage, 0, len(messages))
+	for _, message := range messages {
+		processor.recordReceived(message)
+
+		body := processor.newBody(template)
+		if err := processor.decodeMessage(ctx, message, &body); err != nil {
+			processor.getLogger().Warn("Error unmarshalling message", Fields{
+				"error":     err,
+				"messageID": message.MessageId,
+				"queueName": processor.Queue.Name,
+			})
+			processor.recordFailed(err)
+			var decodeErr *DecodeError
+			if errors.As(err, &decodeErr) {
+				processor.deadLetterMessage(ctx, message, err)
+			}
+			continue
+		}
+		if err := processor.Queue.validateIncoming(body, message); err != nil {
+			processor.recordFailed(err)
+			processor.deadLetterMessage(ctx, message, err)
+			continue
+		}
+		decoded = append(decoded, DecodedMessage{Body: body, Message: message})
+	}
+	if len(decoded) == 0 {
+		return 0, len(messages)
+	}
+
+	started := time.Now()
+	failures, err := processor.HandleDecodedBatch(ctx, decoded)
+	finished := time.Now()
+	duration := finished.Sub(started)
+	deletable := processor.archiveBatch(ctx, decodedMessages(decoded), func(message *sqs.Message) Outcome {
+		return Outcome{Err: batchMessageError(message, failures, err), StartedAt: started, FinishedAt: finished}
+	})
+	if err != nil {
+		for range decoded {
+			processor.recordFailed(err)
+		}
+		processor.getLogger().Warn("Error processing message batch", Fields{
+			"error":     err,
+			"messages":  len(decoded),
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+		return 0, len(messages)
+	}
+
+	succeeded := handledMessages(decoded, failures)
+	for _, failure := range failures {
+		processor.recordFailed(failure.Err)
+		processor.getLogger().Warn("Error processing message", Fields{
+			"error":     failure.Err,
+			"messageID": failure.Message.MessageId,
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+	}
+	for range succeeded {
+		metrics.MessageProcessed(duration)
+	}
+	processor.getState().processedMessages.Add(int64(len(succeeded)))
+	processed, failed = len(succeeded), len(messages)-len(succeeded)
+	if len(deletable) == 0 {
+		return
+	}
+
+	result, err := processor.Queue.DeleteMessagesContext(ctx, deletable)
+	for range result.Deleted {
+		processor.recordDeleted()
+	}
+	if err != nil {
+		for _, failure := range result.Failed {
+			processor.getLogger().Warn("Error deleting queue message", Fields{
+				"messageID": failure.MessageID,
+				"error":     failure.Err,
+				"queueName": processor.Queue.Name,
+				"queueURL":  processor.Queue.URL,
+			})
+		}
+	}
+
+	return
+}
+
+// handledMessages returns the messages of the batch that are not reported as failed.
+func handledMessages(decoded []DecodedMessage, failed []Failed) (handled []*sqs.Message) {
+	failedMessages := make(map[*sqs.Message]bool, len(failed))
+	for _, failure := range failed {
+		failedMessages[failure.Message] = true
+	}
+
+	for _, message := range decoded {
+		if !failedMessages[message.Message] {
+			handled = append(handled, message.Message)
+		}
+	}
+
+	return
+}
+
+// decodedMessages returns the raw messages of the batch.
+func decodedMessages(decoded []DecodedMessage) (messages []*sqs.Message) {
+	messages = make([]*sqs.Message, len(decoded))
+	for i, message := range decoded {
+		messages[i] = message.Message
+	}
+
+	return
+}
+
+// batchMessageError returns the error of handling the message in a batch, the batch error or it's failure.
+func batchMessageError(message *sqs.Message, failed []Failed, batchErr error) error {
+	if batchErr != nil {
+		return batchErr
+	}
+	for _, failure := range failed {
+		if failure.Message == message {
+			return failure.Err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+)
+
+// decodedTestMessage is the body of the decoded batch tests.
+type decodedTestMessage struct {
+	ID int `json:"id"`
+}
+
+func TestDecodedBatchDeletesAfterShutdown(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "decoded", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{decodedTestMessage{ID: 1}, decodedTestMessage{ID: 2}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var ids []int
+	processor := &queue.Processor{
+		Queue: q,
+		NewBody: func() interface{} {
+			return new(decodedTestMessage)
+		},
+		HandleDecodedBatch: func(handlerCtx context.Context, messages []queue.DecodedMessage) ([]queue.Failed, error) {
+			cancel()
+			for _, message := range messages {
+				ids = append(ids, message.Body.(*decodedTestMessage).ID)
+			}
+			return nil, handlerCtx.Err()
+		},
+	}
+	if err := processor.ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 2 {
+		t.Errorf("expected both messages decoded in the batch, got %v", ids)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 0 {
+		t.Errorf("expected the handled batch to be deleted, %d messages left", len(bodies))
+	}
+}
+
+func TestDecodedBatchPartialFailure(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "decoded", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{decodedTestMessage{ID: 1}, decodedTestMessage{ID: 2}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	metrics := &queue.InMemoryMetrics{}
+	processor := &queue.Processor{
+		Queue:   q,
+		Metrics: metrics,
+		NewBody: func() interface{} {
+			return new(decodedTestMessage)
+		},
+		HandleDecodedBatch: func(ctx context.Context, messages []queue.DecodedMessage) (failed []queue.Failed, err error) {
+			cancel()
+			for _, message := range messages {
+				if message.Body.(*decodedTestMessage).ID == 2 {
+					failed = append(failed, queue.Failed{Message: message.Message, Err: errors.New("failed")})
+				}
+			}
+			return
+		},
+	}
+	processor.ProcessWithContext(ctx, nil)
+
+	if counts := metrics.Counts(); counts.Processed != 1 || counts.Failed != 1 || counts.Deleted != 1 {
+		t.Errorf("expected one handled and one failed message, got %+v", counts)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 1 || bodies[0] != `{"id":2}` {
+		t.Errorf("expected the failed message to be kept, got %v", bodies)
+	}
+}
+
+func TestDecodedBatchDeadLettersDecodeErrors(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "decoded", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendRawMessage(`{"id":`)
+	q.SendMessage(decodedTestMessage{ID: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	processor := &queue.Processor{
+		Queue: q,
+		NewBody: func() interface{} {
+			return new(decodedTestMessage)
+		},
+		HandleDecodedBatch: func(ctx context.Context, messages []queue.DecodedMessage) ([]queue.Failed, error) {
+			cancel()
+			return nil, nil
+		},
+	}
+	processor.ProcessWithContext(ctx, nil)
+
+	if bodies := client.Messages(q.URL); len(bodies) != 0 {
+		t.Errorf("expected no messages left, got %v", bodies)
+	}
+	if bodies := client.Messages(q.DeadLetterQueueURL); len(bodies) != 1 || bodies[0] != `{"id":` {
+		t.Errorf("expected the undecodable message in the dead letter queue, got %v", bodies)
+	}
+}
+
+func TestDecodedBatchDrainTimeout(t *testing.T) {
+	q, err := memqueue.New("decoded", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage(decodedTestMessage{ID: 1})
+
+	processor := &queue.Processor{
+		Queue:        q,
+		DrainTimeout: 50 * time.Millisecond,
+	}
+	processor.HandleDecodedBatch = func(ctx context.Context, messages []queue.DecodedMessage) ([]queue.Failed, error) {
+		processor.Stop()
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	if err := processor.ProcessWithContext(context.Background(), nil); err != queue.ErrDrainTimeout {
+		t.Errorf("expected ErrDrainTimeout, got %v", err)
+	}
+}
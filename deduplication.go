@@ -0,0 +1,174 @@
+package queue
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// DeduplicationIDAttribute is the message attribute identifying a message for WithDeduplication.
+const DeduplicationIDAttribute = "DeduplicationId"
+
+// DeduplicationMetrics is implemented by Metrics also counting the deduplication cache lookups of WithDeduplication.
+type DeduplicationMetrics interface {
+	// DuplicateSkipped is called for each message skipped as a duplicate of a handled one.
+	DuplicateSkipped()
+	// DeduplicationMiss is called for each message not handled within the deduplication window.
+	DeduplicationMiss()
+}
+
+// WithDeduplicationID sends the messages with a DeduplicationIDAttribute, the SHA-256 hash of the body as sent
+// unless the message already has one, for processors skipping duplicates with WithDeduplication.
+// The hash is of the encrypted or offloaded body, so it reveals nothing of the payload. Those bodies differ
+// on each send, so then only the redeliveries of a message are detected, not the same payload sent twice.
+func WithDeduplicationID() Option {
+	return func(queue *Queue) error {
+		queue.deduplicationID = true
+		return nil
+	}
+}
+
+// setDeduplicationID adds the hash of the prepared body as the deduplication ID of the message, unless it has one.
+func (queue *Queue) setDeduplicationID(params *sqs.SendMessageInput) {
+	if !queue.deduplicationID {
+		return
+	}
+	if _, ok := params.MessageAttributes[DeduplicationIDAttribute]; ok {
+		return
+	}
+
+	digest := sha256.Sum256([]byte(aws.StringValue(params.MessageBody)))
+	if params.MessageAttributes == nil {
+		params.MessageAttributes = make(map[string]*sqs.MessageAttributeValue)
+	}
+	params.MessageAttributes[DeduplicationIDAttribute] = StringAttribute(hex.EncodeToString(digest[:]))
+}
+
+// WithDeduplication skips the redelivered duplicates of standard queues on a best-effort basis.
+// The IDs of the handled messages are kept for ttl, up to size of them, the duplicates received within it are deleted
+// without handling. Messages are identified by their DeduplicationIDAttribute, or their message ID without one.
+// The cache is per processor, duplicates received by other processes or handled at the same time are not detected.
+func (processor *Processor) WithDeduplication(size int, ttl time.Duration) *Processor {
+	processor.deduplication = newDeduplicationCache(size, ttl)
+
+	return processor
+}
+
+// deduplicationCache is a bounded cache of the IDs of recently handled messages, safe for concurrent use.
+type deduplicationCache struct {
+	mutex   sync.Mutex
+	size    int
+	ttl     time.Duration
+	entries map[string]*list.Element
+	// order lists the entries from the oldest to the newest.
+	order *list.List
+}
+
+// deduplicationEntry is a handled message ID and when it expires.
+type deduplicationEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// newDeduplicationCache returns an empty cache.
+func newDeduplicationCache(size int, ttl time.Duration) *deduplicationCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &deduplicationCache{
+		size:    size,
+		ttl:     ttl,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// seen reports whether the ID was handled within the TTL.
+func (cache *deduplicationCache) seen(id string) bool {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[id]
+	if !ok {
+		return false
+	}
+	if time.Now().After(element.Value.(*deduplicationEntry).expiresAt) {
+		cache.order.Remove(element)
+		delete(cache.entries, id)
+		return false
+	}
+
+	return true
+}
+
+// add records the ID as handled, evicting the oldest entries over the size.
+func (cache *deduplicationCache) add(id string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.entries[id]; ok {
+		cache.order.Remove(element)
+	}
+	cache.entries[id] = cache.order.PushBack(&deduplicationEntry{id: id, expiresAt: time.Now().Add(cache.ttl)})
+
+	for cache.order.Len() > cache.size {
+		oldest := cache.order.Front()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*deduplicationEntry).id)
+	}
+}
+
+// deduplicationID returns the ID of the message for deduplication.
+func deduplicationID(message *sqs.Message) string {
+	if id, ok := GetStringAttribute(message, DeduplicationIDAttribute); ok {
+		return id
+	}
+
+	return aws.StringValue(message.MessageId)
+}
+
+// skipDuplicate deletes the message when it is a duplicate of a recently handled one, and reports whether it was.
+func (processor *Processor) skipDuplicate(ctx context.Context, message *sqs.Message) bool {
+	if processor.deduplication == nil {
+		return false
+	}
+
+	metrics, _ := processor.getMetrics().(DeduplicationMetrics)
+	if !processor.deduplication.seen(deduplicationID(message)) {
+		if metrics != nil {
+			metrics.DeduplicationMiss()
+		}
+		return false
+	}
+	if metrics != nil {
+		metrics.DuplicateSkipped()
+	}
+
+	processor.getLogger().Info("Skipping duplicate message", Fields{
+		"messageID": message.MessageId,
+		"queueName": processor.Queue.Name,
+	})
+	if _, err := processor.Queue.DeleteMessageContext(ctx, message); err != nil {
+		processor.getLogger().Warn("Error deleting duplicate message", Fields{
+			"error":     err,
+			"messageID": message.MessageId,
+			"queueName": processor.Queue.Name,
+		})
+	}
+
+	return true
+}
+
+// recordHandled adds the handled message to the deduplication cache.
+func (processor *Processor) recordHandled(message *sqs.Message) {
+	if processor.deduplication != nil {
+		processor.deduplication.add(deduplicationID(message))
+	}
+}
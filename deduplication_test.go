@@ -0,0 +1,138 @@
+package queue_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// sha256Hex returns the hex encoded SHA-256 hash of the body.
+func sha256Hex(body string) string {
+	digest := sha256.Sum256([]byte(body))
+
+	return hex.EncodeToString(digest[:])
+}
+
+func TestDeduplicationSkipsDuplicate(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "deduplicated", queue.WithReceiveWaitTime(0), queue.WithDeduplicationID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := q.SendMessage(map[string]string{"id": "1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var handled atomic.Int64
+	metrics := &queue.InMemoryMetrics{}
+	processor := &queue.Processor{
+		Queue:   q,
+		Metrics: metrics,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled.Add(1)
+			return nil
+		},
+	}
+	processor.WithDeduplication(10, time.Minute)
+	if _, err := processor.Drain(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if handled.Load() != 1 {
+		t.Errorf("expected the handler to run once, it ran %d times", handled.Load())
+	}
+	if counts := metrics.Counts(); counts.DuplicatesSkipped != 1 || counts.DeduplicationMisses != 1 {
+		t.Errorf("expected a skip and a miss, got %+v", counts)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 0 {
+		t.Errorf("expected the duplicate to be deleted, %d messages left", len(bodies))
+	}
+}
+
+func TestDeduplicationIDBatch(t *testing.T) {
+	q, err := memqueue.New("deduplicated", queue.WithReceiveWaitTime(0), queue.WithDeduplicationID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessages([]interface{}{"same", "same"}); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := q.ReceiveMessages(10)
+	if err != nil || len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d, %v", len(messages), err)
+	}
+	for _, message := range messages {
+		id, ok := queue.GetStringAttribute(message, queue.DeduplicationIDAttribute)
+		if !ok || id != sha256Hex(`"same"`) {
+			t.Errorf("expected the hash of the body as the deduplication ID, got %q", id)
+		}
+	}
+}
+
+func TestDeduplicationIDEncrypted(t *testing.T) {
+	q, err := memqueue.New("deduplicated", queue.WithReceiveWaitTime(0), queue.WithDeduplicationID(), queue.WithPayloadEncryption(encryptionTestKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage(encryptedTestMessage{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := q.ReceiveMessage()
+	if err != nil || message == nil {
+		t.Fatalf("expected a message, got %v", err)
+	}
+	id, ok := queue.GetStringAttribute(message, queue.DeduplicationIDAttribute)
+	if !ok {
+		t.Fatal("expected a deduplication ID")
+	}
+	if id == sha256Hex(`{"email":"user@example.com"}`) {
+		t.Error("the deduplication ID is the hash of the plaintext body")
+	}
+	if id != sha256Hex(*message.Body) {
+		t.Errorf("expected the hash of the encrypted body, got %s", id)
+	}
+}
+
+func TestProcessNDoesNotCountSkippedDuplicates(t *testing.T) {
+	q, err := memqueue.New("deduplicated", queue.WithReceiveWaitTime(0), queue.WithDeduplicationID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"same", "same", "other"} {
+		if _, err := q.SendMessage(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var handled atomic.Int64
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled.Add(1)
+			return nil
+		},
+	}
+	processor.WithDeduplication(10, time.Minute)
+	summary, err := processor.ProcessN(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary != (queue.ProcessSummary{Processed: 2, Skipped: 1}) {
+		t.Errorf("expected 2 processed messages and 1 skipped duplicate, got %+v", summary)
+	}
+	if handled.Load() != 2 {
+		t.Errorf("expected the handler to run twice, it ran %d times", handled.Load())
+	}
+}
@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Delete removes the queue, and it's dead letter queue when deleteDeadLetter is set.
+// Queues that do not exist any more are ignored, so the teardown is idempotent.
+// The URLs of the deleted queues are cleared, later operations on them return ErrQueueNotInitialized.
+func (queue *Queue) Delete(deleteDeadLetter bool) error {
+	return queue.DeleteContext(aws.BackgroundContext(), deleteDeadLetter)
+}
+
+// DeleteContext removes the queue, and it's dead letter queue when deleteDeadLetter is set, within the context.
+func (queue *Queue) DeleteContext(ctx context.Context, deleteDeadLetter bool) error {
+	if queue.URL != "" {
+		if err := queue.deleteQueueByURL(ctx, queue.URL); err != nil {
+			return err
+		}
+		queue.URL = ""
+	}
+
+	if deleteDeadLetter && queue.DeadLetterQueueURL != "" {
+		if err := queue.deleteQueueByURL(ctx, queue.DeadLetterQueueURL); err != nil {
+			return err
+		}
+		queue.DeadLetterQueueURL = ""
+	}
+
+	return nil
+}
+
+// deleteQueueByURL removes the queue by it's URL within the context, ignoring queues that do not exist.
+func (queue *Queue) deleteQueueByURL(ctx context.Context, url string) (err error) {
+	client := queue.GetClient()
+	params := &sqs.DeleteQueueInput{
+		QueueUrl: aws.String(url),
+	}
+	_, err = client.DeleteQueueWithContext(ctx, params)
+
+	if err != nil && !isQueueNotFound(err) {
+		queue.GetLogger().Error("Deleting queue", Fields{
+			"queueName": queue.Name,
+			"queueUrl":  url,
+			"error":     err,
+		})
+		return
+	}
+
+	queue.GetLogger().Info("Queue deleted", Fields{
+		"queueName": queue.Name,
+		"queueUrl":  url,
+	})
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package queue_test
+
+import (
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+)
+
+func TestDeleteQueueAndDeadLetterQueue(t *testing.T) {
+	client := &deletingClient{Client: memqueue.NewClient()}
+	q, err := queue.New("torn-down", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, deadLetterURL := q.URL, q.DeadLetterQueueURL
+
+	if err := q.Delete(true); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := client.deletedQueues(); len(deleted) != 2 || deleted[0] != url || deleted[1] != deadLetterURL {
+		t.Errorf("expected both queues to be deleted, got %v", deleted)
+	}
+	if q.URL != "" || q.DeadLetterQueueURL != "" {
+		t.Errorf("expected the URLs to be cleared, got %q and %q", q.URL, q.DeadLetterQueueURL)
+	}
+	if _, err := q.SendMessage("body"); err != queue.ErrQueueNotInitialized {
+		t.Errorf("expected ErrQueueNotInitialized after the delete, got %v", err)
+	}
+	if err := q.Delete(true); err != nil {
+		t.Errorf("expected deleting again to succeed, got %v", err)
+	}
+}
+
+func TestDeleteQueueOnly(t *testing.T) {
+	client := &deletingClient{Client: memqueue.NewClient()}
+	q, err := queue.New("torn-down", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	url, deadLetterURL := q.URL, q.DeadLetterQueueURL
+
+	if err := q.Delete(false); err != nil {
+		t.Fatal(err)
+	}
+
+	if deleted := client.deletedQueues(); len(deleted) != 1 || deleted[0] != url {
+		t.Errorf("expected only the queue to be deleted, got %v", deleted)
+	}
+	if q.URL != "" || q.DeadLetterQueueURL != deadLetterURL {
+		t.Errorf("expected only the queue URL to be cleared, got %q and %q", q.URL, q.DeadLetterQueueURL)
+	}
+}
+
+func TestDeleteMissingQueue(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "torn-down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := memqueue.NewWithClient(client, "torn-down")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.URL != q.URL {
+		t.Fatalf("expected the same queue, got %s and %s", other.URL, q.URL)
+	}
+
+	if err := q.Delete(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Delete(true); err != nil {
+		t.Errorf("expected the queues deleted elsewhere to be ignored, got %v", err)
+	}
+}
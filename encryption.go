@@ -0,0 +1,156 @@
+package queue
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ContentEncryptionAttribute is the message attribute of encrypted bodies, holding the algorithm and the key ID.
+const ContentEncryptionAttribute = "Content-Encryption"
+
+// Algorithm of the encrypted bodies, followed by the key ID in the attribute.
+const contentEncryptionAlgorithm = "AES-256-GCM"
+
+// Key ID of the key passed to WithPayloadEncryption.
+const defaultEncryptionKeyID = "default"
+
+// ErrInvalidEncryptionKey is returned for encryption keys that are not 32 bytes long.
+var ErrInvalidEncryptionKey = errors.New("encryption key must be 32 bytes for AES-256")
+
+// ErrDecryption is matched by the errors of bodies that could not be decrypted.
+var ErrDecryption = errors.New("message body could not be decrypted")
+
+// errEncryptionNotConfigured is the cause of decrypting without keys.
+var errEncryptionNotConfigured = errors.New("payload encryption is not configured")
+
+// A DecryptionError is the error of a body that could not be decrypted, e.g. with an unknown or wrong key.
+type DecryptionError struct {
+	KeyID string
+	Err   error
+}
+
+// Error returns the key ID and the error message.
+func (err *DecryptionError) Error() string {
+	return "decrypting message body with key " + err.KeyID + ": " + err.Err.Error()
+}
+
+// Is makes errors.Is(err, ErrDecryption) true.
+func (err *DecryptionError) Is(target error) bool {
+	return target == ErrDecryption
+}
+
+// Unwrap returns the underlying error.
+func (err *DecryptionError) Unwrap() error {
+	return err.Err
+}
+
+// payloadEncryption holds the AES-GCM keys of a queue.
+type payloadEncryption struct {
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// WithPayloadEncryption encrypts the sent message bodies with the 32 byte key using AES-256-GCM,
+// received bodies are decrypted before decoding.
+func WithPayloadEncryption(key []byte) Option {
+	return WithPayloadEncryptionKeys(defaultEncryptionKeyID, map[string][]byte{defaultEncryptionKeyID: key})
+}
+
+// WithPayloadEncryptionKeys encrypts the sent message bodies with the key of keyID, received bodies are decrypted
+// with the key named in their attribute, so old keys can be kept for decrypting while rotating.
+func WithPayloadEncryptionKeys(keyID string, keys map[string][]byte) Option {
+	return func(queue *Queue) error {
+		encryption := &payloadEncryption{
+			keyID: keyID,
+			keys:  make(map[string]cipher.AEAD, len(keys)),
+		}
+		for id, key := range keys {
+			if len(key) != 32 {
+				return ErrInvalidEncryptionKey
+			}
+			block, err := aes.NewCipher(key)
+			if err != nil {
+				return err
+			}
+			if encryption.keys[id], err = cipher.NewGCM(block); err != nil {
+				return err
+			}
+		}
+		if _, ok := encryption.keys[keyID]; !ok {
+			return ErrInvalidEncryptionKey
+		}
+
+		queue.encryption = encryption
+		return nil
+	}
+}
+
+// encryptPayload encrypts the body of the input and sets the encryption attribute.
+// Bodies that are encrypted already, e.g. when moving a message, are left as they are.
+func (queue *Queue) encryptPayload(params *sqs.SendMessageInput) error {
+	if queue.encryption == nil {
+		return nil
+	}
+	if _, ok := params.MessageAttributes[ContentEncryptionAttribute]; ok {
+		return nil
+	}
+
+	aead := queue.encryption.keys[queue.encryption.keyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(aws.StringValue(params.MessageBody)), nil)
+
+	params.MessageBody = aws.String(base64.StdEncoding.EncodeToString(sealed))
+	if params.MessageAttributes == nil {
+		params.MessageAttributes = make(map[string]*sqs.MessageAttributeValue)
+	}
+	params.MessageAttributes[ContentEncryptionAttribute] = StringAttribute(contentEncryptionAlgorithm + ";" + queue.encryption.keyID)
+
+	return nil
+}
+
+// decryptPayload returns the decrypted body of the message, or the body itself when it is not encrypted.
+func (queue *Queue) decryptPayload(message *sqs.Message, body string) (string, error) {
+	attribute, ok := GetStringAttribute(message, ContentEncryptionAttribute)
+	if !ok {
+		return body, nil
+	}
+
+	algorithm, keyID := attribute, ""
+	if separator := strings.IndexByte(attribute, ';'); separator >= 0 {
+		algorithm, keyID = attribute[:separator], attribute[separator+1:]
+	}
+	if algorithm != contentEncryptionAlgorithm {
+		return "", &DecryptionError{KeyID: keyID, Err: errors.New("unsupported algorithm " + algorithm)}
+	}
+	if queue.encryption == nil {
+		return "", &DecryptionError{KeyID: keyID, Err: errEncryptionNotConfigured}
+	}
+	aead, ok := queue.encryption.keys[keyID]
+	if !ok {
+		return "", &DecryptionError{KeyID: keyID, Err: errors.New("unknown key")}
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(body)
+	if err != nil {
+		return "", &DecryptionError{KeyID: keyID, Err: err}
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", &DecryptionError{KeyID: keyID, Err: errors.New("body too short")}
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", &DecryptionError{KeyID: keyID, Err: err}
+	}
+
+	return string(plain), nil
+}
@@ -0,0 +1,116 @@
+package queue_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+)
+
+// encryptionTestKey is a 32 byte AES-256 key.
+var encryptionTestKey = bytes.Repeat([]byte{7}, 32)
+
+// encryptedTestMessage is the body sent to the encrypted queues.
+type encryptedTestMessage struct {
+	Email string `json:"email"`
+}
+
+func TestPayloadEncryptionRoundTrip(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "encrypted", queue.WithReceiveWaitTime(0), queue.WithPayloadEncryption(encryptionTestKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendMessage(encryptedTestMessage{Email: "single@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessages([]interface{}{encryptedTestMessage{Email: "batch@example.com"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessageBatchConcurrent(context.Background(), []queue.BatchEntry{{Body: encryptedTestMessage{Email: "concurrent@example.com"}}}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, body := range client.Messages(q.URL) {
+		if strings.Contains(body, "@example.com") {
+			t.Errorf("body sent in plaintext: %s", body)
+		}
+	}
+
+	messages, err := q.Receive(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(messages))
+	}
+	emails := map[string]bool{}
+	for _, message := range messages {
+		var body encryptedTestMessage
+		if err := message.Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		emails[body.Email] = true
+	}
+	for _, email := range []string{"single@example.com", "batch@example.com", "concurrent@example.com"} {
+		if !emails[email] {
+			t.Errorf("%s was not decrypted", email)
+		}
+	}
+}
+
+func TestPayloadEncryptionWrongKey(t *testing.T) {
+	client := memqueue.NewClient()
+	producer, err := memqueue.NewWithClient(client, "encrypted", queue.WithPayloadEncryptionKeys("old", map[string][]byte{"old": encryptionTestKey}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer, err := memqueue.NewWithClient(client, "encrypted", queue.WithReceiveWaitTime(0), queue.WithPayloadEncryptionKeys("new", map[string][]byte{
+		"old": bytes.Repeat([]byte{8}, 32),
+		"new": bytes.Repeat([]byte{9}, 32),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := producer.SendMessage(encryptedTestMessage{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := consumer.Receive(context.Background(), 1)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected a message, got %d, %v", len(messages), err)
+	}
+
+	var body encryptedTestMessage
+	err = messages[0].Decode(&body)
+	var decryptionErr *queue.DecryptionError
+	if !errors.As(err, &decryptionErr) || !errors.Is(err, queue.ErrDecryption) {
+		t.Fatalf("expected a DecryptionError, got %v", err)
+	}
+	if decryptionErr.KeyID != "old" {
+		t.Errorf("expected the key ID of the message, got %s", decryptionErr.KeyID)
+	}
+}
+
+func TestWithPayloadEncryptionInvalidKey(t *testing.T) {
+	if _, err := memqueue.New("encrypted", queue.WithPayloadEncryption([]byte("short"))); err != queue.ErrInvalidEncryptionKey {
+		t.Errorf("expected ErrInvalidEncryptionKey, got %v", err)
+	}
+}
+
+func TestSendMessagesNotInitialized(t *testing.T) {
+	q := &queue.Queue{Name: "uninitialized"}
+	q.SetClient(memqueue.NewClient())
+
+	if _, err := q.SendMessages([]interface{}{"body"}); err != queue.ErrQueueNotInitialized {
+		t.Errorf("expected ErrQueueNotInitialized, got %v", err)
+	}
+	if _, err := q.SendMessageBatchConcurrent(context.Background(), []queue.BatchEntry{{Body: "body"}}, 1); err != queue.ErrQueueNotInitialized {
+		t.Errorf("expected ErrQueueNotInitialized, got %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Attributes reconciled by EnsureQueue on existing queues.
+var reconciledAttributes = []string{
+	sqs.QueueAttributeNameMessageRetentionPeriod,
+	sqs.QueueAttributeNameRedrivePolicy,
+}
+
+// An EnsureSummary reports what EnsureQueue did.
+type EnsureSummary struct {
+	// Created holds the names of the created queues.
+	Created []string
+	// Changed holds the names of the changed attributes by queue name.
+	Changed map[string][]string
+}
+
+// EnsureQueue returns a prepared SQS queue like New, but existing queues with drifted attributes are updated
+// instead of failing with QueueAlreadyExists. MessageRetentionPeriod and RedrivePolicy are reconciled.
+func EnsureQueue(name string, opts ...Option) (*Queue, EnsureSummary, error) {
+	queue := Queue{Name: name}
+	for _, opt := range opts {
+		if err := opt(&queue); err != nil {
+			return &queue, EnsureSummary{}, err
+		}
+	}
+	summary, err := queue.EnsureContext(aws.BackgroundContext())
+
+	return &queue, summary, err
+}
+
+// EnsureContext creates the queue and it's dead letter queue, or reconciles their attributes when they exist.
+// Names SQS would reject return an ErrInvalidQueueName before calling the API, as for InitContext.
+func (queue *Queue) EnsureContext(ctx context.Context) (summary EnsureSummary, err error) {
+	summary.Changed = map[string][]string{}
+	if err = queue.prepareName(); err != nil {
+		return
+	}
+
+	var redrivePolicyString *string
+	switch {
+	case queue.withoutDeadLetterQueue:
+	case queue.deadLetterQueue != "":
+		redrivePolicyString, err = queue.attachDeadLetterQueue(ctx, queue.deadLetterQueue)
+	default:
+		queue.DeadLetterQueueURL, err = queue.ensureQueueByName(ctx, queue.getDeadLetterQueueName(), queue.getDeadLetterQueueAttributes(), &summary)
+		if err == nil {
+			err = queue.applyTags(ctx, queue.DeadLetterQueueURL)
+		}
+		if err == nil {
+			redrivePolicyString, err = queue.getDeadLetterRedrivePolicy(ctx, "")
+		}
+	}
+	if err != nil {
+		return
+	}
+
+	queue.URL, err = queue.ensureQueueByName(ctx, queue.Name, queue.getQueueAttributes(redrivePolicyString), &summary)
+	if err != nil {
+		return
+	}
+	err = queue.applyTags(ctx, queue.URL)
+
+	return
+}
+
+// ensureQueueByName creates the queue, or reconciles the attributes of the existing queue, and returns it's URL.
+func (queue *Queue) ensureQueueByName(ctx context.Context, name string, attributes map[string]*string, summary *EnsureSummary) (url string, err error) {
+	client := queue.GetClient()
+
+	url, err = GetQueueURL(ctx, client, name)
+	if errors.Is(err, ErrQueueNotFound) {
+		resp, err := client.CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+			QueueName:  aws.String(name),
+			Attributes: attributes,
+		})
+		if err != nil {
+			queue.GetLogger().Error("Creating the queue", Fields{
+				"queueName": name,
+				"error":     err,
+			})
+			return "", err
+		}
+
+		summary.Created = append(summary.Created, name)
+		queue.GetLogger().Info("Queue initialized", Fields{
+			"QueueUrl": aws.StringValue(resp.QueueUrl),
+		})
+		return aws.StringValue(resp.QueueUrl), nil
+	}
+	if err != nil {
+		return
+	}
+
+	resp, err := queue.getAttributesByQueueURL(ctx, url, aws.StringSlice(reconciledAttributes))
+	if err != nil {
+		return
+	}
+	changes := map[string]*string{}
+	for _, attributeName := range reconciledAttributes {
+		desired, ok := attributes[attributeName]
+		if !ok || attributeEqual(attributeName, aws.StringValue(resp.Attributes[attributeName]), aws.StringValue(desired)) {
+			continue
+		}
+		changes[attributeName] = desired
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	if err = queue.setAttributesByQueueURL(ctx, url, changes); err != nil {
+		return
+	}
+	changed := make([]string, 0, len(changes))
+	for attributeName := range changes {
+		changed = append(changed, attributeName)
+	}
+	sort.Strings(changed)
+	summary.Changed[name] = changed
+	queue.GetLogger().Info("Queue attributes reconciled", Fields{
+		"queueName":  name,
+		"attributes": changed,
+	})
+
+	return
+}
+
+// attributeEqual compares attribute values, JSON attributes like the RedrivePolicy are compared by content.
+func attributeEqual(name string, current string, desired string) bool {
+	if name != sqs.QueueAttributeNameRedrivePolicy || current == "" || desired == "" {
+		return current == desired
+	}
+
+	var currentValues, desiredValues map[string]interface{}
+	if json.Unmarshal([]byte(current), &currentValues) != nil || json.Unmarshal([]byte(desired), &desiredValues) != nil {
+		return current == desired
+	}
+	if len(currentValues) != len(desiredValues) {
+		return false
+	}
+	for key, value := range desiredValues {
+		// SQS may return numbers as strings.
+		if fmt.Sprint(currentValues[key]) != fmt.Sprint(value) {
+			return false
+		}
+	}
+
+	return true
+}
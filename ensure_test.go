@@ -0,0 +1,63 @@
+package queue_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestEnsureQueue(t *testing.T) {
+	client := memqueue.NewClient()
+
+	q, summary, err := queue.EnsureQueue("ensured", queue.WithClient(client), queue.WithDeadLetterSuffix("-dlq"), queue.WithRetentionPeriod(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(summary.Created, []string{"ensured-dlq", "ensured"}) {
+		t.Errorf("expected the queue and it's dead letter queue to be created, got %v", summary.Created)
+	}
+	if q.URL == "" || q.DeadLetterQueueURL == "" {
+		t.Fatalf("expected the queue URLs, got %q and %q", q.URL, q.DeadLetterQueueURL)
+	}
+
+	_, summary, err = queue.EnsureQueue("ensured", queue.WithClient(client), queue.WithDeadLetterSuffix("-dlq"), queue.WithRetentionPeriod(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Created) != 0 || len(summary.Changed) != 0 {
+		t.Errorf("expected nothing to change for matching queues, got %+v", summary)
+	}
+
+	_, summary, err = queue.EnsureQueue("ensured", queue.WithClient(client), queue.WithDeadLetterSuffix("-dlq"), queue.WithRetentionPeriod(48*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Created) != 0 {
+		t.Errorf("expected no queue to be created, got %v", summary.Created)
+	}
+	if changed := summary.Changed[q.Name]; !reflect.DeepEqual(changed, []string{sqs.QueueAttributeNameMessageRetentionPeriod}) {
+		t.Errorf("expected the drifted retention period to be reconciled, got %v", summary.Changed)
+	}
+	resp, err := q.GetAttributesByQueueURL(q.URL, []*string{aws.String(sqs.QueueAttributeNameMessageRetentionPeriod)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retention := aws.StringValue(resp.Attributes[sqs.QueueAttributeNameMessageRetentionPeriod]); retention != "172800" {
+		t.Errorf("expected the retention period of 2 days, got %s", retention)
+	}
+}
+
+func TestEnsureQueueInvalidName(t *testing.T) {
+	_, _, err := queue.EnsureQueue(strings.Repeat("a", queue.MaxQueueNameLength+1), queue.WithClient(memqueue.NewClient()))
+
+	if !errors.Is(err, queue.ErrInvalidQueueName) {
+		t.Errorf("expected ErrInvalidQueueName, got %v", err)
+	}
+}
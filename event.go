@@ -0,0 +1,121 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Version of the events sent with SendEvent.
+const defaultEventVersion = 1
+
+// ErrUnknownEventType is the error of events without a handler in the Router.
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// An Envelope wraps the payload of an event with it's type, version and time.
+type Envelope struct {
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	OccurredAt time.Time       `json:"occurredAt"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// SendEvent will send the payload to the queue wrapped in an Envelope of the event type.
+func (queue *Queue) SendEvent(eventType string, payload interface{}) (resp *sqs.SendMessageOutput, err error) {
+	return queue.SendEventContext(aws.BackgroundContext(), eventType, defaultEventVersion, payload)
+}
+
+// SendEventContext will send the payload to the queue wrapped in an Envelope of the event type and version within the context.
+// The envelope is always JSON, the payload is encoded with the marshaller of the queue.
+func (queue *Queue) SendEventContext(ctx context.Context, eventType string, version int, payload interface{}) (resp *sqs.SendMessageOutput, err error) {
+	encodedPayload, err := queue.marshalMessageBody(payload)
+	if err != nil {
+		return
+	}
+	envelope, err := json.Marshal(Envelope{
+		Type:       eventType,
+		Version:    version,
+		OccurredAt: time.Now().UTC(),
+		Payload:    json.RawMessage(encodedPayload),
+	})
+	if err != nil {
+		return
+	}
+
+	return queue.sendRawMessage(ctx, string(envelope), nil)
+}
+
+// EventHandlerFunc handles the raw payload of an event, it decodes the payload itself.
+type EventHandlerFunc func(ctx context.Context, payload json.RawMessage, message *sqs.Message) error
+
+// UnknownEventPolicy is what a Router does with events without a handler.
+type UnknownEventPolicy int
+
+const (
+	// UnknownEventError fails the message, it is retried.
+	UnknownEventError UnknownEventPolicy = iota
+	// UnknownEventIgnore deletes the message.
+	UnknownEventIgnore
+	// UnknownEventDeadLetter moves the message to the dead letter queue.
+	UnknownEventDeadLetter
+)
+
+// A Router passes the events of a queue to the handler registered for their type.
+type Router struct {
+	// Unknown is what happens to events without a handler, they fail by default.
+	Unknown UnknownEventPolicy
+
+	handlers map[string]EventHandlerFunc
+}
+
+// NewRouter returns a Router without handlers.
+func NewRouter() *Router {
+	return &Router{handlers: make(map[string]EventHandlerFunc)}
+}
+
+// Handle registers the handler for the event type.
+func (router *Router) Handle(eventType string, handler EventHandlerFunc) *Router {
+	router.handlers[eventType] = handler
+
+	return router
+}
+
+// Processor returns a Processor routing the events of the queue. The envelopes are decoded once as JSON.
+func (router *Router) Processor(queue *Queue) *Processor {
+	return &Processor{
+		Queue:      queue,
+		Marshaller: JSONMarshaller{},
+		NewBody: func() interface{} {
+			return new(Envelope)
+		},
+		handleMessage: router.route,
+	}
+}
+
+// route passes the decoded envelope to the handler of it's type.
+func (router *Router) route(ctx context.Context, body interface{}, message *sqs.Message) error {
+	envelope, ok := body.(*Envelope)
+	if !ok || envelope == nil {
+		return PermanentError(fmt.Errorf("%w: message is not an event", ErrUnknownEventType))
+	}
+
+	handler, ok := router.handlers[envelope.Type]
+	if ok {
+		return handler(ctx, envelope.Payload, message)
+	}
+
+	err := fmt.Errorf("%w: %q", ErrUnknownEventType, envelope.Type)
+	switch router.Unknown {
+	case UnknownEventIgnore:
+		return nil
+	case UnknownEventDeadLetter:
+		return PermanentError(err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,136 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// userCreated is the payload of the events of the tests.
+type userCreated struct {
+	Email string `json:"email"`
+}
+
+func TestSendEventEnvelope(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "events", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := time.Now().UTC()
+	if _, err := q.SendEvent("user.created", userCreated{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now().UTC()
+
+	bodies := client.Messages(q.URL)
+	if len(bodies) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(bodies))
+	}
+	var envelope queue.Envelope
+	if err := json.Unmarshal([]byte(bodies[0]), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Type != "user.created" || envelope.Version != 1 {
+		t.Errorf("expected the type and default version in the envelope, got %+v", envelope)
+	}
+	if envelope.OccurredAt.Before(before) || envelope.OccurredAt.After(after) {
+		t.Errorf("expected the time of sending in the envelope, got %s", envelope.OccurredAt)
+	}
+	var payload userCreated
+	if err := json.Unmarshal(envelope.Payload, &payload); err != nil || payload.Email != "user@example.com" {
+		t.Errorf("expected the payload in the envelope, got %s", envelope.Payload)
+	}
+}
+
+func TestRouterRoutesByType(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "routed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendEventContext(context.Background(), "user.created", 2, userCreated{Email: "user@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var received userCreated
+	deletedCalled := false
+	router := queue.NewRouter().
+		Handle("user.created", func(ctx context.Context, payload json.RawMessage, message *sqs.Message) error {
+			return json.Unmarshal(payload, &received)
+		}).
+		Handle("user.deleted", func(ctx context.Context, payload json.RawMessage, message *sqs.Message) error {
+			deletedCalled = true
+			return nil
+		})
+	summary, err := router.Processor(q).ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 1 || received.Email != "user@example.com" {
+		t.Errorf("expected the event to be routed to it's handler, got %+v and %+v", summary, received)
+	}
+	if deletedCalled {
+		t.Error("expected the handler of another type not to be called")
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 0 {
+		t.Errorf("expected the handled event to be deleted, got %d messages", remaining)
+	}
+}
+
+func TestRouterUnknownEventType(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      queue.UnknownEventPolicy
+		summary     queue.ProcessSummary
+		remaining   int
+		deadLetters int
+	}{
+		{name: "error", policy: queue.UnknownEventError, summary: queue.ProcessSummary{Failed: 1}, remaining: 1},
+		{name: "ignore", policy: queue.UnknownEventIgnore, summary: queue.ProcessSummary{Processed: 1}},
+		{name: "dead letter", policy: queue.UnknownEventDeadLetter, summary: queue.ProcessSummary{Failed: 1}, deadLetters: 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := memqueue.NewClient()
+			q, err := memqueue.NewWithClient(client, "unknown", queue.WithReceiveWaitTime(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.SendEvent("user.renamed", userCreated{}); err != nil {
+				t.Fatal(err)
+			}
+
+			called := false
+			router := queue.NewRouter().Handle("user.created", func(ctx context.Context, payload json.RawMessage, message *sqs.Message) error {
+				called = true
+				return nil
+			})
+			router.Unknown = test.policy
+			summary, err := router.Processor(q).ProcessN(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if called {
+				t.Error("expected no handler to be called for an unknown type")
+			}
+			if summary != test.summary {
+				t.Errorf("expected %+v, got %+v", test.summary, summary)
+			}
+			if remaining := len(client.Messages(q.URL)); remaining != test.remaining {
+				t.Errorf("expected %d messages to stay in the queue, got %d", test.remaining, remaining)
+			}
+			if deadLetters := len(client.Messages(q.DeadLetterQueueURL)); deadLetters != test.deadLetters {
+				t.Errorf("expected %d messages in the dead letter queue, got %d", test.deadLetters, deadLetters)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package queue
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// A FanoutResult holds the outcome of SendToAll for each queue, in the order of the queues.
+type FanoutResult []FanoutSend
+
+// A FanoutSend is the outcome of sending to one queue.
+type FanoutSend struct {
+	Queue     *Queue
+	MessageID string
+	Err       error
+}
+
+// Failed returns the queues the body was not sent to, e.g. for retrying them.
+func (result FanoutResult) Failed() (queues []*Queue) {
+	for _, send := range result {
+		if send.Err != nil {
+			queues = append(queues, send.Queue)
+		}
+	}
+
+	return
+}
+
+// SendToAll sends the body to every queue concurrently, it is marshalled once with the marshaller of the first queue.
+// All sends are attempted, a MultiError is returned when some failed.
+func SendToAll(ctx context.Context, body interface{}, queues ...*Queue) (FanoutResult, error) {
+	return sendToAll(ctx, body, false, queues)
+}
+
+// SendToAllFailFast sends the body to every queue like SendToAll, but cancels the remaining sends on the first failure.
+// Cancelled sends have the context error in the result.
+func SendToAllFailFast(ctx context.Context, body interface{}, queues ...*Queue) (FanoutResult, error) {
+	return sendToAll(ctx, body, true, queues)
+}
+
+// sendToAll sends the body to every queue concurrently.
+func sendToAll(ctx context.Context, body interface{}, failFast bool, queues []*Queue) (FanoutResult, error) {
+	if len(queues) == 0 {
+		return nil, nil
+	}
+	msg, err := queues[0].marshalMessageBody(body)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := make(FanoutResult, len(queues))
+	var wg sync.WaitGroup
+	for i, queue := range queues {
+		wg.Add(1)
+		go func(i int, queue *Queue) {
+			defer wg.Done()
+
+			result[i].Queue = queue
+			resp, err := queue.sendRawMessage(ctx, msg, nil)
+			if err != nil {
+				result[i].Err = err
+				if failFast {
+					cancel()
+				}
+				return
+			}
+			result[i].MessageID = aws.StringValue(resp.MessageId)
+		}(i, queue)
+	}
+	wg.Wait()
+
+	var multiErr MultiError
+	for _, send := range result {
+		if send.Err != nil {
+			multiErr = append(multiErr, &QueueError{Queue: send.Queue.Name, Err: send.Err})
+		}
+	}
+	if len(multiErr) > 0 {
+		return result, multiErr
+	}
+
+	return result, nil
+}
+
+// A QueueError is an error of an operation on one of several queues.
+type QueueError struct {
+	Queue string
+	Err   error
+}
+
+// Error returns the queue name and the error message.
+func (err *QueueError) Error() string {
+	return err.Queue + ": " + err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *QueueError) Unwrap() error {
+	return err.Err
+}
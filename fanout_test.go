@@ -0,0 +1,105 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// fanoutClient fails the sends to the broken queue and holds the ones to the slow queue until they are cancelled.
+type fanoutClient struct {
+	*memqueue.Client
+	broken, slow string
+}
+
+func (client *fanoutClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	switch *input.QueueUrl {
+	case client.broken:
+		return nil, errors.New("send failed")
+	case client.slow:
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+	return client.Client.SendMessageWithContext(ctx, input, opts...)
+}
+
+// fanoutQueues returns a working, a broken and a slow queue of the client.
+func fanoutQueues(t *testing.T, client *fanoutClient) (working, broken, slow *queue.Queue) {
+	t.Helper()
+
+	queues := make([]*queue.Queue, 3)
+	for i, name := range []string{"working", "broken", "slow"} {
+		q, err := queue.New(name, queue.WithClient(client))
+		if err != nil {
+			t.Fatal(err)
+		}
+		queues[i] = q
+	}
+	client.broken, client.slow = queues[1].URL, queues[2].URL
+
+	return queues[0], queues[1], queues[2]
+}
+
+func TestSendToAllPartialFailure(t *testing.T) {
+	client := &fanoutClient{Client: memqueue.NewClient()}
+	working, broken, _ := fanoutQueues(t, client)
+	other, err := queue.New("other", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := queue.SendToAll(context.Background(), "body", working, broken, other)
+
+	var multiErr queue.MultiError
+	if !errors.As(err, &multiErr) || len(multiErr) != 1 {
+		t.Fatalf("expected a MultiError with the failed send, got %v", err)
+	}
+	var queueErr *queue.QueueError
+	if !errors.As(multiErr[0], &queueErr) || queueErr.Queue != "broken" || queueErr.Err.Error() != "send failed" {
+		t.Errorf("expected the QueueError of the broken queue, got %v", multiErr[0])
+	}
+	if failed := result.Failed(); len(failed) != 1 || failed[0] != broken {
+		t.Errorf("expected only the broken queue to fail, got %v", failed)
+	}
+	if len(result) != 3 || result[0].Queue != working || result[0].MessageID == "" || result[2].MessageID == "" {
+		t.Errorf("expected the message IDs of the other queues in order, got %+v", result)
+	}
+	if len(client.Messages(working.URL)) != 1 || len(client.Messages(other.URL)) != 1 {
+		t.Error("expected the body to be sent to the other queues")
+	}
+}
+
+func TestSendToAllFailFastCancelsRemaining(t *testing.T) {
+	client := &fanoutClient{Client: memqueue.NewClient()}
+	_, broken, slow := fanoutQueues(t, client)
+
+	begin := time.Now()
+	result, err := queue.SendToAllFailFast(context.Background(), "body", slow, broken)
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("expected the slow send to be cancelled, took %s", elapsed)
+	}
+
+	var multiErr queue.MultiError
+	if !errors.As(err, &multiErr) || len(multiErr) != 2 {
+		t.Fatalf("expected a MultiError with both sends, got %v", err)
+	}
+	if !errors.Is(result[0].Err, context.Canceled) {
+		t.Errorf("expected the slow send to be cancelled, got %v", result[0].Err)
+	}
+	if failed := result.Failed(); len(failed) != 2 {
+		t.Errorf("expected both queues to be failed, got %v", failed)
+	}
+	if len(client.Messages(slow.URL)) != 0 {
+		t.Error("expected nothing to be sent to the slow queue")
+	}
+}
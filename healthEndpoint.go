@@ -0,0 +1,142 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Time to wait for the health endpoint requests on shutdown.
+const healthEndpointShutdownTimeout = 5 * time.Second
+
+// ProcessorStats is a snapshot of the state of a Processor, the counters are totals since it was created.
+type ProcessorStats struct {
+	Processing bool  `json:"processing"`
+	Paused     bool  `json:"paused"`
+	Processed  int64 `json:"processed"`
+	Failed     int64 `json:"failed"`
+	Deleted    int64 `json:"deleted"`
+	// Polling reports whether the processor is waiting for a receive call, Handling the number of messages being handled.
+	Polling  bool  `json:"polling"`
+	Handling int64 `json:"handling"`
+	// LastReceive is the time of the last successful receive call, zero before the first one.
+	LastReceive time.Time `json:"lastReceive"`
+	// CircuitOpen reports whether the circuit breaker holds the receiving, also while probing half-open.
+	CircuitOpen bool `json:"circuitOpen"`
+}
+
+// Stats returns a snapshot of the state of the processor.
+func (processor *Processor) Stats() ProcessorStats {
+	state := processor.getState()
+
+	return ProcessorStats{
+		Processing:  state.processing.Load(),
+		Paused:      state.paused.Load(),
+		Processed:   state.processedMessages.Load(),
+		Failed:      state.failedMessages.Load(),
+		Deleted:     state.deletedMessages.Load(),
+		Polling:     state.polling.Load(),
+		Handling:    state.handling.Load(),
+		LastReceive: unixNanoTime(state.lastReceive.Load()),
+		CircuitOpen: processor.circuitOpen(),
+	}
+}
+
+// Healthy reports whether the processor is processing and received successfully within maxIdle,
+// e.g. for a liveness probe against a stuck consumer. Right after starting the start time counts as the last receive.
+func (processor *Processor) Healthy(maxIdle time.Duration) bool {
+	state := processor.getState()
+	if !state.processing.Load() {
+		return false
+	}
+
+	last := state.lastReceive.Load()
+	if started := state.startedAt.Load(); started > last {
+		last = started
+	}
+
+	return time.Since(unixNanoTime(last)) <= maxIdle
+}
+
+// unixNanoTime returns the time of the Unix nanoseconds, the zero time for 0.
+func unixNanoTime(nanoseconds int64) time.Time {
+	if nanoseconds == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(0, nanoseconds)
+}
+
+// WithHealthEndpoint starts an HTTP server on addr while Process runs.
+// It serves /health (200 while processing, 503 when paused, stopped or the circuit breaker is open),
+// /stats (ProcessorStats as JSON) and /metrics (Prometheus text when a metrics handler is configured).
+// Process returns the error when the address can not be listened on.
+func (processor *Processor) WithHealthEndpoint(addr string) *Processor {
+	processor.healthEndpointAddr = addr
+
+	return processor
+}
+
+// startHealthEndpoint starts the health endpoint server and returns the function shutting it down.
+func (processor *Processor) startHealthEndpoint() (stop func(), err error) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/health", processor.serveHealth)
+	mux.HandleFunc("/stats", processor.serveStats)
+	mux.HandleFunc("/metrics", processor.serveMetrics)
+
+	server := &http.Server{Handler: mux}
+	listener, err := net.Listen("tcp", processor.healthEndpointAddr)
+	if err != nil {
+		processor.getLogger().Error("Starting the processor health endpoint", Fields{
+			"queueName": processor.Queue.Name,
+			"addr":      processor.healthEndpointAddr,
+			"error":     err,
+		})
+		return nil, fmt.Errorf("starting the health endpoint on %s: %w", processor.healthEndpointAddr, err)
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			processor.getLogger().Error("Serving the processor health endpoint", Fields{
+				"queueName": processor.Queue.Name,
+				"error":     err,
+			})
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), healthEndpointShutdownTimeout)
+		defer cancel()
+		server.Shutdown(ctx)
+	}, nil
+}
+
+// serveHealth responds with 200 while the processor is processing, and the circuit breaker is closed.
+func (processor *Processor) serveHealth(w http.ResponseWriter, r *http.Request) {
+	stats := processor.Stats()
+	if !stats.Processing || stats.Paused || stats.CircuitOpen {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// serveStats responds with the ProcessorStats as JSON.
+func (processor *Processor) serveStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(processor.Stats())
+}
+
+// serveMetrics responds with the metrics of the configured metrics handler.
+func (processor *Processor) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if processor.MetricsHandler == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	processor.MetricsHandler.ServeHTTP(w, r)
+}
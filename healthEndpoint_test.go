@@ -0,0 +1,219 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// freeAddr returns a local address nothing listens on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	return listener.Addr().String()
+}
+
+// waitForStatus polls the URL until it responds with the status code, failing the test after a second.
+func waitForStatus(t *testing.T, url string, status int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err := http.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == status {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s did not respond with %d, last error %v", url, status, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestHealthEndpointCircuitOpen(t *testing.T) {
+	q, err := memqueue.New("health", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := freeAddr(t)
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return errors.New("database is down")
+		},
+	}).WithCircuitBreaker(1, time.Minute).WithEmptyReceivePause(10 * time.Millisecond).WithHealthEndpoint(addr)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.ProcessWithContext(ctx, nil)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	waitForStatus(t, "http://"+addr+"/health", http.StatusOK)
+	if _, err := q.SendMessage("body"); err != nil {
+		t.Fatal(err)
+	}
+	waitForStatus(t, "http://"+addr+"/health", http.StatusServiceUnavailable)
+
+	resp, err := http.Get("http://" + addr + "/stats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var stats queue.ProcessorStats
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Processing || !stats.CircuitOpen {
+		t.Errorf("expected a processing processor with an open circuit, got %+v", stats)
+	}
+}
+
+func TestHealthEndpointListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	q, err := memqueue.New("health", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handled := false
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled = true
+			return nil
+		},
+	}).WithHealthEndpoint(listener.Addr().String())
+	q.SendMessage("body")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := processor.ProcessWithContext(ctx, nil); err == nil || ctx.Err() != nil {
+		t.Fatalf("expected the listen error, got %v", err)
+	}
+	if handled {
+		t.Error("expected no messages to be handled without the health endpoint")
+	}
+}
+
+func TestStatsCountsMessages(t *testing.T) {
+	q, err := memqueue.New("stats", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("ok")
+	q.SendMessage("fail")
+	q.SendMessage("ok")
+
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			if body == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	if stats := processor.Stats(); stats != (queue.ProcessorStats{}) {
+		t.Errorf("expected empty stats before processing, got %+v", stats)
+	}
+	before := time.Now()
+	if _, err := processor.ProcessN(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := processor.Stats()
+	if stats.Processed != 2 || stats.Failed != 1 || stats.Deleted != 2 {
+		t.Errorf("expected 2 processed, 1 failed and 2 deleted messages, got %+v", stats)
+	}
+	if stats.LastReceive.Before(before) {
+		t.Errorf("expected the last receive after %s, got %s", before, stats.LastReceive)
+	}
+	if stats.Processing || stats.Polling || stats.Handling != 0 {
+		t.Errorf("expected an idle processor, got %+v", stats)
+	}
+}
+
+func TestStatsWhileProcessing(t *testing.T) {
+	q, err := memqueue.New("stats", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			close(started)
+			<-release
+			return nil
+		},
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- processor.ProcessWithContext(context.Background(), nil)
+	}()
+
+	<-started
+	if stats := processor.Stats(); !stats.Processing || stats.Handling != 1 || stats.LastReceive.IsZero() {
+		t.Errorf("expected a processor handling a message, got %+v", stats)
+	}
+	if !processor.Healthy(time.Minute) {
+		t.Error("expected a healthy processor")
+	}
+	close(release)
+	processor.Stop()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if stats := processor.Stats(); stats.Processing || stats.Processed != 1 {
+		t.Errorf("expected a stopped processor with 1 processed message, got %+v", stats)
+	}
+	if processor.Healthy(time.Minute) {
+		t.Error("expected a stopped processor not to be healthy")
+	}
+}
+
+func TestStatsOfNewProcessorConcurrently(t *testing.T) {
+	processor := &queue.Processor{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			processor.Stats()
+			processor.Healthy(time.Minute)
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,63 @@
+//go:build integration
+
+package queue_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// integrationEndpoint returns the endpoint of the local SQS emulator, SQS_ENDPOINT or localstack's default.
+func integrationEndpoint() string {
+	if endpoint := os.Getenv("SQS_ENDPOINT"); endpoint != "" {
+		return endpoint
+	}
+
+	return "http://localhost:4566"
+}
+
+// TestEndpointIntegration runs against a local SQS emulator, run it with: go test -tags integration
+func TestEndpointIntegration(t *testing.T) {
+	name := fmt.Sprintf("integration-%d", time.Now().UnixNano())
+	q, err := queue.New(name,
+		queue.WithEndpoint(integrationEndpoint()),
+		queue.WithRegion("us-east-1"),
+		queue.WithCredentials(credentials.NewStaticCredentials("test", "test", "")),
+		queue.WithReceiveWaitTime(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Delete(true)
+
+	if q.DeadLetterQueueURL == "" {
+		t.Error("expected the dead letter queue to be created")
+	}
+
+	if _, err := q.SendMessage(map[string]string{"hello": "world"}); err != nil {
+		t.Fatal(err)
+	}
+	message, err := q.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message == nil || *message.Body != `{"hello":"world"}` {
+		t.Fatalf("expected the sent message, got %v", message)
+	}
+	if _, err := q.DeleteMessage(message); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err = q.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != nil {
+		t.Errorf("expected no message after the delete, got %v", message)
+	}
+}
@@ -0,0 +1,189 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// LargePayloadSizeAttribute is the message attribute marking a body offloaded to S3, like the AWS extended client does.
+// It holds the size of the original body.
+const LargePayloadSizeAttribute = "ExtendedPayloadSize"
+
+// ErrLargePayloadsNotConfigured is returned for a message with an offloaded body when large payloads are not configured.
+var ErrLargePayloadsNotConfigured = errors.New("message body is offloaded to S3 but large payloads are not configured")
+
+// LargePayloadConfig configures offloading large message bodies to S3.
+type LargePayloadConfig struct {
+	S3Client s3iface.S3API
+	Bucket   string
+	// KeyPrefix is prepended to the S3 keys of the offloaded bodies.
+	KeyPrefix string
+	// Threshold is the body size in bytes above which the body is offloaded, it defaults to MaxMessageSize.
+	Threshold int
+	// DeleteObjects deletes the S3 object when the message is deleted.
+	DeleteObjects bool
+}
+
+// s3Pointer is the message body sent instead of an offloaded body.
+type s3Pointer struct {
+	S3Bucket string `json:"s3Bucket"`
+	S3Key    string `json:"s3Key"`
+}
+
+// WithLargePayloads offloads message bodies over the threshold to S3 and sends a pointer to the object instead.
+// Received pointers are resolved transparently by the Processor and Queue.UnmarshalMessage.
+func WithLargePayloads(config LargePayloadConfig) Option {
+	return func(queue *Queue) error {
+		if config.Threshold <= 0 || config.Threshold > MaxMessageSize {
+			config.Threshold = MaxMessageSize
+		}
+		queue.largePayloads = &config
+		return nil
+	}
+}
+
+// UnmarshalMessage decodes the body of the message with the marshaller of the queue.
+// Bodies offloaded to S3 are fetched first.
+func (queue *Queue) UnmarshalMessage(ctx context.Context, message *sqs.Message, v interface{}) error {
+	body, err := queue.getMessageBody(ctx, message)
+	if err != nil {
+		return err
+	}
+
+	return decodeBody(message, body, v, queue.getMarshaller(), queue.GetLogger())
+}
+
+// offloadLargePayload uploads the body to S3 and replaces it with a pointer when it is over the threshold.
+func (queue *Queue) offloadLargePayload(ctx context.Context, params *sqs.SendMessageInput) error {
+	config := queue.largePayloads
+	body := aws.StringValue(params.MessageBody)
+	if config == nil || messageSize(body, params.MessageAttributes) <= config.Threshold {
+		return nil
+	}
+
+	keyBytes := make([]byte, 16)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return err
+	}
+	pointer := s3Pointer{
+		S3Bucket: config.Bucket,
+		S3Key:    path.Join(config.KeyPrefix, queue.Name, hex.EncodeToString(keyBytes)),
+	}
+	_, err := config.S3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(pointer.S3Bucket),
+		Key:    aws.String(pointer.S3Key),
+		Body:   strings.NewReader(body),
+	})
+	if err != nil {
+		queue.GetLogger().Error("Offloading message body to S3", Fields{
+			"queueName": queue.Name,
+			"bucket":    pointer.S3Bucket,
+			"key":       pointer.S3Key,
+			"error":     err,
+		})
+		return err
+	}
+
+	pointerJSON, err := json.Marshal(pointer)
+	if err != nil {
+		return err
+	}
+	params.MessageBody = aws.String(string(pointerJSON))
+	if params.MessageAttributes == nil {
+		params.MessageAttributes = map[string]*sqs.MessageAttributeValue{}
+	}
+	params.MessageAttributes[LargePayloadSizeAttribute] = NumberAttribute(float64(len(body)))
+
+	return nil
+}
+
+// getS3Pointer returns the S3 pointer of a message with an offloaded body.
+func getS3Pointer(message *sqs.Message) (pointer *s3Pointer, err error) {
+	if _, ok := message.MessageAttributes[LargePayloadSizeAttribute]; !ok {
+		return nil, nil
+	}
+
+	pointer = new(s3Pointer)
+	err = json.Unmarshal([]byte(aws.StringValue(message.Body)), pointer)
+	return
+}
+
+// getMessageBody returns the body of the message, fetched from S3 when it was offloaded and decrypted when it was encrypted.
+func (queue *Queue) getMessageBody(ctx context.Context, message *sqs.Message) (string, error) {
+	body, err := queue.fetchMessageBody(ctx, message)
+	if err != nil {
+		return "", err
+	}
+
+	return queue.decryptPayload(message, body)
+}
+
+// fetchMessageBody returns the body of the message as it was sent, fetched from S3 when it was offloaded.
+func (queue *Queue) fetchMessageBody(ctx context.Context, message *sqs.Message) (string, error) {
+	pointer, err := getS3Pointer(message)
+	if err != nil || pointer == nil {
+		return aws.StringValue(message.Body), err
+	}
+	if queue.largePayloads == nil {
+		return "", ErrLargePayloadsNotConfigured
+	}
+
+	resp, err := queue.largePayloads.S3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(pointer.S3Bucket),
+		Key:    aws.String(pointer.S3Key),
+	})
+	if err != nil {
+		queue.GetLogger().Error("Fetching message body from S3", Fields{
+			"queueName": queue.Name,
+			"messageID": message.MessageId,
+			"bucket":    pointer.S3Bucket,
+			"key":       pointer.S3Key,
+			"error":     err,
+		})
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	return string(body), nil
+}
+
+// deleteLargePayload deletes the S3 object of an offloaded body when configured to.
+func (queue *Queue) deleteLargePayload(ctx context.Context, message *sqs.Message) {
+	if queue.largePayloads == nil || !queue.largePayloads.DeleteObjects {
+		return
+	}
+	pointer, err := getS3Pointer(message)
+	if err != nil || pointer == nil {
+		return
+	}
+
+	_, err = queue.largePayloads.S3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(pointer.S3Bucket),
+		Key:    aws.String(pointer.S3Key),
+	})
+	if err != nil {
+		queue.GetLogger().Warn("Deleting message body from S3", Fields{
+			"queueName": queue.Name,
+			"messageID": message.MessageId,
+			"bucket":    pointer.S3Bucket,
+			"key":       pointer.S3Key,
+			"error":     err,
+		})
+	}
+}
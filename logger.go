@@ -0,0 +1,68 @@
+package queue
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// Fields are the structured fields of a log entry.
+type Fields map[string]interface{}
+
+// A Logger receives the log entries of queues and processors.
+type Logger interface {
+	Debug(msg string, fields Fields)
+	Info(msg string, fields Fields)
+	Warn(msg string, fields Fields)
+	Error(msg string, fields Fields)
+}
+
+// logrusLogger is a Logger writing to a logrus logger.
+type logrusLogger struct {
+	logger *log.Logger
+}
+
+// NewLogrusLogger returns a Logger writing to the logrus logger, the standard logger when it is nil.
+func NewLogrusLogger(logger *log.Logger) Logger {
+	if logger == nil {
+		logger = log.StandardLogger()
+	}
+
+	return logrusLogger{logger: logger}
+}
+
+// Debug logs at debug level.
+func (l logrusLogger) Debug(msg string, fields Fields) {
+	l.logger.WithFields(log.Fields(fields)).Debug(msg)
+}
+
+// Info logs at info level.
+func (l logrusLogger) Info(msg string, fields Fields) {
+	l.logger.WithFields(log.Fields(fields)).Info(msg)
+}
+
+// Warn logs at warning level.
+func (l logrusLogger) Warn(msg string, fields Fields) {
+	l.logger.WithFields(log.Fields(fields)).Warning(msg)
+}
+
+// Error logs at error level.
+func (l logrusLogger) Error(msg string, fields Fields) {
+	l.logger.WithFields(log.Fields(fields)).Error(msg)
+}
+
+// GetLogger returns the logger of the queue, the logrus standard logger by default.
+func (queue *Queue) GetLogger() Logger {
+	if queue == nil || queue.Logger == nil {
+		return NewLogrusLogger(nil)
+	}
+
+	return queue.Logger
+}
+
+// getLogger returns the logger of the processor, it defaults to the logger of the Queue.
+func (processor *Processor) getLogger() Logger {
+	if processor.Logger != nil {
+		return processor.Logger
+	}
+
+	return processor.Queue.GetLogger()
+}
@@ -0,0 +1,101 @@
+package queue_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	log "github.com/sirupsen/logrus"
+)
+
+// captureStandardLogger writes the logrus standard logger at every level into the returned buffer until the test ends.
+func captureStandardLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var output bytes.Buffer
+	level := log.GetLevel()
+	log.SetOutput(&output)
+	log.SetLevel(log.TraceLevel)
+	t.Cleanup(func() {
+		log.SetOutput(ioutil.Discard)
+		log.SetLevel(level)
+	})
+
+	return &output
+}
+
+func TestInjectedLoggerReplacesLogrus(t *testing.T) {
+	output := captureStandardLogger(t)
+	logger := &recordingLogger{}
+	q, err := memqueue.New("logged", queue.WithReceiveWaitTime(0), queue.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendMessage(make(chan int)); err == nil {
+		t.Fatal("expected the unmarshalable body to fail")
+	}
+	q.SendMessage("failing")
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return errors.New("failed")
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if output.Len() != 0 {
+		t.Errorf("expected nothing to be logged through logrus, got %s", output)
+	}
+	for _, level := range []string{"warn", "error"} {
+		if len(logger.leveled(level)) == 0 {
+			t.Errorf("expected the %s entries to go through the injected logger", level)
+		}
+	}
+}
+
+func TestProcessorLoggerOverridesQueue(t *testing.T) {
+	output := captureStandardLogger(t)
+	queueLogger, processorLogger := &recordingLogger{}, &recordingLogger{}
+	q, err := memqueue.New("logged", queue.WithReceiveWaitTime(0), queue.WithLogger(queueLogger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("failing")
+
+	processor := &queue.Processor{
+		Queue:  q,
+		Logger: processorLogger,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return errors.New("failed")
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(processorLogger.leveled("warn")) == 0 {
+		t.Error("expected the handler error to be logged by the processor logger")
+	}
+	if len(queueLogger.leveled("warn")) != 0 || output.Len() != 0 {
+		t.Errorf("expected the processor entries not to reach the queue logger or logrus, got %v %s", queueLogger.entries, output)
+	}
+}
+
+func TestDefaultLoggerWritesToLogrus(t *testing.T) {
+	output := captureStandardLogger(t)
+	q := &queue.Queue{Name: "default"}
+
+	q.GetLogger().Warn("Warned", queue.Fields{"queueName": q.Name})
+
+	if !bytes.Contains(output.Bytes(), []byte("Warned")) || !bytes.Contains(output.Bytes(), []byte("queueName=default")) {
+		t.Errorf("expected the entry in the logrus output, got %s", output)
+	}
+}
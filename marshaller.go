@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// A Marshaller encodes message bodies for the queue and decodes them on receive.
+type Marshaller interface {
+	Marshal(v interface{}) (string, error)
+	Unmarshal(s string, v interface{}) error
+}
+
+// JSONMarshaller is the default Marshaller encoding message bodies as JSON.
+// Decoding is lenient by default, unknown fields are ignored and numbers decoded into interface{} become float64.
+type JSONMarshaller struct {
+	// DisallowUnknownFields fails decoding bodies with fields missing from the target struct.
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into interface{} as json.Number, so large int64 IDs keep their precision.
+	UseNumber bool
+}
+
+// A DecodeOption configures the JSON decoding of message bodies.
+type DecodeOption func(*JSONMarshaller)
+
+// DecodeStrict fails decoding bodies with unknown fields, e.g. after a producer renamed one.
+func DecodeStrict() DecodeOption {
+	return func(marshaller *JSONMarshaller) {
+		marshaller.DisallowUnknownFields = true
+	}
+}
+
+// DecodeUseNumber decodes numbers into interface{} as json.Number instead of float64.
+func DecodeUseNumber() DecodeOption {
+	return func(marshaller *JSONMarshaller) {
+		marshaller.UseNumber = true
+	}
+}
+
+// newJSONMarshaller returns a JSONMarshaller with the decode options applied.
+func newJSONMarshaller(opts ...DecodeOption) JSONMarshaller {
+	var marshaller JSONMarshaller
+	for _, opt := range opts {
+		opt(&marshaller)
+	}
+
+	return marshaller
+}
+
+// jsonEncoder is a reusable buffer and JSON encoder writing into it.
+type jsonEncoder struct {
+	buffer  bytes.Buffer
+	encoder *json.Encoder
+}
+
+// jsonEncoders pools the encoders of JSONMarshaller, so sending doesn't allocate a fresh buffer per message.
+var jsonEncoders = sync.Pool{
+	New: func() interface{} {
+		e := &jsonEncoder{}
+		e.encoder = json.NewEncoder(&e.buffer)
+		return e
+	},
+}
+
+// Marshal returns the JSON encoding of v.
+func (JSONMarshaller) Marshal(v interface{}) (string, error) {
+	e := jsonEncoders.Get().(*jsonEncoder)
+	defer func() {
+		// Buffers grown by an oversized body are dropped instead of being kept in the pool.
+		if e.buffer.Cap() <= MaxMessageSize {
+			e.buffer.Reset()
+			jsonEncoders.Put(e)
+		}
+	}()
+
+	if err := e.encoder.Encode(v); err != nil {
+		return "", err
+	}
+
+	// Encode terminates the value with a newline, json.Marshal doesn't.
+	return string(bytes.TrimSuffix(e.buffer.Bytes(), []byte("\n"))), nil
+}
+
+// Unmarshal decodes the JSON string into v.
+func (marshaller JSONMarshaller) Unmarshal(s string, v interface{}) error {
+	decoder := json.NewDecoder(strings.NewReader(s))
+	if marshaller.DisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if marshaller.UseNumber {
+		decoder.UseNumber()
+	}
+
+	return decoder.Decode(v)
+}
+
+// getMarshaller returns the marshaller of the queue.
+func (queue *Queue) getMarshaller() Marshaller {
+	if queue.Marshaller == nil {
+		return JSONMarshaller{}
+	}
+
+	return queue.Marshaller
+}
+
+// WithDecodeOptions makes the processor decode message bodies as JSON with the options, e.g. DecodeStrict.
+func (processor *Processor) WithDecodeOptions(opts ...DecodeOption) *Processor {
+	processor.Marshaller = newJSONMarshaller(opts...)
+
+	return processor
+}
+
+// getMarshaller returns the marshaller of the processor, falling back to the one of the queue.
+func (processor *Processor) getMarshaller() Marshaller {
+	if processor.Marshaller == nil {
+		return processor.Queue.getMarshaller()
+	}
+
+	return processor.Marshaller
+}
@@ -0,0 +1,162 @@
+package queue_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/gob"
+	"strings"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// gobMarshaller encodes the message bodies as base64 gob, a non-JSON codec.
+type gobMarshaller struct{}
+
+func (gobMarshaller) Marshal(v interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+func (gobMarshaller) Unmarshal(s string, v interface{}) error {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// gobEvent is the body encoded by gobMarshaller.
+type gobEvent struct {
+	Name  string
+	Count int
+}
+
+func TestMarshallerRoundTrip(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "gob", queue.WithReceiveWaitTime(0), queue.WithMarshaller(gobMarshaller{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage(gobEvent{Name: "created", Count: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 1 || strings.HasPrefix(bodies[0], "{") {
+		t.Fatalf("expected a gob encoded body, got %v", bodies)
+	}
+
+	var received *gobEvent
+	processor := &queue.Processor{
+		Queue:   q,
+		NewBody: func() interface{} { return new(gobEvent) },
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			received = body.(*gobEvent)
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if received == nil || *received != (gobEvent{Name: "created", Count: 3}) {
+		t.Errorf("expected the decoded event, got %+v", received)
+	}
+}
+
+func TestProcessorMarshallerOverridesQueue(t *testing.T) {
+	q, err := memqueue.New("gob", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := gobMarshaller{}.Marshal(gobEvent{Name: "sent", Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendRawMessage(encoded)
+
+	var received gobEvent
+	processor := &queue.Processor{
+		Queue:      q,
+		Marshaller: gobMarshaller{},
+		NewBody:    func() interface{} { return new(gobEvent) },
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			received = *body.(*gobEvent)
+			return nil
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 1 || received.Name != "sent" {
+		t.Errorf("expected the processor marshaller to decode the body, got %+v %+v", summary, received)
+	}
+}
+
+func TestDecodeMessageBody(t *testing.T) {
+	encoded, err := gobMarshaller{}.Marshal(gobEvent{Name: "decoded"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var event gobEvent
+	if err := queue.DecodeMessageBody(&sqs.Message{Body: aws.String(encoded)}, &event, gobMarshaller{}); err != nil {
+		t.Fatal(err)
+	}
+	if event.Name != "decoded" {
+		t.Errorf("expected the decoded event, got %+v", event)
+	}
+}
+
+func TestWithDecodeOptionsUnknownFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    []queue.DecodeOption
+		summary queue.ProcessSummary
+		handled bool
+	}{
+		{name: "strict", opts: []queue.DecodeOption{queue.DecodeStrict()}, summary: queue.ProcessSummary{Failed: 1}},
+		{name: "lenient", summary: queue.ProcessSummary{Processed: 1}, handled: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q, err := memqueue.New("decoded", queue.WithReceiveWaitTime(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			q.SendMessage(map[string]interface{}{"name": "created", "renamedCount": 3})
+
+			var received *gobEvent
+			processor := (&queue.Processor{
+				Queue:   q,
+				NewBody: func() interface{} { return new(gobEvent) },
+				HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+					received = body.(*gobEvent)
+					return nil
+				},
+			}).WithDecodeOptions(test.opts...)
+			summary, err := processor.ProcessN(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if summary != test.summary {
+				t.Errorf("expected %+v, got %+v", test.summary, summary)
+			}
+			if handled := received != nil; handled != test.handled {
+				t.Fatalf("expected the message to be handled: %v, got %v", test.handled, handled)
+			}
+			if test.handled && received.Name != "created" {
+				t.Errorf("expected the known fields to be decoded, got %+v", received)
+			}
+		})
+	}
+}
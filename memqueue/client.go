@@ -0,0 +1,615 @@
+package memqueue
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// Account and region of the in-memory queues, used in their URLs and ARNs.
+const (
+	accountID = "000000000000"
+	region    = "us-east-1"
+)
+
+// Default visibility timeout of queues created without one, as in SQS.
+const defaultVisibilityTimeout = 30 * time.Second
+
+// Interval of checking for messages while long polling an empty queue.
+const pollInterval = 10 * time.Millisecond
+
+// A Client implements the SQS API calls used by queue.Queue in memory. Set it with queue.WithClient or SetClient.
+// The other calls of sqsiface.SQSAPI are not implemented and return a queue.ErrCodeNotImplemented AWS error.
+type Client struct {
+	sqsiface.SQSAPI
+
+	mutex  sync.Mutex
+	queues map[string]*memoryQueue
+	nextID int64
+}
+
+// memoryQueue is a queue of the Client.
+type memoryQueue struct {
+	name       string
+	url        string
+	arn        string
+	attributes map[string]*string
+	tags       map[string]*string
+	messages   []*storedMessage
+
+	deadLetterArn   string
+	maxReceiveCount int
+}
+
+// storedMessage is a message in a memoryQueue.
+type storedMessage struct {
+	id              string
+	body            string
+	attributes      map[string]*sqs.MessageAttributeValue
+	groupID         string
+	sentAt          time.Time
+	firstReceivedAt time.Time
+	visibleAt       time.Time
+	receiveCount    int
+	receiptHandle   string
+	deduplicationID string
+	traceHeader     string
+	sequenceNumber  int64
+}
+
+// NewClient returns a Client without queues.
+func NewClient() *Client {
+	return &Client{
+		SQSAPI: queue.NotImplementedClient(),
+		queues: make(map[string]*memoryQueue),
+	}
+}
+
+// queueDoesNotExist returns the error of SQS for an unknown queue.
+func queueDoesNotExist(url string) error {
+	return awserr.New(sqs.ErrCodeQueueDoesNotExist, "The specified queue does not exist: "+url, nil)
+}
+
+// getQueue returns the queue of the URL, the client must be locked.
+func (client *Client) getQueue(url *string) (*memoryQueue, error) {
+	q, ok := client.queues[aws.StringValue(url)]
+	if !ok {
+		return nil, queueDoesNotExist(aws.StringValue(url))
+	}
+
+	return q, nil
+}
+
+// getQueueByArn returns the queue of the ARN, the client must be locked.
+func (client *Client) getQueueByArn(arn string) *memoryQueue {
+	for _, q := range client.queues {
+		if q.arn == arn {
+			return q
+		}
+	}
+
+	return nil
+}
+
+// newID returns a new unique ID of the client, the client must be locked.
+func (client *Client) newID() string {
+	client.nextID++
+
+	return "00000000-0000-4000-8000-" + strconv.FormatInt(100000000000+client.nextID, 10)
+}
+
+// CreateQueue creates the queue, or returns the URL of the existing one.
+func (client *Client) CreateQueue(input *sqs.CreateQueueInput) (*sqs.CreateQueueOutput, error) {
+	return client.CreateQueueWithContext(aws.BackgroundContext(), input)
+}
+
+// CreateQueueWithContext creates the queue, or returns the URL of the existing one.
+func (client *Client) CreateQueueWithContext(ctx aws.Context, input *sqs.CreateQueueInput, opts ...request.Option) (*sqs.CreateQueueOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	name := aws.StringValue(input.QueueName)
+	url := "https://sqs." + region + ".amazonaws.com/" + accountID + "/" + name
+	if _, ok := client.queues[url]; !ok {
+		q := &memoryQueue{
+			name:       name,
+			url:        url,
+			arn:        "arn:aws:sqs:" + region + ":" + accountID + ":" + name,
+			attributes: make(map[string]*string),
+			tags:       make(map[string]*string),
+		}
+		client.queues[url] = q
+	}
+	if err := client.queues[url].setAttributes(input.Attributes); err != nil {
+		return nil, err
+	}
+
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String(url)}, nil
+}
+
+// setAttributes sets the attributes of the queue, parsing its redrive policy.
+func (q *memoryQueue) setAttributes(attributes map[string]*string) error {
+	for name, value := range attributes {
+		q.attributes[name] = value
+	}
+
+	policy, ok := attributes[sqs.QueueAttributeNameRedrivePolicy]
+	if !ok {
+		return nil
+	}
+	var redrivePolicy queue.RedrivePolicy
+	if err := json.Unmarshal([]byte(aws.StringValue(policy)), &redrivePolicy); err != nil {
+		return awserr.New("InvalidAttributeValue", "Invalid value for the parameter RedrivePolicy", err)
+	}
+	q.deadLetterArn = redrivePolicy.DeadLetterTargetArn
+	q.maxReceiveCount = redrivePolicy.MaxReceiveCount
+
+	return nil
+}
+
+// GetQueueUrlWithContext returns the URL of the queue of the name.
+func (client *Client) GetQueueUrlWithContext(ctx aws.Context, input *sqs.GetQueueUrlInput, opts ...request.Option) (*sqs.GetQueueUrlOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	for url, q := range client.queues {
+		if q.name == aws.StringValue(input.QueueName) {
+			return &sqs.GetQueueUrlOutput{QueueUrl: aws.String(url)}, nil
+		}
+	}
+
+	return nil, queueDoesNotExist(aws.StringValue(input.QueueName))
+}
+
+// GetQueueAttributesWithContext returns the attributes of the queue, including its ARN and approximate message counts.
+func (client *Client) GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	visible, notVisible, delayed := 0, 0, 0
+	for _, message := range q.messages {
+		switch {
+		case message.receiveCount == 0 && now.Before(message.visibleAt):
+			delayed++
+		case now.Before(message.visibleAt):
+			notVisible++
+		default:
+			visible++
+		}
+	}
+	all := map[string]*string{
+		sqs.QueueAttributeNameQueueArn:                              aws.String(q.arn),
+		sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String(strconv.Itoa(visible)),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String(strconv.Itoa(notVisible)),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed:    aws.String(strconv.Itoa(delayed)),
+	}
+	for name, value := range q.attributes {
+		all[name] = value
+	}
+
+	attributes := make(map[string]*string)
+	for _, name := range input.AttributeNames {
+		if aws.StringValue(name) == sqs.QueueAttributeNameAll {
+			return &sqs.GetQueueAttributesOutput{Attributes: all}, nil
+		}
+		if value, ok := all[aws.StringValue(name)]; ok {
+			attributes[aws.StringValue(name)] = value
+		}
+	}
+
+	return &sqs.GetQueueAttributesOutput{Attributes: attributes}, nil
+}
+
+// SetQueueAttributes sets the attributes of the queue.
+func (client *Client) SetQueueAttributes(input *sqs.SetQueueAttributesInput) (*sqs.SetQueueAttributesOutput, error) {
+	return client.SetQueueAttributesWithContext(aws.BackgroundContext(), input)
+}
+
+// SetQueueAttributesWithContext sets the attributes of the queue.
+func (client *Client) SetQueueAttributesWithContext(ctx aws.Context, input *sqs.SetQueueAttributesInput, opts ...request.Option) (*sqs.SetQueueAttributesOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqs.SetQueueAttributesOutput{}, q.setAttributes(input.Attributes)
+}
+
+// TagQueue adds the tags to the queue.
+func (client *Client) TagQueue(input *sqs.TagQueueInput) (*sqs.TagQueueOutput, error) {
+	return client.TagQueueWithContext(aws.BackgroundContext(), input)
+}
+
+// TagQueueWithContext adds the tags to the queue.
+func (client *Client) TagQueueWithContext(ctx aws.Context, input *sqs.TagQueueInput, opts ...request.Option) (*sqs.TagQueueOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range input.Tags {
+		q.tags[key] = value
+	}
+
+	return &sqs.TagQueueOutput{}, nil
+}
+
+// ListQueueTags returns the tags of the queue.
+func (client *Client) ListQueueTags(input *sqs.ListQueueTagsInput) (*sqs.ListQueueTagsOutput, error) {
+	return client.ListQueueTagsWithContext(aws.BackgroundContext(), input)
+}
+
+// ListQueueTagsWithContext returns the tags of the queue.
+func (client *Client) ListQueueTagsWithContext(ctx aws.Context, input *sqs.ListQueueTagsInput, opts ...request.Option) (*sqs.ListQueueTagsOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]*string, len(q.tags))
+	for key, value := range q.tags {
+		tags[key] = value
+	}
+
+	return &sqs.ListQueueTagsOutput{Tags: tags}, nil
+}
+
+// PurgeQueue deletes all messages of the queue.
+func (client *Client) PurgeQueue(input *sqs.PurgeQueueInput) (*sqs.PurgeQueueOutput, error) {
+	return client.PurgeQueueWithContext(aws.BackgroundContext(), input)
+}
+
+// PurgeQueueWithContext deletes all messages of the queue.
+func (client *Client) PurgeQueueWithContext(ctx aws.Context, input *sqs.PurgeQueueInput, opts ...request.Option) (*sqs.PurgeQueueOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	q.messages = nil
+
+	return &sqs.PurgeQueueOutput{}, nil
+}
+
+// DeleteQueue deletes the queue and its messages.
+func (client *Client) DeleteQueue(input *sqs.DeleteQueueInput) (*sqs.DeleteQueueOutput, error) {
+	return client.DeleteQueueWithContext(aws.BackgroundContext(), input)
+}
+
+// DeleteQueueWithContext deletes the queue and its messages.
+func (client *Client) DeleteQueueWithContext(ctx aws.Context, input *sqs.DeleteQueueInput, opts ...request.Option) (*sqs.DeleteQueueOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if _, err := client.getQueue(input.QueueUrl); err != nil {
+		return nil, err
+	}
+	delete(client.queues, aws.StringValue(input.QueueUrl))
+
+	return &sqs.DeleteQueueOutput{}, nil
+}
+
+// SendMessageWithContext adds the message to the queue, visible after its delay.
+func (client *Client) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	message := client.send(q, aws.StringValue(input.MessageBody), input.MessageAttributes, aws.Int64Value(input.DelaySeconds))
+	message.groupID = aws.StringValue(input.MessageGroupId)
+	message.deduplicationID = aws.StringValue(input.MessageDeduplicationId)
+	message.traceHeader = traceHeader(input.MessageSystemAttributes)
+
+	return &sqs.SendMessageOutput{
+		MessageId:        aws.String(message.id),
+		MD5OfMessageBody: aws.String(md5Hex(message.body)),
+	}, nil
+}
+
+// SendMessageBatchWithContext adds the messages to the queue, visible after their delays.
+func (client *Client) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	output := &sqs.SendMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		message := client.send(q, aws.StringValue(entry.MessageBody), entry.MessageAttributes, aws.Int64Value(entry.DelaySeconds))
+		message.groupID = aws.StringValue(entry.MessageGroupId)
+		message.deduplicationID = aws.StringValue(entry.MessageDeduplicationId)
+		message.traceHeader = traceHeader(entry.MessageSystemAttributes)
+		output.Successful = append(output.Successful, &sqs.SendMessageBatchResultEntry{
+			Id:               entry.Id,
+			MessageId:        aws.String(message.id),
+			MD5OfMessageBody: aws.String(md5Hex(message.body)),
+		})
+	}
+
+	return output, nil
+}
+
+// traceHeader returns the AWSTraceHeader of the system attributes of a sent message.
+func traceHeader(attributes map[string]*sqs.MessageSystemAttributeValue) string {
+	if attribute, ok := attributes[queue.AWSTraceHeaderAttribute]; ok && attribute != nil {
+		return aws.StringValue(attribute.StringValue)
+	}
+
+	return ""
+}
+
+// send adds a message to the queue, the client must be locked.
+func (client *Client) send(q *memoryQueue, body string, attributes map[string]*sqs.MessageAttributeValue, delaySeconds int64) *storedMessage {
+	if delaySeconds == 0 {
+		delaySeconds, _ = strconv.ParseInt(aws.StringValue(q.attributes[sqs.QueueAttributeNameDelaySeconds]), 10, 64)
+	}
+
+	now := time.Now()
+	id := client.newID()
+	message := &storedMessage{
+		id:             id,
+		body:           body,
+		attributes:     attributes,
+		sentAt:         now,
+		visibleAt:      now.Add(time.Duration(delaySeconds) * time.Second),
+		sequenceNumber: client.nextID,
+	}
+	q.messages = append(q.messages, message)
+
+	return message
+}
+
+// ReceiveMessageWithContext returns up to MaxNumberOfMessages visible messages, long polling for WaitTimeSeconds.
+// Messages over the maximum receive count of the redrive policy are moved to the dead letter queue instead.
+func (client *Client) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	deadline := time.Now().Add(time.Duration(aws.Int64Value(input.WaitTimeSeconds)) * time.Second)
+	for {
+		messages, err := client.receive(input)
+		if err != nil || len(messages) > 0 || !time.Now().Before(deadline) {
+			return &sqs.ReceiveMessageOutput{Messages: messages}, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// receive returns the visible messages of the queue, making them invisible for the visibility timeout.
+func (client *Client) receive(input *sqs.ReceiveMessageInput) (messages []*sqs.Message, err error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	max := int(aws.Int64Value(input.MaxNumberOfMessages))
+	if max < 1 {
+		max = 1
+	}
+	visibilityTimeout := q.getVisibilityTimeout(input.VisibilityTimeout)
+	now := time.Now()
+	var kept []*storedMessage
+	for _, message := range q.messages {
+		if len(messages) >= max || now.Before(message.visibleAt) {
+			kept = append(kept, message)
+			continue
+		}
+		if q.maxReceiveCount > 0 && message.receiveCount >= q.maxReceiveCount {
+			if deadLetterQueue := client.getQueueByArn(q.deadLetterArn); deadLetterQueue != nil {
+				client.moveToDeadLetterQueue(message, deadLetterQueue)
+				continue
+			}
+		}
+
+		message.receiveCount++
+		if message.firstReceivedAt.IsZero() {
+			message.firstReceivedAt = now
+		}
+		message.visibleAt = now.Add(visibilityTimeout)
+		message.receiptHandle = client.newID()
+		messages = append(messages, message.toSQSMessage())
+		kept = append(kept, message)
+	}
+	q.messages = kept
+
+	return messages, nil
+}
+
+// moveToDeadLetterQueue moves the message to the dead letter queue keeping its ID, sent time and receive count,
+// the client must be locked.
+func (client *Client) moveToDeadLetterQueue(message *storedMessage, deadLetterQueue *memoryQueue) {
+	message.visibleAt = time.Time{}
+	message.receiptHandle = ""
+	deadLetterQueue.messages = append(deadLetterQueue.messages, message)
+}
+
+// getVisibilityTimeout returns the visibility timeout of the receive, or the one of the queue.
+func (q *memoryQueue) getVisibilityTimeout(timeout *int64) time.Duration {
+	if timeout != nil {
+		return time.Duration(*timeout) * time.Second
+	}
+	if seconds, err := strconv.ParseInt(aws.StringValue(q.attributes[sqs.QueueAttributeNameVisibilityTimeout]), 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	return defaultVisibilityTimeout
+}
+
+// toSQSMessage returns the received message with its system attributes.
+func (message *storedMessage) toSQSMessage() *sqs.Message {
+	attributes := map[string]*string{
+		sqs.MessageSystemAttributeNameSentTimestamp:                    aws.String(strconv.FormatInt(message.sentAt.UnixNano()/int64(time.Millisecond), 10)),
+		sqs.MessageSystemAttributeNameApproximateFirstReceiveTimestamp: aws.String(strconv.FormatInt(message.firstReceivedAt.UnixNano()/int64(time.Millisecond), 10)),
+		sqs.MessageSystemAttributeNameApproximateReceiveCount:          aws.String(strconv.Itoa(message.receiveCount)),
+		sqs.MessageSystemAttributeNameSenderId:                         aws.String(accountID),
+	}
+	if message.groupID != "" {
+		attributes[sqs.MessageSystemAttributeNameMessageGroupId] = aws.String(message.groupID)
+		attributes[sqs.MessageSystemAttributeNameSequenceNumber] = aws.String(strconv.FormatInt(message.sequenceNumber, 10))
+	}
+	if message.deduplicationID != "" {
+		attributes[sqs.MessageSystemAttributeNameMessageDeduplicationId] = aws.String(message.deduplicationID)
+	}
+	if message.traceHeader != "" {
+		attributes[queue.AWSTraceHeaderAttribute] = aws.String(message.traceHeader)
+	}
+
+	return &sqs.Message{
+		MessageId:         aws.String(message.id),
+		ReceiptHandle:     aws.String(message.receiptHandle),
+		Body:              aws.String(message.body),
+		MD5OfBody:         aws.String(md5Hex(message.body)),
+		Attributes:        attributes,
+		MessageAttributes: message.attributes,
+	}
+}
+
+// md5Hex returns the hex encoded MD5 digest of the body, as SQS returns it.
+func md5Hex(body string) string {
+	digest := md5.Sum([]byte(body))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// DeleteMessageWithContext deletes the message of the receipt handle.
+func (client *Client) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.delete(aws.StringValue(input.ReceiptHandle)); err != nil {
+		return nil, err
+	}
+
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// DeleteMessageBatchWithContext deletes the messages of the receipt handles.
+func (client *Client) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		if err := q.delete(aws.StringValue(entry.ReceiptHandle)); err != nil {
+			output.Failed = append(output.Failed, batchResultError(entry.Id, err))
+			continue
+		}
+		output.Successful = append(output.Successful, &sqs.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+
+	return output, nil
+}
+
+// delete removes the message of the receipt handle from the queue.
+func (q *memoryQueue) delete(receiptHandle string) error {
+	for i, message := range q.messages {
+		if message.receiptHandle == receiptHandle && receiptHandle != "" {
+			q.messages = append(q.messages[:i], q.messages[i+1:]...)
+			return nil
+		}
+	}
+
+	return receiptHandleIsInvalid(receiptHandle)
+}
+
+// ChangeMessageVisibilityWithContext makes the message of the receipt handle visible again after the timeout.
+func (client *Client) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, err := client.getQueue(input.QueueUrl)
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range q.messages {
+		if message.receiptHandle == aws.StringValue(input.ReceiptHandle) && aws.StringValue(input.ReceiptHandle) != "" {
+			message.visibleAt = time.Now().Add(time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second)
+			return &sqs.ChangeMessageVisibilityOutput{}, nil
+		}
+	}
+
+	return nil, receiptHandleIsInvalid(aws.StringValue(input.ReceiptHandle))
+}
+
+// receiptHandleIsInvalid returns the error of SQS for an unknown receipt handle.
+func receiptHandleIsInvalid(receiptHandle string) error {
+	return awserr.New(sqs.ErrCodeReceiptHandleIsInvalid, "The receipt handle "+receiptHandle+" is not valid", nil)
+}
+
+// batchResultError returns the failed batch entry of the error.
+func batchResultError(id *string, err error) *sqs.BatchResultErrorEntry {
+	code, message := "InternalError", err.Error()
+	if aerr, ok := err.(awserr.Error); ok {
+		code, message = aerr.Code(), aerr.Message()
+	}
+
+	return &sqs.BatchResultErrorEntry{
+		Id:          id,
+		Code:        aws.String(code),
+		Message:     aws.String(message),
+		SenderFault: aws.Bool(strings.HasPrefix(code, "ReceiptHandle")),
+	}
+}
+
+// Messages returns the bodies of the messages in the queue of the URL, visible or not, e.g. to assert on in tests.
+func (client *Client) Messages(url string) (bodies []string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	q, ok := client.queues[url]
+	if !ok {
+		return nil
+	}
+	for _, message := range q.messages {
+		bodies = append(bodies, message.body)
+	}
+
+	return
+}
+
+// Verify that Client implements the SQS API.
+var _ sqsiface.SQSAPI = (*Client)(nil)
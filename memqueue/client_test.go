@@ -0,0 +1,110 @@
+package memqueue_test
+
+import (
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestVisibilityExpiry(t *testing.T) {
+	q, err := memqueue.New("visibility", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := q.SendMessage("body")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := q.ReceiveMessage()
+	if err != nil || first == nil {
+		t.Fatalf("expected the message, got %v, %v", first, err)
+	}
+	if hidden, err := q.ReceiveMessage(); err != nil || hidden != nil {
+		t.Fatalf("expected the message to be invisible, got %v, %v", hidden, err)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+	second, err := q.ReceiveMessage()
+	if err != nil || second == nil {
+		t.Fatalf("expected the message to be redelivered, got %v, %v", second, err)
+	}
+	if *second.MessageId != *sent.MessageId {
+		t.Errorf("expected message %s, got %s", *sent.MessageId, *second.MessageId)
+	}
+	if *second.ReceiptHandle == *first.ReceiptHandle {
+		t.Error("expected a new receipt handle for the redelivery")
+	}
+	if count := second.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]; count == nil || *count != "2" {
+		t.Errorf("expected receive count 2, got %v", count)
+	}
+	if _, err := q.DeleteMessage(first); !queue.IsReceiptHandleInvalid(err) {
+		t.Errorf("expected the expired receipt handle to be invalid, got %v", err)
+	}
+}
+
+func TestChangeMessageVisibility(t *testing.T) {
+	q, err := memqueue.New("visibility", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage("body"); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := q.ReceiveMessage()
+	if err != nil || message == nil {
+		t.Fatalf("expected the message, got %v, %v", message, err)
+	}
+	if err := q.ChangeMessageVisibility(message, 0); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := q.ReceiveMessage(); err != nil || again == nil {
+		t.Errorf("expected the released message to be visible, got %v, %v", again, err)
+	}
+}
+
+func TestDeadLetteringAfterMaxReceives(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "dead-lettered",
+		queue.WithReceiveWaitTime(0),
+		queue.WithReceiveVisibilityTimeout(0),
+		queue.WithMaxReceiveCount(3),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage("body"); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		message, err := q.ReceiveMessage()
+		if err != nil || message == nil {
+			t.Fatalf("receive %d: expected the message, got %v, %v", i, message, err)
+		}
+	}
+	if message, err := q.ReceiveMessage(); err != nil || message != nil {
+		t.Fatalf("expected the message to be dead-lettered after 3 receives, got %v, %v", message, err)
+	}
+
+	if bodies := client.Messages(q.URL); len(bodies) != 0 {
+		t.Errorf("expected the source queue to be empty, got %v", bodies)
+	}
+	if bodies := client.Messages(q.DeadLetterQueueURL); len(bodies) != 1 {
+		t.Errorf("expected the message in the dead letter queue, got %v", bodies)
+	}
+}
+
+func TestNotImplementedCalls(t *testing.T) {
+	_, err := memqueue.NewClient().ListQueues(&sqs.ListQueuesInput{})
+
+	aerr, ok := err.(awserr.Error)
+	if !ok || aerr.Code() != queue.ErrCodeNotImplemented {
+		t.Errorf("expected a %s error, got %v", queue.ErrCodeNotImplemented, err)
+	}
+}
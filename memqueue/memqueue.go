@@ -0,0 +1,29 @@
+// Package memqueue runs queues fully in memory, for testing code using queues without SQS.
+//
+//	q, err := memqueue.New("your-queue-name")
+//
+//	processor := queue.Processor{
+//		Queue:         q,
+//		HandleMessage: handleMessage,
+//	}
+//
+// The returned *queue.Queue talks to a Client implementing the SQS API in memory, with visibility timeouts,
+// delayed messages, redelivery and a dead letter queue receiving the messages after their maximum receive count.
+package memqueue
+
+import (
+	queue "github.com/Indivizo/sqs"
+)
+
+// New returns an initialized queue, and its dead letter queue, on a new in-memory Client.
+// The options are applied as for queue.New, e.g. queue.WithReceiveWaitTime(0) makes receiving return immediately.
+func New(name string, opts ...queue.Option) (*queue.Queue, error) {
+	return NewWithClient(NewClient(), name, opts...)
+}
+
+// NewWithClient returns an initialized queue on the in-memory Client, so several queues can share it, e.g. for MoveMessageToQueue.
+func NewWithClient(client *Client, name string, opts ...queue.Option) (*queue.Queue, error) {
+	opts = append([]queue.Option{queue.WithClient(client)}, opts...)
+
+	return queue.New(name, opts...)
+}
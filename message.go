@@ -0,0 +1,115 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// A Message is a received message of a queue with nil-safe accessors, the raw message is embedded.
+// The accessors return zero values for missing fields, also for a nil raw message.
+type Message struct {
+	*sqs.Message
+
+	queue *Queue
+}
+
+// WrapMessage returns the raw message received from the queue as a Message, e.g. in a handler.
+func (queue *Queue) WrapMessage(message *sqs.Message) *Message {
+	return &Message{Message: message, queue: queue}
+}
+
+// Receive returns up to max messages from the queue within the context, like ReceiveMessagesContext.
+func (queue *Queue) Receive(ctx context.Context, max int64) (messages []*Message, err error) {
+	received, err := queue.ReceiveMessagesContext(ctx, max)
+	for _, message := range received {
+		messages = append(messages, queue.WrapMessage(message))
+	}
+
+	return
+}
+
+// ID returns the message ID.
+func (message *Message) ID() string {
+	if message.Message == nil {
+		return ""
+	}
+
+	return aws.StringValue(message.MessageId)
+}
+
+// Body returns the raw body, as it was received.
+func (message *Message) Body() string {
+	if message.Message == nil {
+		return ""
+	}
+
+	return aws.StringValue(message.Message.Body)
+}
+
+// ReceiptHandle returns the receipt handle of the receive.
+func (message *Message) ReceiptHandle() string {
+	if message.Message == nil {
+		return ""
+	}
+
+	return aws.StringValue(message.Message.ReceiptHandle)
+}
+
+// Attribute returns the string message attribute of the name, or the system attribute of the name without one.
+// It reports false when there is neither.
+func (message *Message) Attribute(name string) (string, bool) {
+	if message.Message == nil {
+		return "", false
+	}
+	if value, ok := GetStringAttribute(message.Message, name); ok {
+		return value, true
+	}
+	value, ok := message.Attributes[name]
+	if !ok || value == nil {
+		return "", false
+	}
+
+	return *value, true
+}
+
+// ReceiveCount returns how many times the message was received, 0 when it's unknown.
+func (message *Message) ReceiveCount() int {
+	if message.Message == nil {
+		return 0
+	}
+	count, _ := ReceiveCount(message.Message)
+
+	return count
+}
+
+// SentAt returns when the message was sent, the zero time when it's unknown.
+func (message *Message) SentAt() time.Time {
+	if message.Message == nil {
+		return time.Time{}
+	}
+	sentAt, _ := SentTime(message.Message)
+
+	return sentAt
+}
+
+// Decode decodes the body into v with the marshaller of the queue, fetching offloaded and decrypting encrypted bodies.
+func (message *Message) Decode(v interface{}) error {
+	queue := message.queue
+	if queue == nil {
+		queue = new(Queue)
+	}
+	if message.Message == nil {
+		return &DecodeError{Err: errors.New("message is nil")}
+	}
+
+	body, err := queue.getMessageBody(aws.BackgroundContext(), message.Message)
+	if err != nil {
+		return err
+	}
+
+	return decodeBody(message.Message, body, v, queue.getMarshaller(), queue.GetLogger())
+}
@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// SendMessageWithAttributes will send message to the queue with string message attributes.
+func (queue *Queue) SendMessageWithAttributes(messageBody interface{}, attributes map[string]string) (resp *sqs.SendMessageOutput, err error) {
+	attributeValues := make(map[string]*sqs.MessageAttributeValue, len(attributes))
+	for name, value := range attributes {
+		attributeValues[name] = StringAttribute(value)
+	}
+
+	return queue.SendMessageWithAttributeValues(messageBody, attributeValues)
+}
+
+// SendMessageWithAttributeValues will send message to the queue with typed message attributes.
+// See StringAttribute, NumberAttribute and BinaryAttribute.
+func (queue *Queue) SendMessageWithAttributeValues(messageBody interface{}, attributes map[string]*sqs.MessageAttributeValue) (resp *sqs.SendMessageOutput, err error) {
+	msg, err := queue.marshalMessageBody(messageBody)
+	if err != nil {
+		return
+	}
+	params := &sqs.SendMessageInput{
+		MessageBody:       aws.String(msg),
+		QueueUrl:          aws.String(queue.URL),
+		MessageAttributes: attributes,
+	}
+
+	return queue.sendMessageInput(aws.BackgroundContext(), params)
+}
+
+// StringAttribute returns a String message attribute.
+func StringAttribute(value string) *sqs.MessageAttributeValue {
+	return &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
+
+// NumberAttribute returns a Number message attribute.
+func NumberAttribute(value float64) *sqs.MessageAttributeValue {
+	return &sqs.MessageAttributeValue{
+		DataType:    aws.String("Number"),
+		StringValue: aws.String(strconv.FormatFloat(value, 'f', -1, 64)),
+	}
+}
+
+// BinaryAttribute returns a Binary message attribute.
+func BinaryAttribute(value []byte) *sqs.MessageAttributeValue {
+	return &sqs.MessageAttributeValue{
+		DataType:    aws.String("Binary"),
+		BinaryValue: value,
+	}
+}
+
+// GetStringAttribute returns a String message attribute of the message.
+func GetStringAttribute(message *sqs.Message, name string) (string, bool) {
+	attribute, ok := message.MessageAttributes[name]
+	if !ok || attribute == nil || attribute.StringValue == nil {
+		return "", false
+	}
+
+	return *attribute.StringValue, true
+}
+
+// GetNumberAttribute returns a Number message attribute of the message.
+func GetNumberAttribute(message *sqs.Message, name string) (float64, bool) {
+	value, ok := GetStringAttribute(message, name)
+	if !ok {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return number, true
+}
+
+// GetBinaryAttribute returns a Binary message attribute of the message.
+func GetBinaryAttribute(message *sqs.Message, name string) ([]byte, bool) {
+	attribute, ok := message.MessageAttributes[name]
+	if !ok || attribute == nil || attribute.BinaryValue == nil {
+		return nil, false
+	}
+
+	return attribute.BinaryValue, true
+}
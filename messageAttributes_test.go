@@ -0,0 +1,67 @@
+package queue_test
+
+import (
+	"bytes"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestMessageAttributesRoundTrip(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("attributes", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = q.SendMessageWithAttributeValues("body", map[string]*sqs.MessageAttributeValue{
+		"contentType": queue.StringAttribute("application/json"),
+		"version":     queue.NumberAttribute(2.5),
+		"signature":   queue.BinaryAttribute([]byte{0, 1, 2}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	message, err := q.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if names := client.receiveInputs[0].MessageAttributeNames; len(names) != 1 || aws.StringValue(names[0]) != "All" {
+		t.Errorf("expected all message attributes to be requested, got %v", aws.StringValueSlice(names))
+	}
+	if value, ok := queue.GetStringAttribute(message, "contentType"); !ok || value != "application/json" {
+		t.Errorf("expected the string attribute, got %q %v", value, ok)
+	}
+	if value, ok := queue.GetNumberAttribute(message, "version"); !ok || value != 2.5 {
+		t.Errorf("expected the number attribute, got %v %v", value, ok)
+	}
+	if value, ok := queue.GetBinaryAttribute(message, "signature"); !ok || !bytes.Equal(value, []byte{0, 1, 2}) {
+		t.Errorf("expected the binary attribute, got %v %v", value, ok)
+	}
+	if _, ok := queue.GetStringAttribute(message, "missing"); ok {
+		t.Error("expected no missing attribute")
+	}
+	if _, ok := queue.GetNumberAttribute(message, "contentType"); ok {
+		t.Error("expected a string attribute not to be read as a number")
+	}
+}
+
+func TestSendMessageWithAttributes(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("attributes", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendMessageWithAttributes("body", map[string]string{"traceID": "abc"}); err != nil {
+		t.Fatal(err)
+	}
+
+	attribute := client.sendInputs[0].MessageAttributes["traceID"]
+	if aws.StringValue(attribute.DataType) != "String" || aws.StringValue(attribute.StringValue) != "abc" {
+		t.Errorf("expected a string attribute, got %v", attribute)
+	}
+}
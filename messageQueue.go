@@ -0,0 +1,125 @@
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// A MessageQueue sends, receives and deletes messages. Code depending on it instead of *Queue
+// can be tested without SQS, e.g. with a queue from the memqueue package. NewProcessor processes any MessageQueue.
+type MessageQueue interface {
+	SendMessage(messageBody interface{}) (*sqs.SendMessageOutput, error)
+	ReceiveMessage() (*sqs.Message, error)
+	DeleteMessage(message *sqs.Message) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(message *sqs.Message, timeout time.Duration) error
+}
+
+// Queue is the SQS implementation of MessageQueue.
+var _ MessageQueue = (*Queue)(nil)
+
+// messageQueueURL is the URL of the queues processing a MessageQueue other than *Queue.
+const messageQueueURL = "memory://message-queue"
+
+// NewProcessor returns a Processor handling the messages of the queue with handleMessage.
+// A *Queue is processed as usual, other implementations, e.g. fakes in tests, through their MessageQueue methods:
+// messages are received one at a time, and the messages passed to DeleteMessage and ChangeMessageVisibility only
+// have their ReceiptHandle set. Features needing more of the SQS API, e.g. dead letter queues, return errors for them.
+func NewProcessor(q MessageQueue, handleMessage MessageHandlerFunc) *Processor {
+	sqsQueue, ok := q.(*Queue)
+	if !ok {
+		sqsQueue = &Queue{
+			Name:   fmt.Sprintf("%T", q),
+			URL:    messageQueueURL,
+			Client: &messageQueueClient{SQSAPI: NotImplementedClient(), queue: q},
+		}
+	}
+
+	return &Processor{
+		Queue:         sqsQueue,
+		HandleMessage: handleMessage,
+	}
+}
+
+// messageQueueClient implements the SQS calls of processing with the methods of a MessageQueue.
+type messageQueueClient struct {
+	sqsiface.SQSAPI
+	queue MessageQueue
+}
+
+// receivedMessage is the result of MessageQueue.ReceiveMessage.
+type receivedMessage struct {
+	message *sqs.Message
+	err     error
+}
+
+// ReceiveMessageWithContext receives a message, returning when the context is cancelled even if ReceiveMessage blocks.
+// A message received after the cancellation is left to become visible again.
+func (client *messageQueueClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	result := make(chan receivedMessage, 1)
+	go func() {
+		message, err := client.queue.ReceiveMessage()
+		result <- receivedMessage{message: message, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, awserr.New(request.CanceledErrorCode, "request context canceled", ctx.Err())
+	case received := <-result:
+		if received.err != nil {
+			return nil, received.err
+		}
+		output := &sqs.ReceiveMessageOutput{}
+		if received.message != nil {
+			output.Messages = []*sqs.Message{received.message}
+		}
+		return output, nil
+	}
+}
+
+// DeleteMessageWithContext deletes the message by it's receipt handle.
+func (client *messageQueueClient) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	if _, err := client.queue.DeleteMessage(&sqs.Message{ReceiptHandle: input.ReceiptHandle}); err != nil {
+		return nil, err
+	}
+
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// DeleteMessageBatchWithContext deletes the messages one by one, reporting the failed ones.
+func (client *messageQueueClient) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		if _, err := client.queue.DeleteMessage(&sqs.Message{ReceiptHandle: entry.ReceiptHandle}); err != nil {
+			code := "InternalError"
+			if aerr, ok := err.(awserr.Error); ok {
+				code = aerr.Code()
+			}
+			output.Failed = append(output.Failed, &sqs.BatchResultErrorEntry{
+				Id:          entry.Id,
+				Code:        aws.String(code),
+				Message:     aws.String(err.Error()),
+				SenderFault: aws.Bool(false),
+			})
+			continue
+		}
+		output.Successful = append(output.Successful, &sqs.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+
+	return output, nil
+}
+
+// ChangeMessageVisibilityWithContext changes the visibility timeout of the message by it's receipt handle.
+func (client *messageQueueClient) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	timeout := time.Duration(aws.Int64Value(input.VisibilityTimeout)) * time.Second
+	if err := client.queue.ChangeMessageVisibility(&sqs.Message{ReceiptHandle: input.ReceiptHandle}, timeout); err != nil {
+		return nil, err
+	}
+
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
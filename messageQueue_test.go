@@ -0,0 +1,66 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// wrappedQueue is a MessageQueue other than *queue.Queue.
+type wrappedQueue struct {
+	*queue.Queue
+	deleted int
+}
+
+func (q *wrappedQueue) DeleteMessage(message *sqs.Message) (*sqs.DeleteMessageOutput, error) {
+	q.deleted++
+	return q.Queue.DeleteMessage(message)
+}
+
+func TestNewProcessorMessageQueue(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "interface", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage("body"); err != nil {
+		t.Fatal(err)
+	}
+
+	wrapped := &wrappedQueue{Queue: q}
+	var bodies []string
+	processor := queue.NewProcessor(wrapped, func(ctx context.Context, body interface{}, message *sqs.Message) error {
+		bodies = append(bodies, body.(string))
+		return nil
+	})
+	if processor.Queue == q {
+		t.Fatal("expected the MessageQueue to be wrapped")
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bodies) != 1 || bodies[0] != "body" {
+		t.Errorf("expected the sent body to be handled, got %v", bodies)
+	}
+	if wrapped.deleted != 1 {
+		t.Errorf("expected the message to be deleted through the MessageQueue, got %d deletes", wrapped.deleted)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 0 {
+		t.Errorf("expected no messages left, got %v", remaining)
+	}
+}
+
+func TestNewProcessorQueue(t *testing.T) {
+	q, err := memqueue.New("direct", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if processor := queue.NewProcessor(q, nil); processor.Queue != q {
+		t.Error("expected a *Queue to be processed directly")
+	}
+}
@@ -0,0 +1,109 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestMessageAccessorsOfPartialMessages(t *testing.T) {
+	q := &queue.Queue{Name: "partial"}
+	messages := map[string]*sqs.Message{
+		"nil":   nil,
+		"empty": {},
+		"nil attribute values": {
+			Attributes:        map[string]*string{sqs.MessageSystemAttributeNameApproximateReceiveCount: nil},
+			MessageAttributes: map[string]*sqs.MessageAttributeValue{"tenant": nil},
+		},
+	}
+	for name, raw := range messages {
+		t.Run(name, func(t *testing.T) {
+			message := q.WrapMessage(raw)
+
+			if message.ID() != "" || message.Body() != "" || message.ReceiptHandle() != "" {
+				t.Errorf("expected empty fields, got %q %q %q", message.ID(), message.Body(), message.ReceiptHandle())
+			}
+			if value, ok := message.Attribute("tenant"); ok || value != "" {
+				t.Errorf("expected no attribute, got %q", value)
+			}
+			if message.ReceiveCount() != 0 || !message.SentAt().IsZero() {
+				t.Errorf("expected zero receive count and sent time, got %d and %s", message.ReceiveCount(), message.SentAt())
+			}
+			var body interface{}
+			var decodeErr *queue.DecodeError
+			if err := message.Decode(&body); raw == nil && !errors.As(err, &decodeErr) {
+				t.Errorf("expected a DecodeError for a nil message, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMessageAttributeParsing(t *testing.T) {
+	sentAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	message := (&queue.Queue{Name: "parsed"}).WrapMessage(&sqs.Message{
+		MessageId:     aws.String("id"),
+		Body:          aws.String(`{"id":"a","count":2}`),
+		ReceiptHandle: aws.String("receipt"),
+		Attributes: map[string]*string{
+			sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("3"),
+			sqs.MessageSystemAttributeNameSentTimestamp:           aws.String("1682942400000"),
+			sqs.MessageSystemAttributeNameSenderId:                aws.String("sender"),
+		},
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			"tenant":                               queue.StringAttribute("acme"),
+			sqs.MessageSystemAttributeNameSenderId: queue.StringAttribute("overridden"),
+		},
+	})
+
+	if message.ID() != "id" || message.ReceiptHandle() != "receipt" {
+		t.Errorf("expected the ID and receipt handle, got %q %q", message.ID(), message.ReceiptHandle())
+	}
+	if message.ReceiveCount() != 3 || !message.SentAt().Equal(sentAt) {
+		t.Errorf("expected the parsed receive count and sent time, got %d and %s", message.ReceiveCount(), message.SentAt())
+	}
+	if tenant, ok := message.Attribute("tenant"); !ok || tenant != "acme" {
+		t.Errorf("expected the message attribute, got %q", tenant)
+	}
+	if sender, ok := message.Attribute(sqs.MessageSystemAttributeNameSenderId); !ok || sender != "overridden" {
+		t.Errorf("expected the message attribute to take precedence over the system attribute, got %q", sender)
+	}
+	var event subscribedEvent
+	if err := message.Decode(&event); err != nil || event != (subscribedEvent{ID: "a", Count: 2}) {
+		t.Errorf("expected the decoded body, got %+v and %v", event, err)
+	}
+
+	malformed := (&queue.Queue{Name: "parsed"}).WrapMessage(&sqs.Message{Attributes: map[string]*string{
+		sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String("many"),
+		sqs.MessageSystemAttributeNameSentTimestamp:           aws.String("yesterday"),
+	}})
+	if malformed.ReceiveCount() != 0 || !malformed.SentAt().IsZero() {
+		t.Errorf("expected zero values for malformed attributes, got %d and %s", malformed.ReceiveCount(), malformed.SentAt())
+	}
+}
+
+func TestReceiveWrapsMessages(t *testing.T) {
+	q, err := memqueue.New("wrapped", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage(subscribedEvent{ID: "a"})
+
+	messages, err := q.Receive(context.Background(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 1 || messages[0].ID() == "" || messages[0].ReceiveCount() != 1 {
+		t.Fatalf("expected 1 wrapped message, got %v", messages)
+	}
+	var event subscribedEvent
+	if err := messages[0].Decode(&event); err != nil || event.ID != "a" {
+		t.Errorf("expected the body decoded with the queue, got %+v and %v", event, err)
+	}
+}
@@ -0,0 +1,167 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Defaults of receiving with Messages.
+const (
+	defaultMessagesBufferSize  = 0
+	defaultMessagesMaxInFlight = MaxBatchSize
+)
+
+// receiveOptions configure Messages.
+type receiveOptions struct {
+	bufferSize  int
+	maxInFlight int
+	newBody     func() interface{}
+}
+
+// A ReceiveOption configures Messages.
+type ReceiveOption func(opts *receiveOptions)
+
+// WithBufferSize sets the buffer size of the channel returned by Messages, it is unbuffered by default.
+func WithBufferSize(size int) ReceiveOption {
+	return func(opts *receiveOptions) {
+		opts.bufferSize = size
+	}
+}
+
+// WithMaxInFlight sets how many messages Messages delivers before they are settled, 10 by default.
+func WithMaxInFlight(max int) ReceiveOption {
+	return func(opts *receiveOptions) {
+		opts.maxInFlight = max
+	}
+}
+
+// WithDecodedBody makes Messages decode the bodies into the values returned by newBody, instead of delivering the raw body.
+func WithDecodedBody(newBody func() interface{}) ReceiveOption {
+	return func(opts *receiveOptions) {
+		opts.newBody = newBody
+	}
+}
+
+// A ReceivedMessage is a message delivered by Messages, it is only deleted by Ack.
+type ReceivedMessage struct {
+	// Body is the decoded body with WithDecodedBody, otherwise the raw body string.
+	Body    interface{}
+	Message *sqs.Message
+	// Err is the error of a failed receive, the other fields are empty then and it needs no Ack or Nack.
+	Err error
+
+	queue   *Queue
+	release func()
+}
+
+// Ack deletes the message from the queue.
+func (message ReceivedMessage) Ack() error {
+	defer message.release()
+
+	_, err := message.queue.DeleteMessageContext(aws.BackgroundContext(), message.Message)
+
+	return err
+}
+
+// Nack makes the message visible again immediately.
+func (message ReceivedMessage) Nack() error {
+	defer message.release()
+
+	return message.queue.ChangeMessageVisibilityContext(aws.BackgroundContext(), message.Message, 0)
+}
+
+// Messages receives messages from the queue until the context is done and delivers them on the returned channel.
+// At most max in flight messages are delivered before they are settled with Ack or Nack, or their visibility timeout ran out.
+// Messages that fail to decode are left for redelivery. Receive errors are delivered in Err before backing off.
+// The channel is closed when the context is done, messages received but not delivered by then are made visible again.
+func (queue *Queue) Messages(ctx context.Context, opts ...ReceiveOption) <-chan ReceivedMessage {
+	options := receiveOptions{
+		bufferSize:  defaultMessagesBufferSize,
+		maxInFlight: defaultMessagesMaxInFlight,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.maxInFlight < 1 {
+		options.maxInFlight = 1
+	}
+
+	messages := make(chan ReceivedMessage, options.bufferSize)
+	go func() {
+		defer close(messages)
+
+		// The receive loop reuses the backoff and decoding of the Processor.
+		processor := &Processor{Queue: queue, NewBody: options.newBody}
+		inFlight := make(chan struct{}, options.maxInFlight)
+		receiveFailures := 0
+		for ctx.Err() == nil {
+			slots := acquireWorkers(ctx, inFlight)
+			if slots == 0 {
+				continue
+			}
+
+			received, err := queue.ReceiveMessagesContext(ctx, int64(slots))
+			if err != nil && ctx.Err() == nil {
+				receiveFailures++
+				select {
+				case messages <- ReceivedMessage{Err: err}:
+				case <-ctx.Done():
+				}
+				processor.backOffReceiving(ctx, receiveFailures)
+			} else {
+				receiveFailures = 0
+			}
+			for i := len(received); i < slots; i++ {
+				<-inFlight
+			}
+
+			for _, message := range received {
+				delivery := ReceivedMessage{
+					Message: message,
+					queue:   queue,
+					release: queue.inFlightRelease(inFlight),
+				}
+				if options.newBody == nil {
+					delivery.Body = aws.StringValue(message.Body)
+				} else {
+					body := options.newBody()
+					if err := processor.decodeMessage(ctx, message, &body); err != nil {
+						delivery.release()
+						continue
+					}
+					delivery.Body = body
+				}
+
+				if ctx.Err() != nil {
+					delivery.Nack()
+					continue
+				}
+				select {
+				case messages <- delivery:
+				case <-ctx.Done():
+					delivery.Nack()
+				}
+			}
+		}
+	}()
+
+	return messages
+}
+
+// inFlightRelease returns the function freeing an in flight slot once, it is also called when the visibility timeout ran out.
+func (queue *Queue) inFlightRelease(inFlight chan struct{}) func() {
+	var once sync.Once
+	release := func() {
+		once.Do(func() { <-inFlight })
+	}
+	timer := time.AfterFunc(time.Duration(queue.getVisibilityTimeoutSeconds())*time.Second, release)
+
+	return func() {
+		timer.Stop()
+		release()
+	}
+}
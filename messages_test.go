@@ -0,0 +1,70 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+)
+
+func TestMessagesClosesOnCancel(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "channel", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("first")
+	q.SendMessage("second")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages := q.Messages(ctx)
+
+	message := <-messages
+	if message.Err != nil {
+		t.Fatal(message.Err)
+	}
+	if err := message.Ack(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case _, ok := <-messages:
+			if !ok {
+				if remaining := client.Messages(q.URL); len(remaining) != 1 {
+					t.Errorf("expected the unacked message to stay in the queue, got %v", remaining)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("expected the channel to be closed after the context was cancelled")
+		}
+	}
+}
+
+func TestMessagesDeliversReceiveErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &scheduledReceiveClient{fakeClient: newFakeClient(), failures: []bool{true, true}, stop: cancel}
+	q, err := queue.New("failing", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var errs []error
+	for message := range q.Messages(ctx) {
+		if message.Err == nil {
+			t.Fatalf("expected only receive errors, got %v", message.Message)
+		}
+		errs = append(errs, message.Err)
+	}
+
+	if len(errs) != 2 || errs[0].Error() != "receive failed" {
+		t.Errorf("expected the 2 receive errors, got %v", errs)
+	}
+}
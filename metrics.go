@@ -0,0 +1,190 @@
+package queue
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Metrics receives the events of a Processor, e.g. for exporting them to a monitoring system.
+// The hooks are called from the workers concurrently, implementations have to be threadsafe.
+type Metrics interface {
+	// MessageReceived is called for each received message.
+	MessageReceived()
+	// MessageLag is called with the age of each received message.
+	MessageLag(lag time.Duration)
+	// MessageProcessed is called with the handler duration of each successfully handled message.
+	MessageProcessed(duration time.Duration)
+	// MessageFailed is called with the error of each message that could not be decoded or handled.
+	MessageFailed(err error)
+	// MessageDeleted is called for each message deleted after handling.
+	MessageDeleted()
+	// ReceiveError is called with the error of each failed receive.
+	ReceiveError(err error)
+}
+
+// NoopMetrics is a Metrics ignoring all events.
+type NoopMetrics struct{}
+
+// MessageReceived does nothing.
+func (NoopMetrics) MessageReceived() {}
+
+// MessageLag does nothing.
+func (NoopMetrics) MessageLag(lag time.Duration) {}
+
+// MessageProcessed does nothing.
+func (NoopMetrics) MessageProcessed(duration time.Duration) {}
+
+// MessageFailed does nothing.
+func (NoopMetrics) MessageFailed(err error) {}
+
+// MessageDeleted does nothing.
+func (NoopMetrics) MessageDeleted() {}
+
+// ReceiveError does nothing.
+func (NoopMetrics) ReceiveError(err error) {}
+
+// DuplicateSkipped does nothing.
+func (NoopMetrics) DuplicateSkipped() {}
+
+// DeduplicationMiss does nothing.
+func (NoopMetrics) DeduplicationMiss() {}
+
+// ArchiveFailed does nothing.
+func (NoopMetrics) ArchiveFailed(err error) {}
+
+// MetricCounts is a snapshot of the counters of InMemoryMetrics.
+type MetricCounts struct {
+	Received       int64
+	Processed      int64
+	Failed         int64
+	Deleted        int64
+	ReceiveErrors  int64
+	ProcessingTime time.Duration
+	MaxLag         time.Duration
+
+	DuplicatesSkipped   int64
+	DeduplicationMisses int64
+	ArchiveFailures     int64
+}
+
+// InMemoryMetrics is a Metrics counting the events in memory.
+type InMemoryMetrics struct {
+	received       atomic.Int64
+	processed      atomic.Int64
+	failed         atomic.Int64
+	deleted        atomic.Int64
+	receiveErrors  atomic.Int64
+	processingTime atomic.Int64
+	maxLag         atomic.Int64
+
+	duplicatesSkipped   atomic.Int64
+	deduplicationMisses atomic.Int64
+	archiveFailures     atomic.Int64
+}
+
+// MessageReceived counts a received message.
+func (metrics *InMemoryMetrics) MessageReceived() {
+	metrics.received.Add(1)
+}
+
+// MessageLag keeps the largest lag.
+func (metrics *InMemoryMetrics) MessageLag(lag time.Duration) {
+	for {
+		max := metrics.maxLag.Load()
+		if int64(lag) <= max || metrics.maxLag.CompareAndSwap(max, int64(lag)) {
+			return
+		}
+	}
+}
+
+// MessageProcessed counts a handled message and its duration.
+func (metrics *InMemoryMetrics) MessageProcessed(duration time.Duration) {
+	metrics.processed.Add(1)
+	metrics.processingTime.Add(int64(duration))
+}
+
+// MessageFailed counts a failed message.
+func (metrics *InMemoryMetrics) MessageFailed(err error) {
+	metrics.failed.Add(1)
+}
+
+// MessageDeleted counts a deleted message.
+func (metrics *InMemoryMetrics) MessageDeleted() {
+	metrics.deleted.Add(1)
+}
+
+// ReceiveError counts a failed receive.
+func (metrics *InMemoryMetrics) ReceiveError(err error) {
+	metrics.receiveErrors.Add(1)
+}
+
+// DuplicateSkipped counts a skipped duplicate.
+func (metrics *InMemoryMetrics) DuplicateSkipped() {
+	metrics.duplicatesSkipped.Add(1)
+}
+
+// DeduplicationMiss counts a message that is not a duplicate.
+func (metrics *InMemoryMetrics) DeduplicationMiss() {
+	metrics.deduplicationMisses.Add(1)
+}
+
+// ArchiveFailed counts a message that could not be archived.
+func (metrics *InMemoryMetrics) ArchiveFailed(err error) {
+	metrics.archiveFailures.Add(1)
+}
+
+// Counts returns a snapshot of the counters.
+func (metrics *InMemoryMetrics) Counts() MetricCounts {
+	return MetricCounts{
+		Received:       metrics.received.Load(),
+		Processed:      metrics.processed.Load(),
+		Failed:         metrics.failed.Load(),
+		Deleted:        metrics.deleted.Load(),
+		ReceiveErrors:  metrics.receiveErrors.Load(),
+		ProcessingTime: time.Duration(metrics.processingTime.Load()),
+		MaxLag:         time.Duration(metrics.maxLag.Load()),
+
+		DuplicatesSkipped:   metrics.duplicatesSkipped.Load(),
+		DeduplicationMisses: metrics.deduplicationMisses.Load(),
+		ArchiveFailures:     metrics.archiveFailures.Load(),
+	}
+}
+
+// getMetrics returns the metrics of the processor, NoopMetrics when there are none.
+func (processor *Processor) getMetrics() Metrics {
+	if processor.Metrics == nil {
+		return NoopMetrics{}
+	}
+
+	return processor.Metrics
+}
+
+// recordReceive records the time of a successful receive call for Stats, also when it returned no message.
+func (processor *Processor) recordReceive(err error) {
+	if err == nil {
+		processor.getState().lastReceive.Store(time.Now().UnixNano())
+	}
+}
+
+// recordFailed reports a message that could not be decoded or handled.
+func (processor *Processor) recordFailed(err error) {
+	processor.getState().failedMessages.Add(1)
+	processor.getMetrics().MessageFailed(err)
+}
+
+// recordDeleted reports a message deleted after handling.
+func (processor *Processor) recordDeleted() {
+	processor.getState().deletedMessages.Add(1)
+	processor.getMetrics().MessageDeleted()
+}
+
+// recordReceived reports a received message and its lag to the metrics.
+func (processor *Processor) recordReceived(message *sqs.Message) {
+	metrics := processor.getMetrics()
+	metrics.MessageReceived()
+	if sentAt, ok := SentTime(message); ok {
+		metrics.MessageLag(time.Since(sentAt))
+	}
+}
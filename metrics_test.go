@@ -0,0 +1,82 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestInMemoryMetricsWithWorkers(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("measured", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sentAt := strconv.FormatInt(time.Now().Add(-5*time.Second).UnixNano()/int64(time.Millisecond), 10)
+	for i := 0; i < 8; i++ {
+		client.messages = append(client.messages, &sqs.Message{
+			MessageId:     aws.String(fmt.Sprintf("message-%d", i)),
+			ReceiptHandle: aws.String(fmt.Sprintf("receipt-%d", i)),
+			Body:          aws.String(strconv.Itoa(i)),
+			Attributes:    map[string]*string{sqs.MessageSystemAttributeNameSentTimestamp: aws.String(sentAt)},
+		})
+	}
+
+	metrics := &queue.InMemoryMetrics{}
+	processor := &queue.Processor{
+		Queue:       q,
+		Concurrency: 4,
+		Metrics:     metrics,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			time.Sleep(10 * time.Millisecond)
+			if body.(float64) < 2 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 8); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := metrics.Counts()
+	if counts.Received != 8 || counts.Processed != 6 || counts.Failed != 2 || counts.Deleted != 6 {
+		t.Errorf("expected 8 received, 6 processed and deleted and 2 failed messages, got %+v", counts)
+	}
+	if counts.ProcessingTime < 6*10*time.Millisecond {
+		t.Errorf("expected the processing time of the handled messages, got %s", counts.ProcessingTime)
+	}
+	if counts.MaxLag < 5*time.Second || counts.MaxLag > time.Minute {
+		t.Errorf("expected the lag since the sent timestamp, got %s", counts.MaxLag)
+	}
+}
+
+func TestInMemoryMetricsReceiveErrors(t *testing.T) {
+	client := &scheduledReceiveClient{fakeClient: newFakeClient(), failures: []bool{true, true}}
+	q, err := queue.New("measured", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := &queue.InMemoryMetrics{}
+	processor := (&queue.Processor{
+		Queue:         q,
+		Metrics:       metrics,
+		HandleMessage: failWith(nil),
+	}).WithReceiveBackoff(time.Millisecond, time.Millisecond)
+	client.stop = processor.Stop
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if counts := metrics.Counts(); counts.ReceiveErrors != 2 || counts.Received != 0 {
+		t.Errorf("expected 2 receive errors, got %+v", counts)
+	}
+}
@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MessageHandlerFunc handles the decoded body of a message, the raw message is passed for its ID and attributes.
+type MessageHandlerFunc func(ctx context.Context, body interface{}, message *sqs.Message) error
+
+// Middleware wraps the handling of messages, it may act before and after calling next or not call it at all.
+type Middleware func(next MessageHandlerFunc) MessageHandlerFunc
+
+// Use adds middleware around the handler, the first one added runs outermost.
+func (processor *Processor) Use(middleware ...Middleware) *Processor {
+	processor.middleware = append(processor.middleware, middleware...)
+
+	return processor
+}
+
+// wrapMiddleware wraps the handler in the middleware of the processor.
+func (processor *Processor) wrapMiddleware(handler MessageHandlerFunc) MessageHandlerFunc {
+	for i := len(processor.middleware) - 1; i >= 0; i-- {
+		handler = processor.middleware[i](handler)
+	}
+
+	return handler
+}
+
+// LogDuration returns a Middleware logging how long handling each message took, to the logrus standard logger when logger is nil.
+func LogDuration(logger Logger) Middleware {
+	if logger == nil {
+		logger = NewLogrusLogger(nil)
+	}
+
+	return func(next MessageHandlerFunc) MessageHandlerFunc {
+		return func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			start := time.Now()
+			err := next(ctx, body, message)
+
+			logger.Info("Handled message", Fields{
+				"messageID": aws.StringValue(message.MessageId),
+				"duration":  time.Since(start),
+				"error":     err,
+			})
+
+			return err
+		}
+	}
+}
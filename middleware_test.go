@@ -0,0 +1,165 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// recordingLogger records the level, message and fields of the entries.
+type recordingLogger struct {
+	mutex   sync.Mutex
+	entries []queue.Fields
+}
+
+func (logger *recordingLogger) Debug(msg string, fields queue.Fields) {
+	logger.record("debug", msg, fields)
+}
+
+func (logger *recordingLogger) Info(msg string, fields queue.Fields) {
+	logger.record("info", msg, fields)
+}
+
+func (logger *recordingLogger) Warn(msg string, fields queue.Fields) {
+	logger.record("warn", msg, fields)
+}
+
+func (logger *recordingLogger) Error(msg string, fields queue.Fields) {
+	logger.record("error", msg, fields)
+}
+
+func (logger *recordingLogger) record(level string, msg string, fields queue.Fields) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	logger.entries = append(logger.entries, queue.Fields{"level": level, "msg": msg, "fields": fields})
+}
+
+// leveled returns the entries of the level.
+func (logger *recordingLogger) leveled(level string) (entries []queue.Fields) {
+	logger.mutex.Lock()
+	defer logger.mutex.Unlock()
+	for _, entry := range logger.entries {
+		if entry["level"] == level {
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
+
+// recordMiddleware returns a Middleware appending it's name to calls before and after next.
+func recordMiddleware(name string, calls *[]string) queue.Middleware {
+	return func(next queue.MessageHandlerFunc) queue.MessageHandlerFunc {
+		return func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			*calls = append(*calls, name+" before")
+			err := next(ctx, body, message)
+			*calls = append(*calls, name+" after")
+			return err
+		}
+	}
+}
+
+func TestMiddlewareOrderAndErrors(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "wrapped", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	var calls []string
+	var seen error
+	handlerErr := errors.New("handler failed")
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			calls = append(calls, "handler")
+			return handlerErr
+		},
+	}).Use(recordMiddleware("outer", &calls), func(next queue.MessageHandlerFunc) queue.MessageHandlerFunc {
+		return func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			seen = next(ctx, body, message)
+			return seen
+		}
+	}, recordMiddleware("inner", &calls))
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"outer before", "inner before", "handler", "inner after", "outer after"}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected the calls %v, got %v", expected, calls)
+	}
+	if seen != handlerErr {
+		t.Errorf("expected the middleware to see the handler error, got %v", seen)
+	}
+	if summary.Failed != 1 || len(client.Messages(q.URL)) != 1 {
+		t.Errorf("expected the error to fail the message, got %+v", summary)
+	}
+}
+
+func TestMiddlewareShortCircuits(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "wrapped", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	called := false
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			called = true
+			return nil
+		},
+	}).Use(func(next queue.MessageHandlerFunc) queue.MessageHandlerFunc {
+		return func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		}
+	})
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if called {
+		t.Error("expected the handler not to be called")
+	}
+	if summary.Processed != 1 || len(client.Messages(q.URL)) != 0 {
+		t.Errorf("expected the short-circuited message to be deleted, got %+v", summary)
+	}
+}
+
+func TestLogDuration(t *testing.T) {
+	logger := &recordingLogger{}
+	handlerErr := errors.New("handler failed")
+	handler := queue.LogDuration(logger)(func(ctx context.Context, body interface{}, message *sqs.Message) error {
+		time.Sleep(10 * time.Millisecond)
+		return handlerErr
+	})
+
+	messageID := "message-1"
+	if err := handler(context.Background(), nil, &sqs.Message{MessageId: &messageID}); err != handlerErr {
+		t.Fatalf("expected the handler error, got %v", err)
+	}
+
+	entries := logger.leveled("info")
+	if len(entries) != 1 {
+		t.Fatalf("expected one log entry, got %v", logger.entries)
+	}
+	fields := entries[0]["fields"].(queue.Fields)
+	if fields["messageID"] != messageID || fields["error"] != handlerErr {
+		t.Errorf("expected the message ID and the error, got %v", fields)
+	}
+	if duration, ok := fields["duration"].(time.Duration); !ok || duration < 10*time.Millisecond {
+		t.Errorf("expected the handling duration, got %v", fields["duration"])
+	}
+}
@@ -0,0 +1,40 @@
+package queue
+
+import (
+	"context"
+	"errors"
+)
+
+// checkQueueExists returns the unrecoverable error of a receive error about a missing queue, or nil for other errors.
+// Up to RecreateOnMissing times the queue is initialized again instead, counted by recreated.
+func (processor *Processor) checkQueueExists(ctx context.Context, err error, recreated *int) error {
+	switch {
+	case errors.Is(err, ErrQueueNotInitialized):
+	case isQueueNotFound(err):
+		err = &queueNotFoundError{name: processor.Queue.Name, err: err}
+	default:
+		return nil
+	}
+
+	if *recreated < processor.RecreateOnMissing {
+		*recreated++
+		processor.getLogger().Warn("Queue is missing, initializing it again", Fields{
+			"queueName": processor.Queue.Name,
+			"attempt":   *recreated,
+			"error":     err,
+		})
+		if initErr := processor.Queue.InitContext(ctx); initErr == nil {
+			return nil
+		}
+	}
+
+	processor.getLogger().Error("Queue is missing, processing stopped", Fields{
+		"queueName": processor.Queue.Name,
+		"error":     err,
+	})
+	if processor.OnUnrecoverableError != nil {
+		processor.OnUnrecoverableError(err)
+	}
+
+	return err
+}
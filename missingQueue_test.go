@@ -0,0 +1,78 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// nonExistentQueueClient fails the receives with the error of SQS for a deleted queue.
+type nonExistentQueueClient struct {
+	*fakeClient
+}
+
+func (client *nonExistentQueueClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	return nil, awserr.New("AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.", nil)
+}
+
+func TestProcessStopsOnNonExistentQueue(t *testing.T) {
+	q, err := queue.New("deleted", queue.WithClient(&nonExistentQueueClient{fakeClient: newFakeClient()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var unrecoverable error
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+		OnUnrecoverableError: func(err error) {
+			unrecoverable = err
+		},
+	}
+	err = processor.ProcessWithContext(context.Background(), nil)
+
+	if !errors.Is(err, queue.ErrQueueNotFound) {
+		t.Fatalf("expected ErrQueueNotFound, got %v", err)
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != "AWS.SimpleQueueService.NonExistentQueue" {
+		t.Errorf("expected the AWS error to be wrapped, got %v", err)
+	}
+	if unrecoverable != err {
+		t.Errorf("expected OnUnrecoverableError to get the error, got %v", unrecoverable)
+	}
+}
+
+func TestLegacyProcessLogsStoppingError(t *testing.T) {
+	q, err := queue.New("deleted", queue.WithClient(&nonExistentQueueClient{fakeClient: newFakeClient()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := &recordingLogger{}
+	processor := &queue.Processor{
+		Queue:  q,
+		Logger: logger,
+		HandleMessageBody: func(processor queue.Processor, body *interface{}) error {
+			return nil
+		},
+	}
+	processor.Process(nil)
+
+	entries := logger.leveled("error")
+	if len(entries) == 0 {
+		t.Fatal("expected the error stopping Process to be logged")
+	}
+	last := entries[len(entries)-1]["fields"].(queue.Fields)
+	if err, _ := last["error"].(error); !errors.Is(err, queue.ErrQueueNotFound) {
+		t.Errorf("expected ErrQueueNotFound to be logged, got %v", last)
+	}
+}
@@ -0,0 +1,130 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MoveOptions select and rewrite the messages moved by Move.
+type MoveOptions struct {
+	// MaxMessages is the maximum number of messages moved or skipped, all of them when not positive.
+	MaxMessages int
+	// MaxDuration stops moving after the duration, no limit when not positive.
+	MaxDuration time.Duration
+	// Transform rewrites the body of each message before it is sent, returning nil skips the message.
+	// Skipped messages are deleted from the source without being sent. A message whose Transform fails stays in the source.
+	Transform func(body []byte) ([]byte, error)
+}
+
+// MoveReport counts the messages handled by Move.
+type MoveReport struct {
+	Moved   int
+	Skipped int
+	Failed  int
+}
+
+// Move moves the messages of the source queue to the destination queue with their attributes, until the source is empty.
+// A message is deleted from the source only after it was sent. Messages that could not be sent stay invisible
+// in the source until their visibility timeout runs out. Reaching MaxMessages or MaxDuration is not an error.
+func Move(ctx context.Context, src, dst *Queue, opts MoveOptions) (report MoveReport, err error) {
+	if opts.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.MaxDuration)
+		defer cancel()
+	}
+
+	handled := func() int { return report.Moved + report.Skipped }
+	for opts.MaxMessages <= 0 || handled() < opts.MaxMessages {
+		receiveSize := MaxBatchSize
+		if remaining := opts.MaxMessages - handled(); opts.MaxMessages > 0 && remaining < receiveSize {
+			receiveSize = remaining
+		}
+
+		messages, err := src.ReceiveMessagesContext(ctx, int64(receiveSize))
+		if err != nil {
+			return report, moveStopped(err)
+		}
+		if len(messages) == 0 {
+			return report, nil
+		}
+
+		for _, message := range messages {
+			if err := ctx.Err(); err != nil {
+				return report, moveStopped(err)
+			}
+
+			params, err := src.moveInput(ctx, message, dst, opts.Transform)
+			if err != nil {
+				src.GetLogger().Warn("Transforming message to move", Fields{
+					"queueName": src.Name,
+					"messageID": message.MessageId,
+					"error":     err,
+				})
+				report.Failed++
+				continue
+			}
+			if params == nil {
+				report.Skipped++
+			} else if _, err := dst.sendMessageInput(ctx, params); err != nil {
+				report.Failed++
+				continue
+			} else {
+				report.Moved++
+			}
+
+			// Untransformed messages are deleted without their S3 payload, the moved message refers to it.
+			if _, err := src.deleteMessageByReceiptHandle(ctx, message.ReceiptHandle); err != nil {
+				src.GetLogger().Warn("Message moved but not deleted from source queue", Fields{
+					"queueName":       src.Name,
+					"targetQueueName": dst.Name,
+					"messageID":       message.MessageId,
+					"error":           err,
+				})
+			}
+		}
+	}
+
+	return
+}
+
+// moveStopped returns nil for the deadline of MaxDuration, the other errors as they are.
+func moveStopped(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+
+	return err
+}
+
+// moveInput returns the input sending the message to the destination, nil when the transform skips it.
+// Transformed bodies are sent as plain bodies, offloaded and encrypted as configured for the destination.
+func (queue *Queue) moveInput(ctx context.Context, message *sqs.Message, dst *Queue, transform func(body []byte) ([]byte, error)) (*sqs.SendMessageInput, error) {
+	if transform == nil {
+		return dst.resendInput(message, message.MessageAttributes), nil
+	}
+
+	body, err := queue.getMessageBody(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	transformed, err := transform([]byte(body))
+	if err != nil || transformed == nil {
+		return nil, err
+	}
+
+	attributes := make(map[string]*sqs.MessageAttributeValue, len(message.MessageAttributes))
+	for name, value := range message.MessageAttributes {
+		if name == LargePayloadSizeAttribute || name == ContentEncryptionAttribute {
+			continue
+		}
+		attributes[name] = value
+	}
+	params := dst.resendInput(message, attributes)
+	params.MessageBody = aws.String(string(transformed))
+
+	return params, nil
+}
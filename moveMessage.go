@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MoveMessageToQueue sends the message with it's original body and attributes to the target queue, then deletes it from this queue.
+// The message is not deleted if sending fails. On FIFO targets it keeps it's message group and is deduplicated by it's ID.
+func (queue *Queue) MoveMessageToQueue(ctx context.Context, message *sqs.Message, target *Queue) error {
+	// The attributes are copied, so preparing the send doesn't write into the received message.
+	attributes := make(map[string]*sqs.MessageAttributeValue, len(message.MessageAttributes))
+	for name, value := range message.MessageAttributes {
+		attributes[name] = value
+	}
+	if _, err := target.sendMessageInput(ctx, target.resendInput(message, attributes)); err != nil {
+		return err
+	}
+
+	if _, err := queue.DeleteMessageContext(ctx, message); err != nil {
+		queue.GetLogger().Warn("Message sent to target queue but not deleted from source queue", Fields{
+			"queueName":       queue.Name,
+			"targetQueueName": target.Name,
+			"messageID":       message.MessageId,
+			"error":           err,
+		})
+		return err
+	}
+
+	return nil
+}
+
+// getDeadLetterQueue returns the dead letter queue of the queue, sharing it's client, logger and receive settings,
+// and the encryption keys and validator, as it holds the messages of the queue.
+func (queue *Queue) getDeadLetterQueue() *Queue {
+	return &Queue{
+		Name:              queue.getDeadLetterQueueName(),
+		URL:               queue.DeadLetterQueueURL,
+		Client:            queue.GetClient(),
+		Logger:            queue.Logger,
+		Marshaller:        queue.Marshaller,
+		fifo:              queue.fifo,
+		visibilityTimeout: queue.visibilityTimeout,
+		waitTime:          queue.waitTime,
+		attributeNames:    queue.attributeNames,
+		largePayloads:     queue.largePayloads,
+		encryption:        queue.encryption,
+		validator:         queue.validator,
+	}
+}
+
+// resendInput returns the input sending the received message to the queue with the attributes.
+// On FIFO queues the message keeps it's message group and is deduplicated by it's ID.
+func (queue *Queue) resendInput(message *sqs.Message, attributes map[string]*sqs.MessageAttributeValue) *sqs.SendMessageInput {
+	params := &sqs.SendMessageInput{
+		MessageBody: message.Body,
+		QueueUrl:    aws.String(queue.URL),
+	}
+	if len(attributes) > 0 {
+		params.MessageAttributes = attributes
+	}
+	if queue.fifo {
+		groupID, ok := message.Attributes[sqs.MessageSystemAttributeNameMessageGroupId]
+		if !ok {
+			groupID = message.MessageId
+		}
+		params.MessageGroupId = groupID
+		params.MessageDeduplicationId = message.MessageId
+	}
+
+	return params
+}
@@ -0,0 +1,130 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// movedQueues returns a source and a target queue of the client with a message received from the source queue.
+func movedQueues(t *testing.T, client *memqueue.Client, opts ...queue.Option) (source *queue.Queue, target *queue.Queue, message *sqs.Message) {
+	t.Helper()
+
+	source, err := memqueue.NewWithClient(client, "source", append(opts, queue.WithReceiveWaitTime(0))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err = memqueue.NewWithClient(client, "target", queue.WithReceiveWaitTime(0), queue.WithSendHook(
+		func(ctx context.Context, attributes map[string]*sqs.MessageAttributeValue) {
+			attributes["hooked"] = queue.StringAttribute("true")
+		},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.SendMessageWithAttributes("body", map[string]string{"tenant": "acme"}); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := source.ReceiveMessagesContext(context.Background(), 1)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected 1 message, got %v and %v", messages, err)
+	}
+
+	return source, target, messages[0]
+}
+
+func TestMoveMessageToQueue(t *testing.T) {
+	client := memqueue.NewClient()
+	source, target, message := movedQueues(t, client)
+
+	if err := source.MoveMessageToQueue(context.Background(), message, target); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := client.Messages(source.URL); len(remaining) != 0 {
+		t.Errorf("expected the message to be deleted from the source queue, got %v", remaining)
+	}
+	moved, err := target.ReceiveMessagesContext(context.Background(), 1)
+	if err != nil || len(moved) != 1 || aws.StringValue(moved[0].Body) != `"body"` {
+		t.Fatalf("expected the message in the target queue, got %v and %v", moved, err)
+	}
+	if tenant := moved[0].MessageAttributes["tenant"]; tenant == nil || aws.StringValue(tenant.StringValue) != "acme" {
+		t.Errorf("expected the original attributes, got %v", moved[0].MessageAttributes)
+	}
+	if _, ok := message.MessageAttributes["hooked"]; ok {
+		t.Errorf("expected the attributes of the moved message not to be changed, got %v", message.MessageAttributes)
+	}
+}
+
+func TestMoveMessageToQueueSendFailure(t *testing.T) {
+	client := memqueue.NewClient()
+	source, target, message := movedQueues(t, client)
+	if _, err := client.DeleteQueueWithContext(context.Background(), &sqs.DeleteQueueInput{QueueUrl: aws.String(target.URL)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := source.MoveMessageToQueue(context.Background(), message, target); err == nil {
+		t.Fatal("expected the send error")
+	}
+
+	if remaining := client.Messages(source.URL); len(remaining) != 1 {
+		t.Errorf("expected the message to stay in the source queue, got %v", remaining)
+	}
+}
+
+func TestMoveMessageToQueueDeleteFailure(t *testing.T) {
+	client := memqueue.NewClient()
+	logger := &recordingLogger{}
+	source, target, message := movedQueues(t, client, queue.WithLogger(logger))
+	message.ReceiptHandle = aws.String("expired")
+
+	err := source.MoveMessageToQueue(context.Background(), message, target)
+
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != sqs.ErrCodeReceiptHandleIsInvalid {
+		t.Errorf("expected the delete error, got %v", err)
+	}
+	if moved := client.Messages(target.URL); len(moved) != 1 {
+		t.Errorf("expected the message in the target queue, got %v", moved)
+	}
+	if warnings := logger.leveled("warn"); len(warnings) != 1 {
+		t.Errorf("expected a warning about the message not being deleted, got %v", warnings)
+	}
+}
+
+func TestMoveMessageToFIFOQueue(t *testing.T) {
+	client := newFakeClient()
+	source, err := queue.New("source", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := queue.New("target", queue.WithClient(client), queue.WithFIFO(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := &sqs.Message{
+		MessageId:     aws.String("id"),
+		Body:          aws.String("body"),
+		ReceiptHandle: aws.String("receipt"),
+		Attributes:    map[string]*string{sqs.MessageSystemAttributeNameMessageGroupId: aws.String("group")},
+	}
+
+	if err := source.MoveMessageToQueue(context.Background(), message, target); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.sendInputs) != 1 {
+		t.Fatalf("expected 1 send, got %v", client.sendInputs)
+	}
+	input := client.sendInputs[0]
+	if aws.StringValue(input.MessageGroupId) != "group" || aws.StringValue(input.MessageDeduplicationId) != "id" {
+		t.Errorf("expected the message group and deduplication IDs, got %v", input)
+	}
+	if len(client.deleteInputs) != 1 || aws.StringValue(client.deleteInputs[0].ReceiptHandle) != "receipt" {
+		t.Errorf("expected the message to be deleted from the source queue, got %v", client.deleteInputs)
+	}
+}
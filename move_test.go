@@ -0,0 +1,113 @@
+package queue_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestMoveWithTransform(t *testing.T) {
+	client := memqueue.NewClient()
+	src, err := memqueue.NewWithClient(client, "src", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := memqueue.NewWithClient(client, "dst", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"a", "skip", "bad", "b"} {
+		src.SendMessage(body)
+	}
+
+	report, err := queue.Move(context.Background(), src, dst, queue.MoveOptions{
+		Transform: func(body []byte) ([]byte, error) {
+			switch string(body) {
+			case `"skip"`:
+				return nil, nil
+			case `"bad"`:
+				return nil, errors.New("can't transform")
+			}
+			return bytes.ToUpper(body), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != (queue.MoveReport{Moved: 2, Skipped: 1, Failed: 1}) {
+		t.Errorf("expected 2 moved, 1 skipped and 1 failed message, got %+v", report)
+	}
+	moved := client.Messages(dst.URL)
+	sort.Strings(moved)
+	if !reflect.DeepEqual(moved, []string{`"A"`, `"B"`}) {
+		t.Errorf("expected the transformed messages in the destination, got %v", moved)
+	}
+	if remaining := client.Messages(src.URL); !reflect.DeepEqual(remaining, []string{`"bad"`}) {
+		t.Errorf("expected only the failed message to stay in the source, got %v", remaining)
+	}
+}
+
+func TestMoveKeepsUnsentMessages(t *testing.T) {
+	client := memqueue.NewClient()
+	src, err := memqueue.NewWithClient(client, "src", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := memqueue.NewWithClient(client, "dst", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.SendMessage("first")
+	src.SendMessage("second")
+	if _, err := client.DeleteQueueWithContext(context.Background(), &sqs.DeleteQueueInput{QueueUrl: aws.String(dst.URL)}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := queue.Move(context.Background(), src, dst, queue.MoveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != (queue.MoveReport{Failed: 2}) {
+		t.Errorf("expected both sends to fail, got %+v", report)
+	}
+	if remaining := client.Messages(src.URL); len(remaining) != 2 {
+		t.Errorf("expected the unsent messages to stay in the source, got %v", remaining)
+	}
+}
+
+func TestMoveMaxMessages(t *testing.T) {
+	client := memqueue.NewClient()
+	src, err := memqueue.NewWithClient(client, "src", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := memqueue.NewWithClient(client, "dst", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		src.SendMessage(i)
+	}
+
+	report, err := queue.Move(context.Background(), src, dst, queue.MoveOptions{MaxMessages: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report != (queue.MoveReport{Moved: 3}) {
+		t.Errorf("expected 3 moved messages, got %+v", report)
+	}
+	if len(client.Messages(src.URL)) != 2 || len(client.Messages(dst.URL)) != 3 {
+		t.Errorf("expected 2 messages left in the source and 3 in the destination, got %v and %v", client.Messages(src.URL), client.Messages(dst.URL))
+	}
+}
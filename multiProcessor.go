@@ -0,0 +1,47 @@
+package queue
+
+import "context"
+
+// queueContextKey is the context key of the queue of the message being handled.
+type queueContextKey struct{}
+
+// QueueFromContext returns the queue of the message handled within the context, e.g. for a MultiProcessor handler.
+func QueueFromContext(ctx context.Context) (queue *Queue, ok bool) {
+	queue, ok = ctx.Value(queueContextKey{}).(*Queue)
+
+	return
+}
+
+// A MultiProcessor handles the messages of several queues in priority order with the settings of a Processor.
+// The first queue is drained first, a queue is only polled when the queues before it are empty.
+type MultiProcessor struct {
+	// Processor holds the handler, middleware, metrics and other settings, it's Queue is not used.
+	Processor *Processor
+	// Queues are the queues by decreasing priority.
+	Queues []*Queue
+}
+
+// NewMultiProcessor returns a MultiProcessor handling the queues, by decreasing priority, with the processor.
+func NewMultiProcessor(processor *Processor, queues ...*Queue) *MultiProcessor {
+	return &MultiProcessor{Processor: processor, Queues: queues}
+}
+
+// ProcessWithContext handles the messages of the queues until the context is cancelled or Stop is called on the processor,
+// like Processor.ProcessWithContext, with all it's settings, e.g. the circuit breaker and the batch handlers.
+// The queues are short polled in priority order, only the last one is long polled so an idle processor doesn't busy-wait.
+func (multi *MultiProcessor) ProcessWithContext(ctx context.Context, body interface{}) error {
+	if len(multi.Queues) == 0 {
+		return ErrQueueNotInitialized
+	}
+
+	// The copies share the state of the template, so counts and Stop apply to all queues.
+	multi.Processor.getState()
+	processors := make([]*Processor, len(multi.Queues))
+	for i, queue := range multi.Queues {
+		processor := *multi.Processor
+		processor.Queue = queue
+		processors[i] = &processor
+	}
+
+	return processQueues(ctx, body, processors)
+}
@@ -0,0 +1,168 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestMultiProcessorDrainsHighPriorityFirst(t *testing.T) {
+	client := memqueue.NewClient()
+	high, err := memqueue.NewWithClient(client, "high", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, err := memqueue.NewWithClient(client, "low", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		low.SendMessage(i)
+		high.SendMessage(i)
+	}
+
+	var handled []string
+	processor := (&queue.Processor{
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			q, _ := queue.QueueFromContext(ctx)
+			handled = append(handled, q.Name)
+			return nil
+		},
+	}).WithMaxMessages(6)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := queue.NewMultiProcessor(processor, high, low).ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"high", "high", "high", "low", "low", "low"}
+	if len(handled) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, handled)
+	}
+	for i := range expected {
+		if handled[i] != expected[i] {
+			t.Fatalf("expected the high priority queue to be drained first, got %v", handled)
+		}
+	}
+	if stats := processor.Stats(); stats.Processed != 6 || stats.Deleted != 6 {
+		t.Errorf("expected the counts of both queues in the stats, got %+v", stats)
+	}
+}
+
+func TestMultiProcessorCircuitBreaker(t *testing.T) {
+	client := memqueue.NewClient()
+	high, err := memqueue.NewWithClient(client, "high", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, err := memqueue.NewWithClient(client, "low", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	high.SendMessage("first")
+	low.SendMessage("second")
+
+	var mutex sync.Mutex
+	calls := 0
+	processor := (&queue.Processor{
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls++
+			return errors.New("database is down")
+		},
+	}).WithCircuitBreaker(1, time.Minute).WithEmptyReceivePause(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := queue.NewMultiProcessor(processor, high, low).ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if calls != 1 {
+		t.Errorf("expected the open circuit to stop receiving after 1 failure, got %d calls", calls)
+	}
+	if len(client.Messages(low.URL)) != 1 {
+		t.Error("expected the message of the low priority queue to stay in it")
+	}
+}
+
+func TestMultiProcessorMissingQueue(t *testing.T) {
+	client := memqueue.NewClient()
+	high, err := memqueue.NewWithClient(client, "high", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, err := memqueue.NewWithClient(client, "low", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.DeleteQueueWithContext(context.Background(), &sqs.DeleteQueueInput{QueueUrl: aws.String(high.URL)}); err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &queue.Processor{
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = queue.NewMultiProcessor(processor, high, low).ProcessWithContext(ctx, nil)
+	if !errors.Is(err, queue.ErrQueueNotFound) {
+		t.Fatalf("expected ErrQueueNotFound, got %v", err)
+	}
+	if ctx.Err() != nil {
+		t.Error("expected the processor to stop by itself")
+	}
+}
+
+func TestMultiProcessorDecodedBatches(t *testing.T) {
+	client := memqueue.NewClient()
+	high, err := memqueue.NewWithClient(client, "high", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, err := memqueue.NewWithClient(client, "low", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low.SendMessage("low")
+	high.SendMessage("high")
+	high.SendMessage("high")
+
+	var batches [][]interface{}
+	processor := (&queue.Processor{
+		HandleDecodedBatch: func(ctx context.Context, messages []queue.DecodedMessage) ([]queue.Failed, error) {
+			var bodies []interface{}
+			for _, message := range messages {
+				bodies = append(bodies, message.Body)
+			}
+			batches = append(batches, bodies)
+			return nil, nil
+		},
+	}).WithMaxMessages(3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := queue.NewMultiProcessor(processor, high, low).ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 2 || len(batches[0]) != 2 || batches[0][0] != "high" || len(batches[1]) != 1 || batches[1][0] != "low" {
+		t.Errorf("expected a batch of each queue by priority, got %v", batches)
+	}
+	if len(client.Messages(high.URL)) != 0 || len(client.Messages(low.URL)) != 0 {
+		t.Error("expected the handled batches to be deleted")
+	}
+}
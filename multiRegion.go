@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// ErrMultiRegionClient is returned when WithClient is passed to NewMultiRegion, one client can not serve every region.
+var ErrMultiRegionClient = errors.New("WithClient can not be used for multi-region queues")
+
+// A MultiError collects the errors of operations running in parallel.
+type MultiError []error
+
+// Error returns the messages of all errors.
+func (errs MultiError) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// A RegionError is an error of creating the queue in one region.
+type RegionError struct {
+	Region string
+	Err    error
+}
+
+// Error returns the region and the error message.
+func (err *RegionError) Error() string {
+	return err.Region + ": " + err.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (err *RegionError) Unwrap() error {
+	return err.Err
+}
+
+// NewMultiRegion creates the same queue in every region concurrently.
+// It returns the successfully created queues in the order of the regions, and a MultiError of RegionErrors on failures.
+// Each queue has it's own client scoped to it's region, the region overrides a WithRegion of opts,
+// and ErrMultiRegionClient is returned for a WithClient option before creating any queue.
+func NewMultiRegion(name string, regions []string, opts ...Option) ([]*Queue, error) {
+	options := Queue{Name: name}
+	for _, opt := range opts {
+		if err := opt(&options); err != nil {
+			return nil, err
+		}
+	}
+	if options.Client != nil {
+		return nil, ErrMultiRegionClient
+	}
+
+	queues := make([]*Queue, len(regions))
+	errs := make([]error, len(regions))
+
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func(i int, region string) {
+			defer wg.Done()
+
+			regionOpts := append(append(make([]Option, 0, len(opts)+1), opts...), WithRegion(region))
+			queues[i], errs[i] = New(name, regionOpts...)
+		}(i, region)
+	}
+	wg.Wait()
+
+	created := make([]*Queue, 0, len(regions))
+	var multiErr MultiError
+	for i, region := range regions {
+		if errs[i] != nil {
+			multiErr = append(multiErr, &RegionError{Region: region, Err: errs[i]})
+			continue
+		}
+		created = append(created, queues[i])
+	}
+
+	if len(multiErr) > 0 {
+		return created, multiErr
+	}
+
+	return created, nil
+}
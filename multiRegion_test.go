@@ -0,0 +1,72 @@
+package queue_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+)
+
+// regionServer answers CreateQueue calls, recording the signing region of each.
+type regionServer struct {
+	mutex   sync.Mutex
+	regions []string
+}
+
+func (server *regionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The credential scope is id/date/region/service/aws4_request.
+	scope := strings.Split(strings.SplitN(r.Header.Get("Authorization"), "Credential=", 2)[1], "/")
+	server.mutex.Lock()
+	server.regions = append(server.regions, scope[2])
+	server.mutex.Unlock()
+
+	var input struct{ QueueName string }
+	json.NewDecoder(r.Body).Decode(&input)
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	json.NewEncoder(w).Encode(map[string]string{
+		"QueueUrl": "https://sqs." + scope[2] + ".amazonaws.com/000000000000/" + input.QueueName,
+	})
+}
+
+func TestNewMultiRegionScopesRegions(t *testing.T) {
+	server := &regionServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+
+	queues, err := queue.NewMultiRegion("geo", []string{"eu-west-1", "us-east-1"},
+		queue.WithRegion("ap-south-1"),
+		queue.WithEndpoint(httpServer.URL),
+		queue.WithCredentials(credentials.NewStaticCredentials("id", "secret", "")),
+		queue.WithoutDeadLetterQueue(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queues) != 2 || queues[0].Region != "eu-west-1" || queues[1].Region != "us-east-1" {
+		t.Fatalf("expected the queues of the regions in order, got %v", queues)
+	}
+	sort.Strings(server.regions)
+	if strings.Join(server.regions, ",") != "eu-west-1,us-east-1" {
+		t.Errorf("expected the calls to be signed for each region, got %v", server.regions)
+	}
+}
+
+func TestNewMultiRegionRejectsClient(t *testing.T) {
+	queues, err := queue.NewMultiRegion("geo", []string{"eu-west-1"}, queue.WithClient(memqueue.NewClient()))
+
+	if !errors.Is(err, queue.ErrMultiRegionClient) {
+		t.Errorf("expected ErrMultiRegionClient, got %v", err)
+	}
+	if queues != nil {
+		t.Errorf("expected no queues, got %v", queues)
+	}
+}
@@ -0,0 +1,50 @@
+package queue
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/defaults"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// ErrCodeNotImplemented is the AWS error code of the calls failed by NotImplementedClient.
+const ErrCodeNotImplemented = "NotImplemented"
+
+// notImplementedConfig is the config of the clients returned by errorClient, it never loads credentials or profiles.
+type notImplementedConfig struct{}
+
+// ClientConfig returns a config with anonymous credentials and the default handlers.
+func (notImplementedConfig) ClientConfig(serviceName string, cfgs ...*aws.Config) client.Config {
+	config := defaults.Config().WithRegion("us-east-1").WithCredentials(credentials.AnonymousCredentials)
+
+	return client.Config{
+		Config:        config,
+		Handlers:      defaults.Handlers(),
+		Endpoint:      "https://sqs.us-east-1.amazonaws.com",
+		SigningRegion: "us-east-1",
+	}
+}
+
+// NotImplementedClient returns an SQS client failing every call with an ErrCodeNotImplemented AWS error,
+// without sending requests. Embed it in partial sqsiface.SQSAPI implementations, e.g. fakes in tests,
+// so the calls they do not implement return an error instead of panicking.
+func NotImplementedClient() sqsiface.SQSAPI {
+	return errorClient(func(operation string) error {
+		return awserr.New(ErrCodeNotImplemented, operation+" is not implemented", nil)
+	})
+}
+
+// errorClient returns an SQS client failing every call with the error of newError, without sending requests.
+func errorClient(newError func(operation string) error) sqsiface.SQSAPI {
+	svc := sqs.New(notImplementedConfig{})
+	svc.Handlers.Clear()
+	svc.Handlers.Send.PushBack(func(r *request.Request) {
+		r.Error = newError(r.Operation.Name)
+	})
+
+	return svc
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Open returns an existing SQS queue without creating anything, e.g. for queues provisioned elsewhere.
+// The dead letter queue is resolved from the redrive policy of the queue, if it has one.
+// ErrQueueNotFound is returned when the queue does not exist.
+func Open(name string, opts ...Option) (*Queue, error) {
+	queue := Queue{Name: name}
+	for _, opt := range opts {
+		if err := opt(&queue); err != nil {
+			return &queue, err
+		}
+	}
+	err := queue.OpenContext(aws.BackgroundContext())
+
+	return &queue, err
+}
+
+// OpenContext resolves the URLs of the existing queue and it's dead letter queue within the context.
+func (queue *Queue) OpenContext(ctx context.Context) (err error) {
+	client := queue.GetClient()
+	queue.URL, err = GetQueueURL(ctx, client, queue.Name)
+	if err != nil {
+		queue.GetLogger().Error("Opening the queue", Fields{
+			"queueName": queue.Name,
+			"error":     err,
+		})
+		return
+	}
+
+	resp, err := queue.getAttributesByQueueURL(ctx, queue.URL, []*string{aws.String(sqs.QueueAttributeNameRedrivePolicy)})
+	if err != nil {
+		return
+	}
+	redrivePolicy, err := parseRedrivePolicy(aws.StringValue(resp.Attributes[sqs.QueueAttributeNameRedrivePolicy]))
+	if err != nil {
+		return
+	}
+	if redrivePolicy != nil {
+		accountID, name, err := parseQueueArn(redrivePolicy.DeadLetterTargetArn)
+		if err != nil {
+			return err
+		}
+		deadLetterQueueURL, err := getQueueURL(ctx, client, name, accountID)
+		if err != nil {
+			queue.GetLogger().Error("Resolving the dead letter queue", Fields{
+				"queueName":       queue.Name,
+				"deadLetterQueue": redrivePolicy.DeadLetterTargetArn,
+				"error":           err,
+			})
+			return err
+		}
+		queue.DeadLetterQueueURL = deadLetterQueueURL
+	}
+
+	queue.GetLogger().Info("Queue opened", Fields{
+		"QueueUrl":           queue.URL,
+		"DeadLetterQueueUrl": queue.DeadLetterQueueURL,
+	})
+
+	return
+}
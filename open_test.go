@@ -0,0 +1,68 @@
+package queue_test
+
+import (
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// noCreateClient fails the queue creation, like a role without sqs:CreateQueue.
+type noCreateClient struct {
+	*memqueue.Client
+}
+
+func (client *noCreateClient) CreateQueueWithContext(ctx aws.Context, input *sqs.CreateQueueInput, opts ...request.Option) (*sqs.CreateQueueOutput, error) {
+	return nil, errors.New("sqs:CreateQueue is not allowed")
+}
+
+func TestOpenWithRedrivePolicy(t *testing.T) {
+	client := memqueue.NewClient()
+	created, err := memqueue.NewWithClient(client, "provisioned")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := queue.Open("provisioned", queue.WithClient(&noCreateClient{Client: client}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.URL != created.URL {
+		t.Errorf("expected the URL %s, got %s", created.URL, q.URL)
+	}
+	if q.DeadLetterQueueURL != created.DeadLetterQueueURL {
+		t.Errorf("expected the dead letter queue URL %s, got %s", created.DeadLetterQueueURL, q.DeadLetterQueueURL)
+	}
+}
+
+func TestOpenWithoutRedrivePolicy(t *testing.T) {
+	client := memqueue.NewClient()
+	created, err := memqueue.NewWithClient(client, "provisioned", queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := queue.Open("provisioned", queue.WithClient(&noCreateClient{Client: client}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.URL != created.URL || q.DeadLetterQueueURL != "" {
+		t.Errorf("expected the queue without a dead letter queue, got %q and %q", q.URL, q.DeadLetterQueueURL)
+	}
+}
+
+func TestOpenMissingQueue(t *testing.T) {
+	q, err := queue.Open("missing", queue.WithClient(&noCreateClient{Client: memqueue.NewClient()}))
+	if !errors.Is(err, queue.ErrQueueNotFound) {
+		t.Fatalf("expected ErrQueueNotFound, got %v", err)
+	}
+	if q.URL != "" {
+		t.Errorf("expected no URL, got %s", q.URL)
+	}
+}
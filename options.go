@@ -0,0 +1,298 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// ErrInvalidRetentionPeriod is returned when the retention period is outside the SQS range of 1 minute to 14 days.
+var ErrInvalidRetentionPeriod = errors.New("retention period must be between 1 minute and 14 days")
+
+// ErrInvalidMaxReceiveCount is returned when the max receive count is outside the SQS range of 1 to 1000.
+var ErrInvalidMaxReceiveCount = errors.New("max receive count must be between 1 and 1000")
+
+// ErrInvalidDeadLetterSuffix is returned for an empty dead letter queue suffix.
+var ErrInvalidDeadLetterSuffix = errors.New("dead letter queue suffix must not be empty")
+
+// ErrInvalidVisibilityTimeout is returned when the visibility timeout is outside the SQS range of 0 to 12 hours.
+var ErrInvalidVisibilityTimeout = errors.New("visibility timeout must be between 0 and 12 hours")
+
+// ErrInvalidWaitTime is returned when the receive wait time is outside the SQS range of 0 to 20 seconds.
+var ErrInvalidWaitTime = errors.New("wait time must be between 0 and 20 seconds")
+
+// ErrInvalidBatchRetries is returned for a negative number of batch retries.
+var ErrInvalidBatchRetries = errors.New("batch retries must not be negative")
+
+// Maximum visibility timeout SQS allows.
+const maxVisibilityTimeout = 12 * time.Hour
+
+// ErrInvalidDataKeyReusePeriod is returned when the KMS data key reuse period is outside the SQS range of 1 minute to 24 hours.
+var ErrInvalidDataKeyReusePeriod = errors.New("data key reuse period must be between 1 minute and 24 hours")
+
+// An Option configures a Queue before it is initialized.
+type Option func(queue *Queue) error
+
+// WithClient sets the SQS client used by the queue.
+func WithClient(client sqsiface.SQSAPI) Option {
+	return func(queue *Queue) error {
+		queue.SetClient(client)
+		return nil
+	}
+}
+
+// WithRegion sets the region of the queue.
+func WithRegion(region string) Option {
+	return func(queue *Queue) error {
+		queue.Region = region
+		return nil
+	}
+}
+
+// WithEndpoint sets a custom SQS endpoint, e.g. a local emulator like localstack or GoAWS.
+func WithEndpoint(endpoint string) Option {
+	return func(queue *Queue) error {
+		queue.Endpoint = endpoint
+		return nil
+	}
+}
+
+// WithRetentionPeriod sets the message retention period of the queue and its dead letter queue.
+// It defaults to 14 days.
+func WithRetentionPeriod(retentionPeriod time.Duration) Option {
+	return func(queue *Queue) error {
+		if retentionPeriod < time.Minute || retentionPeriod > defaultRetentionPeriod {
+			return ErrInvalidRetentionPeriod
+		}
+		queue.retentionPeriod = retentionPeriod
+		return nil
+	}
+}
+
+// WithMaxReceiveCount sets the receive count before a message is sent to the dead letter queue.
+// It defaults to MaxReceiveCountBeforeDead.
+func WithMaxReceiveCount(maxReceiveCount int) Option {
+	return func(queue *Queue) error {
+		if maxReceiveCount < 1 || maxReceiveCount > 1000 {
+			return ErrInvalidMaxReceiveCount
+		}
+		queue.maxReceiveCount = maxReceiveCount
+		return nil
+	}
+}
+
+// WithDeadLetterSuffix sets the name suffix of the dead letter queue.
+// It defaults to "-deadMessages".
+func WithDeadLetterSuffix(suffix string) Option {
+	return func(queue *Queue) error {
+		if suffix == "" {
+			return ErrInvalidDeadLetterSuffix
+		}
+		queue.deadLetterSuffix = suffix
+		return nil
+	}
+}
+
+// WithoutDeadLetterQueue creates the queue without a dead letter queue and redrive policy.
+// Messages that keep failing stay in the queue until they expire.
+func WithoutDeadLetterQueue() Option {
+	return func(queue *Queue) error {
+		queue.withoutDeadLetterQueue = true
+		return nil
+	}
+}
+
+// WithDeadLetterQueue redrives into an existing dead letter queue, given by name or ARN, instead of creating one.
+// This allows several queues to share a dead letter queue.
+func WithDeadLetterQueue(nameOrArn string) Option {
+	return func(queue *Queue) error {
+		queue.deadLetterQueue = nameOrArn
+		return nil
+	}
+}
+
+// WithFIFO creates a FIFO queue with a FIFO dead letter queue.
+// The .fifo suffix is appended to the queue names when missing.
+func WithFIFO(contentBasedDeduplication bool) Option {
+	return func(queue *Queue) error {
+		queue.fifo = true
+		queue.contentBasedDeduplication = contentBasedDeduplication
+		return nil
+	}
+}
+
+// WithBatchRetries sets how many times SendMessages retries entries failing on the SQS side.
+// It defaults to 2, 0 disables the retries.
+func WithBatchRetries(retries int) Option {
+	return func(queue *Queue) error {
+		if retries < 0 {
+			return ErrInvalidBatchRetries
+		}
+		queue.batchRetries = &retries
+		return nil
+	}
+}
+
+// WithMarshaller sets the marshaller encoding the message bodies.
+func WithMarshaller(marshaller Marshaller) Option {
+	return func(queue *Queue) error {
+		queue.Marshaller = marshaller
+		return nil
+	}
+}
+
+// withHighThroughput enables the high throughput mode of a FIFO queue.
+func withHighThroughput() Option {
+	return func(queue *Queue) error {
+		queue.highThroughput = true
+		return nil
+	}
+}
+
+// WithReceiveVisibilityTimeout sets how long received messages stay invisible to other consumers.
+// It defaults to 10 minutes and applies to every receive, including the Processor.
+func WithReceiveVisibilityTimeout(timeout time.Duration) Option {
+	return func(queue *Queue) error {
+		if timeout < 0 || timeout > maxVisibilityTimeout {
+			return ErrInvalidVisibilityTimeout
+		}
+		queue.visibilityTimeout = &timeout
+		return nil
+	}
+}
+
+// WithReceiveWaitTime sets how long receive calls long poll for messages, 0 short polls and returns immediately.
+// It defaults to 20 seconds and applies to every receive, including the Processor.
+func WithReceiveWaitTime(waitTime time.Duration) Option {
+	return func(queue *Queue) error {
+		if waitTime < 0 || waitTime > defaultWaitTime {
+			return ErrInvalidWaitTime
+		}
+		queue.waitTime = &waitTime
+		return nil
+	}
+}
+
+// WithTags tags the queue and the dead letter queue created for it, e.g. for cost allocation.
+func WithTags(tags map[string]string) Option {
+	return func(queue *Queue) error {
+		queue.tags = tags
+		return nil
+	}
+}
+
+// WithKMSKey encrypts the queue and it's dead letter queue with the KMS key.
+// A zero dataKeyReusePeriod keeps the SQS default of 5 minutes.
+func WithKMSKey(keyID string, dataKeyReusePeriod time.Duration) Option {
+	return func(queue *Queue) error {
+		if dataKeyReusePeriod != 0 && (dataKeyReusePeriod < time.Minute || dataKeyReusePeriod > 24*time.Hour) {
+			return ErrInvalidDataKeyReusePeriod
+		}
+		queue.kmsKeyID = keyID
+		queue.kmsDataKeyReusePeriod = dataKeyReusePeriod
+		return nil
+	}
+}
+
+// WithSSE encrypts the queue and it's dead letter queue with SQS managed keys.
+func WithSSE() Option {
+	return func(queue *Queue) error {
+		queue.sqsManagedSSE = true
+		return nil
+	}
+}
+
+// A SendHook may add message attributes to the messages sent within the context, e.g. the trace context.
+type SendHook func(ctx context.Context, attributes map[string]*sqs.MessageAttributeValue)
+
+// WithSendHook calls the hook for each message sent with SendMessage, it's variants and the batch sends.
+func WithSendHook(hook SendHook) Option {
+	return func(queue *Queue) error {
+		queue.sendHooks = append(queue.sendHooks, hook)
+		return nil
+	}
+}
+
+// WithLogger sends the log entries of the queue to the logger instead of the logrus standard logger.
+func WithLogger(logger Logger) Option {
+	return func(queue *Queue) error {
+		queue.Logger = logger
+		return nil
+	}
+}
+
+// WithReceiveAttributeNames sets the system attributes requested for received messages, e.g. ApproximateReceiveCount.
+// All of them are requested by default.
+func WithReceiveAttributeNames(names ...string) Option {
+	return func(queue *Queue) error {
+		queue.attributeNames = names
+		return nil
+	}
+}
+
+// WithMaxRetries sets how many times the client of the queue retries failed requests.
+func WithMaxRetries(maxRetries int) Option {
+	return func(queue *Queue) error {
+		queue.maxRetries = &maxRetries
+		return nil
+	}
+}
+
+// WithRetryer makes the client of the queue retry failed requests with the retryer.
+func WithRetryer(retryer request.Retryer) Option {
+	return func(queue *Queue) error {
+		queue.retryer = retryer
+		return nil
+	}
+}
+
+// WithHTTPClient makes the client of the queue send it's requests with the HTTP client, e.g. one with timeouts.
+func WithHTTPClient(client *http.Client) Option {
+	return func(queue *Queue) error {
+		queue.httpClient = client
+		return nil
+	}
+}
+
+// WithQueueLongPolling sets the ReceiveMessageWaitTimeSeconds attribute of the created queue and dead letter queue,
+// so every consumer of the queue long polls, not only this package.
+// ErrInvalidWaitTime is returned when seconds is outside the SQS range of 0 to 20.
+func WithQueueLongPolling(seconds int) Option {
+	return func(queue *Queue) error {
+		if seconds < 0 || time.Duration(seconds)*time.Second > defaultWaitTime {
+			return ErrInvalidWaitTime
+		}
+		queue.queueWaitTimeSeconds = &seconds
+		return nil
+	}
+}
+
+// WithQueueVisibilityTimeout sets the VisibilityTimeout attribute of the created queue and dead letter queue,
+// the default of consumers that don't set it on receive.
+// ErrInvalidVisibilityTimeout is returned when the timeout is outside the SQS range of 0 to 12 hours.
+func WithQueueVisibilityTimeout(timeout time.Duration) Option {
+	return func(queue *Queue) error {
+		if timeout < 0 || timeout > maxVisibilityTimeout {
+			return ErrInvalidVisibilityTimeout
+		}
+		queue.queueVisibilityTimeout = &timeout
+		return nil
+	}
+}
+
+// WithDelaySeconds sets the DelaySeconds attribute of the created queue, the dead letter queue is not delayed.
+// ErrInvalidDelaySeconds is returned when the delay is outside the SQS range of 0 to 15 minutes.
+func WithDelaySeconds(delay time.Duration) Option {
+	return func(queue *Queue) error {
+		if delay < 0 || delay > MaxDelaySeconds*time.Second {
+			return ErrInvalidDelaySeconds
+		}
+		queue.delay = &delay
+		return nil
+	}
+}
@@ -0,0 +1,309 @@
+package queue_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/client"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// createdAttributes returns the attributes of the CreateQueue call of the queue name.
+func createdAttributes(t *testing.T, client *fakeClient, name string) map[string]string {
+	t.Helper()
+	for _, input := range client.createQueueInputs {
+		if *input.QueueName == name {
+			return aws.StringValueMap(input.Attributes)
+		}
+	}
+	t.Fatalf("expected the queue %s to be created", name)
+	return nil
+}
+
+func TestDefaultQueueAttributes(t *testing.T) {
+	client := newFakeClient()
+	if _, err := queue.New("defaults", queue.WithClient(client)); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDeadLetter := map[string]string{"MessageRetentionPeriod": "1209600"}
+	if attributes := createdAttributes(t, client, "defaults-deadMessages"); !reflect.DeepEqual(attributes, expectedDeadLetter) {
+		t.Errorf("expected the dead letter queue attributes %v, got %v", expectedDeadLetter, attributes)
+	}
+	expected := map[string]string{
+		"MessageRetentionPeriod": "1209600",
+		"RedrivePolicy":          `{"maxReceiveCount":5,"deadLetterTargetArn":"arn:aws:sqs:us-east-1:000000000000:defaults-deadMessages"}`,
+	}
+	if attributes := createdAttributes(t, client, "defaults"); !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("expected the queue attributes %v, got %v", expected, attributes)
+	}
+}
+
+func TestQueueAttributesOptions(t *testing.T) {
+	client := newFakeClient()
+	_, err := queue.New("configured", queue.WithClient(client),
+		queue.WithRetentionPeriod(24*time.Hour),
+		queue.WithMaxReceiveCount(2),
+		queue.WithDeadLetterSuffix("-dlq"),
+		queue.WithQueueLongPolling(10),
+		queue.WithQueueVisibilityTimeout(5*time.Minute),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDeadLetter := map[string]string{
+		"MessageRetentionPeriod":        "86400",
+		"ReceiveMessageWaitTimeSeconds": "10",
+		"VisibilityTimeout":             "300",
+	}
+	if attributes := createdAttributes(t, client, "configured-dlq"); !reflect.DeepEqual(attributes, expectedDeadLetter) {
+		t.Errorf("expected the dead letter queue attributes %v, got %v", expectedDeadLetter, attributes)
+	}
+	expected := map[string]string{
+		"MessageRetentionPeriod":        "86400",
+		"ReceiveMessageWaitTimeSeconds": "10",
+		"VisibilityTimeout":             "300",
+		"RedrivePolicy":                 `{"maxReceiveCount":2,"deadLetterTargetArn":"arn:aws:sqs:us-east-1:000000000000:configured-dlq"}`,
+	}
+	if attributes := createdAttributes(t, client, "configured"); !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("expected the queue attributes %v, got %v", expected, attributes)
+	}
+}
+
+func TestInvalidQueueAttributesOptions(t *testing.T) {
+	options := map[string]queue.Option{
+		"retention":           queue.WithRetentionPeriod(time.Second),
+		"receive count":       queue.WithMaxReceiveCount(0),
+		"suffix":              queue.WithDeadLetterSuffix(""),
+		"wait time":           queue.WithQueueLongPolling(21),
+		"negative wait":       queue.WithQueueLongPolling(-1),
+		"visibility":          queue.WithQueueVisibilityTimeout(13 * time.Hour),
+		"negative visibility": queue.WithQueueVisibilityTimeout(-time.Second),
+	}
+	for name, option := range options {
+		client := newFakeClient()
+		if _, err := queue.New("invalid", queue.WithClient(client), option); err == nil {
+			t.Errorf("expected the invalid %s to be rejected", name)
+		}
+		if len(client.createQueueInputs) != 0 {
+			t.Errorf("expected no queue to be created with the invalid %s", name)
+		}
+	}
+}
+
+func TestWithoutDeadLetterQueue(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("transient", queue.WithClient(client), queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.createQueueInputs) != 1 {
+		t.Fatalf("expected only the queue to be created, got %d CreateQueue calls", len(client.createQueueInputs))
+	}
+	if _, ok := createdAttributes(t, client, "transient")["RedrivePolicy"]; ok {
+		t.Error("expected no redrive policy")
+	}
+	if q.DeadLetterQueueURL != "" {
+		t.Errorf("expected no dead letter queue URL, got %s", q.DeadLetterQueueURL)
+	}
+
+	stats, err := q.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.DeadLetterApproximateNumberOfMessages != 0 {
+		t.Errorf("expected no dead letter messages, got %+v", stats)
+	}
+	if len(client.getAttributesInputs) != 1 || *client.getAttributesInputs[0].QueueUrl != q.URL {
+		t.Errorf("expected only the attributes of the queue to be fetched, got %v", client.getAttributesInputs)
+	}
+}
+
+func TestWithFIFO(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("orders", queue.WithClient(client), queue.WithFIFO(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Name != "orders.fifo" {
+		t.Errorf("expected the .fifo suffix, got %s", q.Name)
+	}
+
+	for _, name := range []string{"orders-deadMessages.fifo", "orders.fifo"} {
+		attributes := createdAttributes(t, client, name)
+		if attributes["FifoQueue"] != "true" || attributes["ContentBasedDeduplication"] != "true" {
+			t.Errorf("expected %s to be a FIFO queue with content based deduplication, got %v", name, attributes)
+		}
+	}
+
+	if _, err := q.SendMessageFIFO("body", "group", "dedup"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessageFIFO("body", "group", ""); err != nil {
+		t.Fatal(err)
+	}
+	input := client.sendInputs[0]
+	if aws.StringValue(input.MessageGroupId) != "group" || aws.StringValue(input.MessageDeduplicationId) != "dedup" {
+		t.Errorf("expected the group and deduplication IDs, got %v", input)
+	}
+	if input := client.sendInputs[1]; input.MessageDeduplicationId != nil {
+		t.Errorf("expected no deduplication ID, got %v", input)
+	}
+}
+
+func TestWithFIFOWithoutContentBasedDeduplication(t *testing.T) {
+	client := newFakeClient()
+	if _, err := queue.New("orders.fifo", queue.WithClient(client), queue.WithFIFO(false)); err != nil {
+		t.Fatal(err)
+	}
+
+	attributes := createdAttributes(t, client, "orders.fifo")
+	if _, ok := attributes["ContentBasedDeduplication"]; ok || attributes["FifoQueue"] != "true" {
+		t.Errorf("expected a FIFO queue without content based deduplication, got %v", attributes)
+	}
+}
+
+func TestWithKMSKey(t *testing.T) {
+	client := newFakeClient()
+	_, err := queue.New("encrypted", queue.WithClient(client), queue.WithKMSKey("alias/queues", 5*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedDeadLetter := map[string]string{
+		"MessageRetentionPeriod":       "1209600",
+		"KmsMasterKeyId":               "alias/queues",
+		"KmsDataKeyReusePeriodSeconds": "300",
+	}
+	if attributes := createdAttributes(t, client, "encrypted-deadMessages"); !reflect.DeepEqual(attributes, expectedDeadLetter) {
+		t.Errorf("expected the dead letter queue attributes %v, got %v", expectedDeadLetter, attributes)
+	}
+	attributes := createdAttributes(t, client, "encrypted")
+	if attributes["KmsMasterKeyId"] != "alias/queues" || attributes["KmsDataKeyReusePeriodSeconds"] != "300" {
+		t.Errorf("expected the queue to be encrypted with the key, got %v", attributes)
+	}
+}
+
+func TestWithSSE(t *testing.T) {
+	client := newFakeClient()
+	if _, err := queue.New("encrypted", queue.WithClient(client), queue.WithSSE()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"encrypted-deadMessages", "encrypted"} {
+		attributes := createdAttributes(t, client, name)
+		if attributes["SqsManagedSseEnabled"] != "true" {
+			t.Errorf("expected %s to use SQS managed encryption, got %v", name, attributes)
+		}
+		if _, ok := attributes["KmsMasterKeyId"]; ok {
+			t.Errorf("expected no KMS key for %s, got %v", name, attributes)
+		}
+	}
+}
+
+func TestWithKMSKeyInvalidReusePeriod(t *testing.T) {
+	for _, period := range []time.Duration{59 * time.Second, 24*time.Hour + time.Second} {
+		client := newFakeClient()
+		if _, err := queue.New("encrypted", queue.WithClient(client), queue.WithKMSKey("alias/queues", period)); err != queue.ErrInvalidDataKeyReusePeriod {
+			t.Errorf("expected ErrInvalidDataKeyReusePeriod for %s, got %v", period, err)
+		}
+		if len(client.createQueueInputs) != 0 {
+			t.Errorf("expected no queue to be created for %s", period)
+		}
+	}
+	for _, period := range []time.Duration{time.Minute, 24 * time.Hour} {
+		if _, err := queue.New("encrypted", queue.WithClient(newFakeClient()), queue.WithKMSKey("alias/queues", period)); err != nil {
+			t.Errorf("expected the reuse period %s to be accepted, got %v", period, err)
+		}
+	}
+}
+
+// hangingServer creates queues, but never answers the other calls until the client gives up.
+func hangingServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var input struct{ QueueName string }
+		json.NewDecoder(r.Body).Decode(&input)
+		if r.Header.Get("X-Amz-Target") != "AmazonSQS.CreateQueue" {
+			// The body is read, so the closed connection of the client cancels the context.
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		json.NewEncoder(w).Encode(map[string]string{"QueueUrl": "https://sqs.us-east-1.amazonaws.com/000000000000/" + input.QueueName})
+	}))
+}
+
+// newServedQueue returns a queue of the server, with a client built by the package.
+func newServedQueue(t *testing.T, server *httptest.Server, opts ...queue.Option) *sqs.SQS {
+	t.Helper()
+
+	opts = append(opts,
+		queue.WithEndpoint(server.URL),
+		queue.WithCredentials(credentials.NewStaticCredentials("id", "secret", "")),
+		queue.WithoutDeadLetterQueue(),
+	)
+	q, err := queue.New("served", opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return q.GetClient().(*sqs.SQS)
+}
+
+func TestClientOptionsReachConfig(t *testing.T) {
+	server := hangingServer()
+	defer server.Close()
+
+	httpClient := &http.Client{Timeout: time.Second}
+	svc := newServedQueue(t, server, queue.WithMaxRetries(7), queue.WithHTTPClient(httpClient))
+	if aws.IntValue(svc.Config.MaxRetries) != 7 {
+		t.Errorf("expected 7 max retries in the client config, got %v", svc.Config.MaxRetries)
+	}
+	if svc.Config.HTTPClient != httpClient {
+		t.Error("expected the HTTP client in the client config")
+	}
+
+	retryer := client.DefaultRetryer{NumMaxRetries: 4}
+	svc = newServedQueue(t, server, queue.WithRetryer(retryer))
+	if svc.Retryer != retryer {
+		t.Errorf("expected the retryer of the client to be set, got %v", svc.Retryer)
+	}
+}
+
+func TestHTTPClientTimeoutCutsOffSend(t *testing.T) {
+	server := hangingServer()
+	defer server.Close()
+
+	svc := newServedQueue(t, server,
+		queue.WithHTTPClient(&http.Client{Timeout: 100 * time.Millisecond}),
+		queue.WithMaxRetries(0),
+	)
+	q := &queue.Queue{Name: "served", URL: "https://sqs.us-east-1.amazonaws.com/000000000000/served", Client: svc}
+
+	started := time.Now()
+	if _, err := q.SendMessage("hanging"); err == nil {
+		t.Fatal("expected the send to time out")
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the timeout to cut off the send, took %s", elapsed)
+	}
+}
+
+func TestWithBatchRetriesNegative(t *testing.T) {
+	client := newFakeClient()
+	if _, err := queue.New("batch", queue.WithClient(client), queue.WithBatchRetries(-1)); err != queue.ErrInvalidBatchRetries {
+		t.Errorf("expected ErrInvalidBatchRetries, got %v", err)
+	}
+	if len(client.createQueueInputs) != 0 {
+		t.Errorf("expected no queue to be created, got %v", client.createQueueInputs)
+	}
+}
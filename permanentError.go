@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// FailureReasonAttribute is the message attribute holding the error of messages moved to the dead letter queue.
+const FailureReasonAttribute = "X-Failure-Reason"
+
+// permanentError marks a handler error that retrying won't fix.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// PermanentError marks the handler error as permanent, the Processor moves the message straight to the dead letter queue
+// instead of retrying it.
+func PermanentError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return permanentError{err: err}
+}
+
+// IsPermanentError reports whether the error, or one it wraps, was marked with PermanentError.
+func IsPermanentError(err error) bool {
+	var permanent permanentError
+
+	return errors.As(err, &permanent)
+}
+
+// deadLetterMessage sends the message to the dead letter queue with the failure reason, then deletes it from the queue.
+// Without a dead letter queue the message is left to be retried.
+func (processor *Processor) deadLetterMessage(ctx context.Context, message *sqs.Message, reason error) {
+	queue := processor.Queue
+	if queue.DeadLetterQueueURL == "" {
+		processor.getLogger().Warn("Permanent error but the queue has no dead letter queue, the message will be retried", Fields{
+			"queueName": queue.Name,
+			"messageID": message.MessageId,
+			"error":     reason,
+		})
+		return
+	}
+
+	attributes := make(map[string]*sqs.MessageAttributeValue, len(message.MessageAttributes)+1)
+	for name, value := range message.MessageAttributes {
+		attributes[name] = value
+	}
+	attributes[FailureReasonAttribute] = StringAttribute(reason.Error())
+
+	// The body is forwarded as it is, payloads offloaded to S3 are kept for the dead letter queue.
+	deadLetterQueue := queue.getDeadLetterQueue()
+	deadLetterQueue.Logger = processor.getLogger()
+	params := deadLetterQueue.resendInput(message, attributes)
+	if _, err := deadLetterQueue.sendMessageInput(ctx, params); err != nil {
+		processor.getLogger().Error("Sending message to dead letter queue", Fields{
+			"queueName": queue.Name,
+			"messageID": message.MessageId,
+			"error":     err,
+		})
+		return
+	}
+
+	if _, err := queue.deleteMessageByReceiptHandle(ctx, message.ReceiptHandle); err != nil {
+		processor.getLogger().Warn("Message sent to dead letter queue but not deleted from queue", Fields{
+			"queueName": queue.Name,
+			"messageID": message.MessageId,
+			"error":     err,
+		})
+		return
+	}
+
+	processor.getLogger().Info("Message moved to dead letter queue", Fields{
+		"queueName": queue.Name,
+		"messageID": message.MessageId,
+		"reason":    reason,
+	})
+}
@@ -0,0 +1,101 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// failWith returns a handler failing every message with err.
+func failWith(err error) queue.MessageHandlerFunc {
+	return func(ctx context.Context, body interface{}, message *sqs.Message) error {
+		return err
+	}
+}
+
+func TestPermanentErrorDeadLettersMessage(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "permanent", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessageWithAttributes("invalid", map[string]string{"tenant": "acme"})
+
+	processor := &queue.Processor{Queue: q, HandleMessage: failWith(queue.PermanentError(errors.New("unknown message type")))}
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Failed != 1 || len(client.Messages(q.URL)) != 0 {
+		t.Errorf("expected the message to be removed from the queue, got %+v", summary)
+	}
+	deadLetterQueue := &queue.Queue{Name: "permanent-deadMessages", URL: q.DeadLetterQueueURL, Client: client}
+	message, err := deadLetterQueue.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message == nil || *message.Body != `"invalid"` {
+		t.Fatalf("expected the message in the dead letter queue, got %v", message)
+	}
+	if reason, _ := queue.GetStringAttribute(message, queue.FailureReasonAttribute); reason != "unknown message type" {
+		t.Errorf("expected the failure reason, got %q", reason)
+	}
+	if tenant, _ := queue.GetStringAttribute(message, "tenant"); tenant != "acme" {
+		t.Errorf("expected the attributes of the message to be kept, got %q", tenant)
+	}
+}
+
+func TestRetryableErrorKeepsMessage(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "retryable", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	processor := &queue.Processor{Queue: q, HandleMessage: failWith(errors.New("downstream unavailable"))}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := client.Messages(q.URL); len(remaining) != 1 {
+		t.Errorf("expected the message to be retried, got %v", remaining)
+	}
+	if dead := client.Messages(q.DeadLetterQueueURL); len(dead) != 0 {
+		t.Errorf("expected nothing in the dead letter queue, got %v", dead)
+	}
+}
+
+func TestPermanentErrorWithoutDeadLetterQueue(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "permanent", queue.WithReceiveWaitTime(0), queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	processor := &queue.Processor{Queue: q, HandleMessage: failWith(queue.PermanentError(errors.New("invalid")))}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining := client.Messages(q.URL); len(remaining) != 1 {
+		t.Errorf("expected the message to be left in the queue, got %v", remaining)
+	}
+}
+
+func TestIsPermanentError(t *testing.T) {
+	err := queue.PermanentError(errors.New("invalid"))
+	if !queue.IsPermanentError(err) || !queue.IsPermanentError(fmt.Errorf("wrapped: %w", err)) {
+		t.Error("expected the permanent error to be recognized")
+	}
+	if queue.IsPermanentError(errors.New("retryable")) || queue.PermanentError(nil) != nil {
+		t.Error("expected only marked errors to be permanent")
+	}
+}
@@ -0,0 +1,77 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ErrAccessDenied is returned by Ping when the credentials are invalid or not allowed to access the queue.
+var ErrAccessDenied = errors.New("access to the queue denied")
+
+// ErrQueueUnreachable is returned by Ping when SQS could not be reached in time.
+var ErrQueueUnreachable = errors.New("queue unreachable")
+
+// Error codes of invalid or unauthorized credentials.
+var accessDeniedErrorCodes = map[string]bool{
+	"AccessDenied":                true,
+	"AccessDeniedException":       true,
+	"InvalidClientTokenId":        true,
+	"SignatureDoesNotMatch":       true,
+	"ExpiredToken":                true,
+	"UnrecognizedClientException": true,
+	"InvalidSecurity":             true,
+}
+
+// pingError wraps the AWS error of a failed Ping with the sentinel error of it's cause.
+type pingError struct {
+	cause error
+	err   error
+}
+
+// Error returns the cause and the AWS error.
+func (err *pingError) Error() string {
+	return err.cause.Error() + ": " + err.err.Error()
+}
+
+// Is makes errors.Is match the sentinel error of the cause.
+func (err *pingError) Is(target error) bool {
+	return target == err.cause
+}
+
+// Unwrap returns the AWS error.
+func (err *pingError) Unwrap() error {
+	return err.err
+}
+
+// Ping checks that the queue is reachable with the credentials of the client, by requesting only it's ARN.
+// Failures wrap ErrQueueNotFound, ErrAccessDenied or ErrQueueUnreachable, other errors are returned as they are.
+func (queue *Queue) Ping(ctx context.Context) error {
+	if queue.URL == "" {
+		return ErrQueueNotInitialized
+	}
+
+	_, err := queue.GetClient().GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{
+		QueueUrl:       aws.String(queue.URL),
+		AttributeNames: aws.StringSlice([]string{sqs.QueueAttributeNameQueueArn}),
+	})
+	if err == nil {
+		return nil
+	}
+
+	var aerr awserr.Error
+	switch {
+	case isQueueNotFound(err):
+		return &queueNotFoundError{name: queue.Name, err: err}
+	case errors.As(err, &aerr) && accessDeniedErrorCodes[aerr.Code()]:
+		return &pingError{cause: ErrAccessDenied, err: err}
+	case ctx.Err() != nil || request.IsErrorRetryable(err) || (errors.As(err, &aerr) && aerr.Code() == "RequestError"):
+		return &pingError{cause: ErrQueueUnreachable, err: err}
+	default:
+		return err
+	}
+}
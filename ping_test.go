@@ -0,0 +1,80 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// pingClient fails the attribute requests with err once it is set.
+type pingClient struct {
+	*fakeClient
+	err error
+}
+
+func (client *pingClient) GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	if client.err != nil {
+		return nil, client.err
+	}
+	return client.fakeClient.GetQueueAttributesWithContext(ctx, input, opts...)
+}
+
+func TestPing(t *testing.T) {
+	client := &pingClient{fakeClient: newFakeClient()}
+	q, err := queue.New("pinged", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Ping(context.Background()); err != nil {
+		t.Fatalf("expected a reachable queue, got %v", err)
+	}
+	input := client.getAttributesInputs[len(client.getAttributesInputs)-1]
+	if len(input.AttributeNames) != 1 || *input.AttributeNames[0] != sqs.QueueAttributeNameQueueArn {
+		t.Errorf("expected only the ARN to be requested, got %v", input.AttributeNames)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		ctx      context.Context
+		err      error
+		sentinel error
+	}{
+		{context.Background(), awserr.New(sqs.ErrCodeQueueDoesNotExist, "The specified queue does not exist.", nil), queue.ErrQueueNotFound},
+		{context.Background(), awserr.New("InvalidClientTokenId", "The security token included in the request is invalid.", nil), queue.ErrAccessDenied},
+		{context.Background(), awserr.New(request.ErrCodeRequestError, "send request failed", errors.New("connection refused")), queue.ErrQueueUnreachable},
+		{cancelled, awserr.New(request.CanceledErrorCode, "request context canceled", context.Canceled), queue.ErrQueueUnreachable},
+	}
+	for _, test := range tests {
+		client.err = test.err
+		err := q.Ping(test.ctx)
+
+		if !errors.Is(err, test.sentinel) {
+			t.Errorf("expected %v for %v, got %v", test.sentinel, test.err, err)
+		}
+		var aerr awserr.Error
+		if !errors.As(err, &aerr) || aerr != test.err {
+			t.Errorf("expected the AWS error %v to be kept, got %v", test.err, err)
+		}
+	}
+
+	client.err = awserr.New(sqs.ErrCodeInvalidAttributeName, "The specified attribute doesn't exist.", nil)
+	if err := q.Ping(context.Background()); err != client.err {
+		t.Errorf("expected other errors to be returned as they are, got %v", err)
+	}
+}
+
+func TestPingNotInitialized(t *testing.T) {
+	q := &queue.Queue{Name: "uninitialized"}
+
+	if err := q.Ping(context.Background()); err != queue.ErrQueueNotInitialized {
+		t.Errorf("expected ErrQueueNotInitialized, got %v", err)
+	}
+}
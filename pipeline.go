@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ErrNoDestination is returned when HandleAndForward is set without a ForwardTo queue.
+var ErrNoDestination = errors.New("processor has no destination queue to forward to")
+
+// handleAndForward passes the body to HandleAndForward and sends it's result to the ForwardTo queue.
+// The message is only deleted after the result was sent, a nil result is not forwarded.
+func (processor *Processor) handleAndForward(ctx context.Context, body interface{}, message *sqs.Message) error {
+	outBody, err := processor.HandleAndForward(ctx, body, message)
+	if err != nil || outBody == nil {
+		return err
+	}
+	if processor.ForwardTo == nil {
+		return ErrNoDestination
+	}
+
+	_, err = processor.ForwardTo.SendMessageContext(ctx, outBody)
+
+	return err
+}
@@ -0,0 +1,75 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestHandleAndForward(t *testing.T) {
+	tests := []struct {
+		name      string
+		outBody   interface{}
+		err       error
+		summary   queue.ProcessSummary
+		remaining int
+		forwarded []string
+		// deleted makes the sends fail by deleting the destination queue.
+		deleted       bool
+		noDestination bool
+	}{
+		{name: "forwarded", outBody: "result", summary: queue.ProcessSummary{Processed: 1}, forwarded: []string{`"result"`}},
+		{name: "nil result", summary: queue.ProcessSummary{Processed: 1}},
+		{name: "handler failure", err: errors.New("failed"), summary: queue.ProcessSummary{Failed: 1}, remaining: 1},
+		{name: "send failure", outBody: "result", summary: queue.ProcessSummary{Failed: 1}, remaining: 1, deleted: true},
+		{name: "no destination", outBody: "result", summary: queue.ProcessSummary{Failed: 1}, remaining: 1, noDestination: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := memqueue.NewClient()
+			source, err := memqueue.NewWithClient(client, "source", queue.WithReceiveWaitTime(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			destination, err := memqueue.NewWithClient(client, "destination", queue.WithReceiveWaitTime(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.deleted {
+				if _, err := client.DeleteQueueWithContext(context.Background(), &sqs.DeleteQueueInput{QueueUrl: aws.String(destination.URL)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			source.SendMessage("input")
+
+			processor := &queue.Processor{
+				Queue: source,
+				HandleAndForward: func(ctx context.Context, body interface{}, message *sqs.Message) (interface{}, error) {
+					return test.outBody, test.err
+				},
+			}
+			if !test.noDestination {
+				processor.ForwardTo = destination
+			}
+			summary, err := processor.ProcessN(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if summary != test.summary {
+				t.Errorf("expected %+v, got %+v", test.summary, summary)
+			}
+			if remaining := client.Messages(source.URL); len(remaining) != test.remaining {
+				t.Errorf("expected %d messages to stay in the source queue, got %v", test.remaining, remaining)
+			}
+			if forwarded := client.Messages(destination.URL); len(forwarded) != len(test.forwarded) || (len(forwarded) > 0 && forwarded[0] != test.forwarded[0]) {
+				t.Errorf("expected %v in the destination queue, got %v", test.forwarded, forwarded)
+			}
+		})
+	}
+}
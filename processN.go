@@ -0,0 +1,143 @@
+package queue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Default number of consecutive empty receives after which Drain considers the queue empty.
+const defaultDrainEmptyReceives = 1
+
+// ProcessSummary counts the messages handled by ProcessN and Drain.
+type ProcessSummary struct {
+	Processed int
+	Failed    int
+	// Skipped counts the duplicates deleted without handling them, they don't count towards the n of ProcessN.
+	Skipped int
+}
+
+// WithDrainEmptyReceives sets how many consecutive empty receives Drain waits for before returning, 1 by default.
+// SQS may return an empty receive while there are messages left, so a higher count tolerates that.
+func (processor *Processor) WithDrainEmptyReceives(emptyReceives int) *Processor {
+	processor.drainEmptyReceives = emptyReceives
+
+	return processor
+}
+
+// ProcessN handles messages like Process until n messages were handled, successfully or not, then it returns.
+// With HandleBatch and a batch window or HandleDecodedBatch the messages are handled in batches of up to the remaining ones.
+// ErrConflictingHandlers is returned before receiving when both HandleWithAck and HandleMessage are set.
+// The messages are decoded into values returned by NewBody, or maps without it.
+// An error is returned when receiving fails or the context is done.
+func (processor *Processor) ProcessN(ctx context.Context, n int) (summary ProcessSummary, err error) {
+	return processor.processUntil(ctx, n, 0)
+}
+
+// Drain handles messages like Process until the queue is empty, then it returns, also in batches like ProcessN.
+// The messages are decoded into values returned by NewBody, or maps without it.
+// An error is returned when receiving fails or the context is done.
+func (processor *Processor) Drain(ctx context.Context) (summary ProcessSummary, err error) {
+	emptyReceives := processor.drainEmptyReceives
+	if emptyReceives < 1 {
+		emptyReceives = defaultDrainEmptyReceives
+	}
+
+	return processor.processUntil(ctx, 0, emptyReceives)
+}
+
+// processUntil handles messages until max messages were handled or the given number of consecutive receives was empty.
+// A zero max or emptyReceives is no limit.
+func (processor *Processor) processUntil(ctx context.Context, max int, emptyReceives int) (summary ProcessSummary, err error) {
+	if err = processor.checkHandlers(); err != nil {
+		return
+	}
+	var processed, failed, skipped atomic.Int64
+	defer func() {
+		summary = ProcessSummary{Processed: int(processed.Load()), Failed: int(failed.Load()), Skipped: int(skipped.Load())}
+	}()
+
+	workers := make(chan struct{}, processor.getConcurrency())
+	empty := 0
+	for {
+		handled := int(processed.Load() + failed.Load())
+		if max > 0 && handled >= max {
+			return
+		}
+		if emptyReceives > 0 && empty >= emptyReceives {
+			return
+		}
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		processor.waitForHealthyDependencies(ctx)
+
+		batchWindow := processor.HandleBatch != nil && processor.batchMaxMessages > 0
+		receiveSize := MaxBatchSize
+		if batchWindow {
+			receiveSize = processor.batchMaxMessages
+		}
+		if max > 0 && max-handled < receiveSize {
+			receiveSize = max - handled
+		}
+		if receiveSize = processor.waitForRate(ctx, receiveSize); receiveSize == 0 {
+			continue
+		}
+		var messages []*sqs.Message
+		var receiveErr error
+		if batchWindow {
+			messages, receiveErr = processor.collectBatch(ctx, receiveSize, processor.Queue.getWaitTimeSeconds())
+		} else {
+			messages, receiveErr = processor.Queue.ReceiveMessagesContext(ctx, int64(receiveSize))
+		}
+		processor.recordReceive(receiveErr)
+		if receiveErr != nil {
+			processor.getMetrics().ReceiveError(receiveErr)
+			return summary, receiveErr
+		}
+		if len(messages) == 0 {
+			empty++
+			continue
+		}
+		empty = 0
+		if err = processor.chargeRate(ctx, len(messages)); err != nil {
+			return
+		}
+
+		if batchWindow || processor.HandleDecodedBatch != nil {
+			var batchProcessed, batchFailed int
+			if batchWindow {
+				batchProcessed, batchFailed = processor.handleBatchWindow(ctx, messages)
+			} else {
+				batchProcessed, batchFailed = processor.handleDecodedBatch(ctx, messages, nil)
+			}
+			processed.Add(int64(batchProcessed))
+			failed.Add(int64(batchFailed))
+			continue
+		}
+
+		var inFlight sync.WaitGroup
+		for _, message := range messages {
+			processor.recordReceived(message)
+			workers <- struct{}{}
+			inFlight.Add(1)
+			go func(message *sqs.Message) {
+				defer inFlight.Done()
+				defer func() { <-workers }()
+
+				switch processor.processMessage(ctx, message, nil) {
+				case processHandled:
+					processed.Add(1)
+				case processSkipped:
+					skipped.Add(1)
+				default:
+					failed.Add(1)
+				}
+			}(message)
+		}
+		inFlight.Wait()
+	}
+}
@@ -0,0 +1,166 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestProcessNReturnsAfterN(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "limited", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		q.SendMessage(i)
+	}
+
+	handled := 0
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled++
+			return nil
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary != (queue.ProcessSummary{Processed: 3}) || handled != 3 {
+		t.Errorf("expected 3 processed messages, got %+v and %d handled", summary, handled)
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 2 {
+		t.Errorf("expected 2 messages to stay in the queue, got %d", remaining)
+	}
+}
+
+func TestDrainReturnsOnEmptyReceive(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "drained", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("ok")
+	q.SendMessage("fail")
+	q.SendMessage("ok")
+
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			if body == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	summary, err := processor.Drain(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary != (queue.ProcessSummary{Processed: 2, Failed: 1}) {
+		t.Errorf("expected 2 processed and 1 failed message, got %+v", summary)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 1 || remaining[0] != `"fail"` {
+		t.Errorf("expected only the failed message to stay in the queue, got %v", remaining)
+	}
+}
+
+func TestProcessNReturnsReceiveError(t *testing.T) {
+	client := &scheduledReceiveClient{fakeClient: newFakeClient(), failures: []bool{true, true}, stop: func() {}}
+	q, err := queue.New("failing", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err == nil || err.Error() != "receive failed" {
+		t.Errorf("expected the receive error from ProcessN, got %v", err)
+	}
+	if _, err := processor.Drain(context.Background()); err == nil {
+		t.Error("expected the receive error from Drain")
+	}
+}
+
+func TestProcessNDecodedBatches(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "batched", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		q.SendMessage(i)
+	}
+
+	var batches []int
+	processor := &queue.Processor{
+		Queue: q,
+		HandleDecodedBatch: func(ctx context.Context, messages []queue.DecodedMessage) (failed []queue.Failed, err error) {
+			batches = append(batches, len(messages))
+			return []queue.Failed{{Message: messages[0].Message, Err: errors.New("failed")}}, nil
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 1 || batches[0] != 3 {
+		t.Errorf("expected one batch of 3 messages, got %v", batches)
+	}
+	if summary != (queue.ProcessSummary{Processed: 2, Failed: 1}) {
+		t.Errorf("expected 2 processed and 1 failed message, got %+v", summary)
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 3 {
+		t.Errorf("expected the failed and the unreceived messages to stay in the queue, got %d", remaining)
+	}
+}
+
+func TestDrainBatchWindows(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "windowed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		q.SendMessage(i)
+	}
+
+	var batches []int
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleBatch: func(ctx context.Context, processor *queue.Processor, messages []*sqs.Message) error {
+			batches = append(batches, len(messages))
+			return nil
+		},
+	}).WithBatchWindow(3, 10*time.Millisecond)
+	summary, err := processor.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batches) != 2 || batches[0] != 3 || batches[1] != 2 {
+		t.Errorf("expected batches of 3 and 2 messages, got %v", batches)
+	}
+	if summary != (queue.ProcessSummary{Processed: 5}) {
+		t.Errorf("expected 5 processed messages, got %+v", summary)
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 0 {
+		t.Errorf("expected the batches to be deleted, got %d messages", remaining)
+	}
+}
@@ -0,0 +1,79 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Time to wait after a failed receive in the ProcessorChain.
+const chainReceiveErrorPause = time.Second
+
+// A ProcessorChain pipes the messages of one queue into another queue through a transform function.
+type ProcessorChain struct {
+	Source      *Queue
+	Destination *Queue
+	Transform   func(ctx context.Context, message *sqs.Message) (interface{}, error)
+}
+
+// NewProcessorChain returns a ProcessorChain from src to dst.
+func NewProcessorChain(src, dst *Queue, transform func(ctx context.Context, message *sqs.Message) (interface{}, error)) *ProcessorChain {
+	return &ProcessorChain{
+		Source:      src,
+		Destination: dst,
+		Transform:   transform,
+	}
+}
+
+// Run receives the messages of the source queue, sends the transformed messages to the destination queue
+// and deletes them from the source queue only after a successful send.
+// It runs until the context is cancelled and returns the context error.
+func (chain *ProcessorChain) Run(ctx context.Context) error {
+	queueDetails := Fields{
+		"sourceQueueName":      chain.Source.Name,
+		"destinationQueueName": chain.Destination.Name,
+	}
+
+	chain.Source.GetLogger().Info("Processor chain started", queueDetails)
+	for ctx.Err() == nil {
+		messages, err := chain.Source.receiveMessages(ctx, MaxBatchSize, chain.Source.getWaitTimeSeconds())
+		if err != nil {
+			aws.SleepWithContext(ctx, chainReceiveErrorPause)
+			continue
+		}
+
+		for _, message := range messages {
+			chain.forward(ctx, message)
+		}
+	}
+	chain.Source.GetLogger().Info("Processor chain stopped", queueDetails)
+
+	return ctx.Err()
+}
+
+// forward transforms and sends one message, then deletes it from the source queue.
+func (chain *ProcessorChain) forward(ctx context.Context, message *sqs.Message) {
+	details := Fields{
+		"sourceQueueName":      chain.Source.Name,
+		"destinationQueueName": chain.Destination.Name,
+		"messageID":            message.MessageId,
+	}
+
+	out, err := chain.Transform(ctx, message)
+	if err != nil {
+		details["error"] = err
+		chain.Source.GetLogger().Warn("Error transforming message", details)
+		return
+	}
+	if _, err = chain.Destination.SendMessageContext(ctx, out); err != nil {
+		details["error"] = err
+		chain.Source.GetLogger().Warn("Error forwarding message", details)
+		return
+	}
+	if _, err = chain.Source.DeleteMessageContext(ctx, message); err != nil {
+		details["error"] = err
+		chain.Source.GetLogger().Warn("Error deleting forwarded message", details)
+	}
+}
@@ -0,0 +1,81 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// runChain runs the chain of a message from a source to a destination queue until the transform returned.
+func runChain(t *testing.T, transform func(ctx context.Context, message *sqs.Message) (interface{}, error)) (client *memqueue.Client, chain *queue.ProcessorChain) {
+	t.Helper()
+
+	client = memqueue.NewClient()
+	source, err := memqueue.NewWithClient(client, "source", queue.WithReceiveWaitTime(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	destination, err := memqueue.NewWithClient(client, "destination")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source.SendMessage("hello")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	transformed := make(chan struct{})
+	chain = queue.NewProcessorChain(source, destination, func(ctx context.Context, message *sqs.Message) (interface{}, error) {
+		defer close(transformed)
+		return transform(ctx, message)
+	})
+	stopped := make(chan error)
+	go func() {
+		stopped <- chain.Run(ctx)
+	}()
+
+	select {
+	case <-transformed:
+	case <-time.After(time.Second):
+		t.Fatal("expected the message to be transformed")
+	}
+	// The chain finishes forwarding the message before it receives again.
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	if err := <-stopped; err != context.Canceled {
+		t.Errorf("expected the chain to stop with the context, got %v", err)
+	}
+
+	return client, chain
+}
+
+func TestProcessorChain(t *testing.T) {
+	client, chain := runChain(t, func(ctx context.Context, message *sqs.Message) (interface{}, error) {
+		return strings.ToUpper(aws.StringValue(message.Body)), nil
+	})
+
+	if forwarded := client.Messages(chain.Destination.URL); len(forwarded) != 1 || forwarded[0] != `"\"HELLO\""` {
+		t.Errorf("expected the transformed message in the destination queue, got %v", forwarded)
+	}
+	if remaining := client.Messages(chain.Source.URL); len(remaining) != 0 {
+		t.Errorf("expected the message to be deleted from the source queue, got %v", remaining)
+	}
+}
+
+func TestProcessorChainTransformError(t *testing.T) {
+	client, chain := runChain(t, func(ctx context.Context, message *sqs.Message) (interface{}, error) {
+		return nil, errors.New("invalid message")
+	})
+
+	if forwarded := client.Messages(chain.Destination.URL); len(forwarded) != 0 {
+		t.Errorf("expected nothing to be forwarded, got %v", forwarded)
+	}
+	if remaining := client.Messages(chain.Source.URL); len(remaining) != 1 {
+		t.Errorf("expected the message to stay in the source queue, got %v", remaining)
+	}
+}
@@ -0,0 +1,216 @@
+// Package prometheus exports the metrics of queue Processors to Prometheus.
+//
+//	collector := prometheus.NewCollector(q)
+//	registry.MustRegister(collector)
+//	go collector.RefreshDepth(ctx, time.Minute)
+//
+//	processor := queue.Processor{
+//		Queue:             q,
+//		HandleMessageBody: handleMessageBody,
+//		Metrics:           collector,
+//	}
+package prometheus
+
+import (
+	"context"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	prom "github.com/prometheus/client_golang/prometheus"
+)
+
+// A Collector implements queue.Metrics with Prometheus metrics labelled with the queue name.
+// It is a prometheus.Collector, register it to export its metrics.
+type Collector struct {
+	queue *queue.Queue
+
+	received      prom.Counter
+	processed     prom.Counter
+	failed        prom.Counter
+	deleted       prom.Counter
+	receiveErrors prom.Counter
+	duplicates    prom.Counter
+	misses        prom.Counter
+	archiveErrors prom.Counter
+	duration      prom.Histogram
+	lag           prom.Gauge
+	depth         prom.Gauge
+}
+
+// NewCollector returns a Collector for the processors of the queue.
+func NewCollector(q *queue.Queue) *Collector {
+	labels := prom.Labels{"queue": q.Name}
+
+	return &Collector{
+		queue: q,
+		received: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_messages_received_total",
+			Help:        "Number of messages received from the queue.",
+			ConstLabels: labels,
+		}),
+		processed: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_messages_processed_total",
+			Help:        "Number of messages handled successfully.",
+			ConstLabels: labels,
+		}),
+		failed: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_messages_failed_total",
+			Help:        "Number of messages that could not be decoded or handled.",
+			ConstLabels: labels,
+		}),
+		deleted: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_messages_deleted_total",
+			Help:        "Number of messages deleted after handling.",
+			ConstLabels: labels,
+		}),
+		receiveErrors: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_receive_errors_total",
+			Help:        "Number of failed receives.",
+			ConstLabels: labels,
+		}),
+		duplicates: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_duplicates_skipped_total",
+			Help:        "Number of messages skipped as duplicates of handled ones.",
+			ConstLabels: labels,
+		}),
+		misses: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_deduplication_misses_total",
+			Help:        "Number of messages not handled before within the deduplication window.",
+			ConstLabels: labels,
+		}),
+		archiveErrors: prom.NewCounter(prom.CounterOpts{
+			Name:        "sqs_archive_errors_total",
+			Help:        "Number of handled messages that could not be archived.",
+			ConstLabels: labels,
+		}),
+		duration: prom.NewHistogram(prom.HistogramOpts{
+			Name:        "sqs_handler_duration_seconds",
+			Help:        "Duration of handling the messages.",
+			ConstLabels: labels,
+			Buckets:     prom.DefBuckets,
+		}),
+		lag: prom.NewGauge(prom.GaugeOpts{
+			Name:        "sqs_message_lag_seconds",
+			Help:        "Age of the last received message.",
+			ConstLabels: labels,
+		}),
+		depth: prom.NewGauge(prom.GaugeOpts{
+			Name:        "sqs_queue_depth",
+			Help:        "Approximate number of messages available in the queue.",
+			ConstLabels: labels,
+		}),
+	}
+}
+
+// Describe sends the descriptors of the metrics.
+func (collector *Collector) Describe(descs chan<- *prom.Desc) {
+	for _, metric := range collector.metrics() {
+		metric.Describe(descs)
+	}
+}
+
+// Collect sends the current values of the metrics.
+func (collector *Collector) Collect(metrics chan<- prom.Metric) {
+	for _, metric := range collector.metrics() {
+		metric.Collect(metrics)
+	}
+}
+
+// metrics returns all metrics of the collector.
+func (collector *Collector) metrics() []prom.Collector {
+	return []prom.Collector{
+		collector.received,
+		collector.processed,
+		collector.failed,
+		collector.deleted,
+		collector.receiveErrors,
+		collector.duplicates,
+		collector.misses,
+		collector.archiveErrors,
+		collector.duration,
+		collector.lag,
+		collector.depth,
+	}
+}
+
+// RefreshDepth updates the queue depth gauge every interval until the context is done.
+func (collector *Collector) RefreshDepth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		collector.refreshDepth(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshDepth updates the queue depth gauge from the queue attributes, fetched within the context.
+func (collector *Collector) refreshDepth(ctx context.Context) {
+	stats, err := collector.queue.StatsContext(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		collector.queue.GetLogger().Warn("Refreshing the queue depth metric", queue.Fields{
+			"queueName": collector.queue.Name,
+			"error":     err,
+		})
+		return
+	}
+
+	collector.depth.Set(float64(stats.ApproximateNumberOfMessages))
+}
+
+// MessageReceived counts a received message.
+func (collector *Collector) MessageReceived() {
+	collector.received.Inc()
+}
+
+// MessageLag sets the age of the last received message.
+func (collector *Collector) MessageLag(lag time.Duration) {
+	collector.lag.Set(lag.Seconds())
+}
+
+// MessageProcessed counts a handled message and observes its duration.
+func (collector *Collector) MessageProcessed(duration time.Duration) {
+	collector.processed.Inc()
+	collector.duration.Observe(duration.Seconds())
+}
+
+// MessageFailed counts a failed message.
+func (collector *Collector) MessageFailed(err error) {
+	collector.failed.Inc()
+}
+
+// MessageDeleted counts a deleted message.
+func (collector *Collector) MessageDeleted() {
+	collector.deleted.Inc()
+}
+
+// ReceiveError counts a failed receive.
+func (collector *Collector) ReceiveError(err error) {
+	collector.receiveErrors.Inc()
+}
+
+// DuplicateSkipped counts a skipped duplicate.
+func (collector *Collector) DuplicateSkipped() {
+	collector.duplicates.Inc()
+}
+
+// DeduplicationMiss counts a message that is not a duplicate.
+func (collector *Collector) DeduplicationMiss() {
+	collector.misses.Inc()
+}
+
+// ArchiveFailed counts a message that could not be archived.
+func (collector *Collector) ArchiveFailed(err error) {
+	collector.archiveErrors.Inc()
+}
+
+var _ queue.Metrics = (*Collector)(nil)
+var _ queue.DeduplicationMetrics = (*Collector)(nil)
+var _ queue.ArchiveMetrics = (*Collector)(nil)
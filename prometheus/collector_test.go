@@ -0,0 +1,98 @@
+package prometheus_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/Indivizo/sqs/prometheus"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollectorCountsProcessedMessages(t *testing.T) {
+	q, err := memqueue.New("collected", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{"ok", "ok", "fail"})
+
+	collector := prometheus.NewCollector(q)
+	processor := &queue.Processor{
+		Queue:   q,
+		Metrics: collector,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			if body == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 3); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `
+# HELP sqs_messages_failed_total Number of messages that could not be decoded or handled.
+# TYPE sqs_messages_failed_total counter
+sqs_messages_failed_total{queue="collected"} 1
+# HELP sqs_messages_processed_total Number of messages handled successfully.
+# TYPE sqs_messages_processed_total counter
+sqs_messages_processed_total{queue="collected"} 2
+# HELP sqs_messages_received_total Number of messages received from the queue.
+# TYPE sqs_messages_received_total counter
+sqs_messages_received_total{queue="collected"} 3
+# HELP sqs_receive_errors_total Number of failed receives.
+# TYPE sqs_receive_errors_total counter
+sqs_receive_errors_total{queue="collected"} 0
+`
+	names := []string{"sqs_messages_failed_total", "sqs_messages_processed_total", "sqs_messages_received_total", "sqs_receive_errors_total"}
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+	registry := prom.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	if count, err := testutil.GatherAndCount(registry, "sqs_handler_duration_seconds"); err != nil || count != 1 {
+		t.Errorf("expected the handler duration histogram, got %d %v", count, err)
+	}
+}
+
+func TestCollectorRefreshesDepth(t *testing.T) {
+	q, err := memqueue.New("collected", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessages([]interface{}{1, 2, 3})
+
+	collector := prometheus.NewCollector(q)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		collector.RefreshDepth(ctx, time.Hour)
+	}()
+
+	expected := `
+# HELP sqs_queue_depth Approximate number of messages available in the queue.
+# TYPE sqs_queue_depth gauge
+sqs_queue_depth{queue="collected"} 3
+`
+	// The depth is refreshed right away, then every interval.
+	deadline := time.Now().Add(time.Second)
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sqs_queue_depth")
+	for err != nil && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+		err = testutil.CollectAndCompare(collector, strings.NewReader(expected), "sqs_queue_depth")
+	}
+	cancel()
+	<-done
+
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,116 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// SQS allows one purge per queue in this period.
+const purgeCoolDown = 60 * time.Second
+
+// ErrPurgeInProgress is returned when the queue was purged within the last 60 seconds.
+// The returned error is a *PurgeInProgressError holding the remaining cool-down.
+var ErrPurgeInProgress = errors.New("queue purge already in progress")
+
+// A PurgeInProgressError is returned when SQS rejects a purge because of an earlier one.
+type PurgeInProgressError struct {
+	// RetryAfter is the remaining cool-down, estimated from the last purge by this Queue if there was one.
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error returns the remaining cool-down and the AWS error.
+func (err *PurgeInProgressError) Error() string {
+	return ErrPurgeInProgress.Error() + ", retry after " + err.RetryAfter.String() + ": " + err.Err.Error()
+}
+
+// Is makes errors.Is(err, ErrPurgeInProgress) true.
+func (err *PurgeInProgressError) Is(target error) bool {
+	return target == ErrPurgeInProgress
+}
+
+// Unwrap returns the AWS error.
+func (err *PurgeInProgressError) Unwrap() error {
+	return err.Err
+}
+
+// Purge deletes every message of the queue.
+func (queue *Queue) Purge() error {
+	return queue.PurgeContext(aws.BackgroundContext())
+}
+
+// PurgeContext deletes every message of the queue within the context.
+// ErrQueueNotInitialized is returned for queues without a URL.
+func (queue *Queue) PurgeContext(ctx context.Context) error {
+	if queue.URL == "" {
+		return ErrQueueNotInitialized
+	}
+
+	return queue.purgeByQueueURL(ctx, queue.URL)
+}
+
+// PurgeDeadLetterQueue deletes every message of the dead letter queue.
+// ErrNoDeadLetterQueue is returned for queues without a dead letter queue.
+func (queue *Queue) PurgeDeadLetterQueue() error {
+	if queue.DeadLetterQueueURL == "" {
+		return ErrNoDeadLetterQueue
+	}
+
+	return queue.purgeByQueueURL(aws.BackgroundContext(), queue.DeadLetterQueueURL)
+}
+
+// purgeByQueueURL purges the queue by it's URL within the context.
+func (queue *Queue) purgeByQueueURL(ctx context.Context, url string) (err error) {
+	client := queue.GetClient()
+	params := &sqs.PurgeQueueInput{
+		QueueUrl: aws.String(url),
+	}
+	_, err = client.PurgeQueueWithContext(ctx, params)
+
+	if err != nil {
+		queue.GetLogger().Error("Purging queue", Fields{
+			"queueName": queue.Name,
+			"queueUrl":  url,
+			"error":     err,
+		})
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == sqs.ErrCodePurgeQueueInProgress {
+			return &PurgeInProgressError{RetryAfter: queue.purgeRetryAfter(url), Err: err}
+		}
+		return
+	}
+
+	queue.purgeMutex.Lock()
+	if queue.purgedAt == nil {
+		queue.purgedAt = map[string]time.Time{}
+	}
+	queue.purgedAt[url] = time.Now()
+	queue.purgeMutex.Unlock()
+
+	queue.GetLogger().Info("Queue purged", Fields{
+		"queueName": queue.Name,
+		"queueUrl":  url,
+	})
+
+	return
+}
+
+// purgeRetryAfter returns the remaining cool-down of the last purge of the queue by this Queue.
+func (queue *Queue) purgeRetryAfter(url string) time.Duration {
+	queue.purgeMutex.Lock()
+	defer queue.purgeMutex.Unlock()
+
+	purgedAt, ok := queue.purgedAt[url]
+	if !ok {
+		return purgeCoolDown
+	}
+	if remaining := purgeCoolDown - time.Since(purgedAt); remaining > 0 {
+		return remaining
+	}
+
+	return 0
+}
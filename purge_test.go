@@ -0,0 +1,107 @@
+package queue_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// purgingClient allows one purge per queue URL, like the SQS cool-down.
+type purgingClient struct {
+	*fakeClient
+	purged []string
+}
+
+func (client *purgingClient) PurgeQueueWithContext(ctx aws.Context, input *sqs.PurgeQueueInput, opts ...request.Option) (*sqs.PurgeQueueOutput, error) {
+	for _, url := range client.purged {
+		if url == *input.QueueUrl {
+			return nil, awserr.New(sqs.ErrCodePurgeQueueInProgress, "Only one PurgeQueue operation is allowed every 60 seconds.", nil)
+		}
+	}
+	client.purged = append(client.purged, *input.QueueUrl)
+	return &sqs.PurgeQueueOutput{}, nil
+}
+
+func TestPurge(t *testing.T) {
+	client := &purgingClient{fakeClient: newFakeClient()}
+	q, err := queue.New("purged", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Purge(); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.PurgeDeadLetterQueue(); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.purged) != 2 || client.purged[0] != q.URL || client.purged[1] != q.DeadLetterQueueURL {
+		t.Errorf("expected the queue and the dead letter queue to be purged, got %v", client.purged)
+	}
+}
+
+func TestPurgeInProgress(t *testing.T) {
+	client := &purgingClient{fakeClient: newFakeClient()}
+	q, err := queue.New("purged", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Purge(); err != nil {
+		t.Fatal(err)
+	}
+
+	err = q.Purge()
+	var inProgress *queue.PurgeInProgressError
+	if !errors.As(err, &inProgress) || !errors.Is(err, queue.ErrPurgeInProgress) {
+		t.Fatalf("expected a *PurgeInProgressError, got %v", err)
+	}
+	if inProgress.RetryAfter <= 0 || inProgress.RetryAfter > time.Minute {
+		t.Errorf("expected the remaining cool-down of the last purge, got %s", inProgress.RetryAfter)
+	}
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) || aerr.Code() != sqs.ErrCodePurgeQueueInProgress {
+		t.Errorf("expected the AWS error to be wrapped, got %v", err)
+	}
+
+	// Without an earlier purge by this Queue the full cool-down is reported.
+	other, err := queue.New("purged", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Purge(); !errors.As(err, &inProgress) || inProgress.RetryAfter != time.Minute {
+		t.Errorf("expected the full cool-down, got %v", err)
+	}
+}
+
+func TestPurgeDeadLetterQueueWithoutOne(t *testing.T) {
+	client := &purgingClient{fakeClient: newFakeClient()}
+	q, err := queue.New("purged", queue.WithClient(client), queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.PurgeDeadLetterQueue(); err != queue.ErrNoDeadLetterQueue {
+		t.Errorf("expected ErrNoDeadLetterQueue, got %v", err)
+	}
+	if len(client.purged) != 0 {
+		t.Errorf("expected no purge, got %v", client.purged)
+	}
+}
+
+func TestPurgeWithoutURL(t *testing.T) {
+	client := &purgingClient{fakeClient: newFakeClient()}
+	q := &queue.Queue{Name: "purged", Client: client}
+
+	if err := q.Purge(); err != queue.ErrQueueNotInitialized {
+		t.Errorf("expected ErrQueueNotInitialized, got %v", err)
+	}
+	if len(client.purged) != 0 {
+		t.Errorf("expected no purge, got %v", client.purged)
+	}
+}
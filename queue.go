@@ -1,12 +1,22 @@
 package queue
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/sqs"
-	log "github.com/sirupsen/logrus"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
 )
 
 // Frankfurt region.
@@ -15,14 +25,122 @@ const sqsRegion = "eu-central-1"
 // Default suffix for dead letter queue.
 const deadLetterQueueSuffix = "-deadMessages"
 
+// Name suffix required for FIFO queues.
+const fifoSuffix = ".fifo"
+
+// FIFO high throughput attributes, missing from the SDK.
+const (
+	queueAttributeNameDeduplicationScope  = "DeduplicationScope"
+	queueAttributeNameFifoThroughputLimit = "FifoThroughputLimit"
+	deduplicationScopeMessageGroup        = "messageGroup"
+	fifoThroughputLimitPerMessageGroupID  = "perMessageGroupId"
+	deduplicationScopeQueue               = "queue"
+	fifoThroughputLimitPerQueue           = "perQueue"
+)
+
+// Default visibility timeout of received messages.
+const defaultVisibilityTimeout = 600 * time.Second
+
+// Default long poll wait time of receive calls.
+const defaultWaitTime = 20 * time.Second
+
+// Attribute enabling SQS managed server-side encryption, missing from the SDK.
+const queueAttributeNameSqsManagedSseEnabled = "SqsManagedSseEnabled"
+
+// Default message retention period, the SQS maximum of 14 days.
+const defaultRetentionPeriod = 14 * 24 * time.Hour
+
 // MaxReceiveCountBeforeDead is the receive count before a message is sent to a dead letter queue.
 const MaxReceiveCountBeforeDead = 5
 
+// ErrFIFOSuffixRequired is returned for a FIFO queue name not ending with .fifo.
+var ErrFIFOSuffixRequired = errors.New("FIFO queue name must end with .fifo")
+
+// ErrInvalidMaxMessages is returned when the number of messages to receive is not between 1 and 10.
+var ErrInvalidMaxMessages = errors.New("max number of messages must be between 1 and 10")
+
+// ErrQueueNotInitialized is returned for operations on a queue without URL, e.g. before Init or after Delete.
+var ErrQueueNotInitialized = errors.New("queue URL is empty, the queue is not initialized or was deleted")
+
+// ErrInvalidQueueURL is returned when the queue URL does not have the expected SQS format.
+var ErrInvalidQueueURL = errors.New("invalid queue URL")
+
 // A Queue represents an SQS queue.
 type Queue struct {
 	Name               string
 	URL                string
 	DeadLetterQueueURL string
+
+	// Region of the queue, it defaults to Frankfurt.
+	Region string
+
+	// Endpoint overrides the SQS endpoint, e.g. http://localhost:4566 for localstack.
+	Endpoint string
+
+	// Marshaller encodes the message bodies, it defaults to JSON.
+	Marshaller Marshaller
+
+	// Client is used for every SQS call. It is created on first use when not set.
+	Client      sqsiface.SQSAPI
+	clientMutex sync.Mutex
+
+	retentionPeriod  time.Duration
+	maxReceiveCount  int
+	deadLetterSuffix string
+
+	withoutDeadLetterQueue bool
+	deadLetterQueue        string
+
+	fifo                      bool
+	contentBasedDeduplication bool
+	highThroughput            bool
+
+	tags map[string]string
+
+	kmsKeyID              string
+	kmsDataKeyReusePeriod time.Duration
+	sqsManagedSSE         bool
+
+	batchRetries *int
+
+	visibilityTimeout *time.Duration
+	waitTime          *time.Duration
+	attributeNames    []string
+
+	queueWaitTimeSeconds   *int
+	queueVisibilityTimeout *time.Duration
+	delay                  *time.Duration
+	largePayloads          *LargePayloadConfig
+
+	sendHooks  []SendHook
+	validator  Validator
+	encryption *payloadEncryption
+
+	traceHeader     func(ctx context.Context) string
+	deduplicationID bool
+
+	credentials           *credentials.Credentials
+	profile               string
+	assumeRoleArn         string
+	assumeRoleSessionName string
+
+	maxRetries *int
+	retryer    request.Retryer
+	httpClient *http.Client
+
+	// Logger receives the log entries of the queue, it defaults to the logrus standard logger.
+	Logger Logger
+
+	urlRegion      string
+	urlRegionMutex sync.Mutex
+
+	purgedAt   map[string]time.Time
+	purgeMutex sync.Mutex
+
+	replies               *replyListener
+	replyMutex            sync.Mutex
+	allowedReplyQueues    []string
+	replyQueueIdleTimeout *time.Duration
 }
 
 // A RedrivePolicy is an sqs policy of a dead letter queue.
@@ -31,9 +149,25 @@ type RedrivePolicy struct {
 	DeadLetterTargetArn string `json:"deadLetterTargetArn"`
 }
 
+// NewFIFOWithHighThroughput returns a prepared FIFO queue in high throughput mode.
+// The name must end with .fifo, otherwise ErrFIFOSuffixRequired is returned.
+func NewFIFOWithHighThroughput(name string, contentBasedDeduplication bool, opts ...Option) (*Queue, error) {
+	if !strings.HasSuffix(name, fifoSuffix) {
+		return nil, ErrFIFOSuffixRequired
+	}
+
+	opts = append(opts, WithFIFO(contentBasedDeduplication), withHighThroughput())
+	return New(name, opts...)
+}
+
 // New returns a prepared SQS queue.
-func New(name string) (*Queue, error) {
+func New(name string, opts ...Option) (*Queue, error) {
 	queue := Queue{Name: name}
+	for _, opt := range opts {
+		if err := opt(&queue); err != nil {
+			return &queue, err
+		}
+	}
 	err := queue.Init()
 
 	return &queue, err
@@ -41,194 +175,715 @@ func New(name string) (*Queue, error) {
 
 // Init will create the actual queue and set a Client with a live session to it.
 func (queue *Queue) Init() (err error) {
+	return queue.InitContext(aws.BackgroundContext())
+}
+
+// InitContext will create the actual queue within the context.
+// Names SQS would reject return an ErrInvalidQueueName before calling the API.
+func (queue *Queue) InitContext(ctx context.Context) (err error) {
+	if err = queue.prepareName(); err != nil {
+		return
+	}
+
+	var redrivePolicyString *string
+	switch {
+	case queue.withoutDeadLetterQueue:
+	case queue.deadLetterQueue != "":
+		redrivePolicyString, err = queue.attachDeadLetterQueue(ctx, queue.deadLetterQueue)
+	default:
+		redrivePolicyString, err = queue.initDeadLetterQueue(ctx)
+	}
+	if err != nil {
+		return
+	}
+
+	client := queue.GetClient()
+	params := &sqs.CreateQueueInput{
+		QueueName:  aws.String(queue.Name),
+		Attributes: queue.getQueueAttributes(redrivePolicyString),
+	}
+	resp, err := client.CreateQueueWithContext(ctx, params)
+	if err != nil {
+		queue.GetLogger().Error("Createing the queue", Fields{
+			"queueName": queue.Name,
+			"error":     err,
+		})
+		return
+	}
+
+	queue.URL = *resp.QueueUrl
+	queue.GetLogger().Info("Queue initialized", Fields{
+		"QueueUrl": queue.URL,
+	})
+
+	return queue.applyTags(ctx, queue.URL)
+}
+
+// initDeadLetterQueue creates the dead letter queue and returns the redrive policy pointing to it.
+func (queue *Queue) initDeadLetterQueue(ctx context.Context) (redrivePolicyString *string, err error) {
 	client := queue.GetClient()
 
 	params := &sqs.CreateQueueInput{
-		QueueName: aws.String(queue.Name + deadLetterQueueSuffix),
-		Attributes: map[string]*string{
-			"MessageRetentionPeriod": aws.String("1209600"),
-		},
+		QueueName:  aws.String(queue.getDeadLetterQueueName()),
+		Attributes: queue.getDeadLetterQueueAttributes(),
 	}
-	resp, err := client.CreateQueue(params)
+	resp, err := client.CreateQueueWithContext(ctx, params)
 	if err != nil {
-		log.WithFields(log.Fields{
+		queue.GetLogger().Error("Createing the dead letter queue", Fields{
 			"queueName": queue.Name,
 			"error":     err,
-		}).Error("Createing the dead letter queue")
+		})
 		return
 	}
 
 	queue.DeadLetterQueueURL = *resp.QueueUrl
-	log.WithFields(log.Fields{
+	queue.GetLogger().Info("Dead Letter Queue initialized", Fields{
 		"QueueUrl": queue.DeadLetterQueueURL,
-	}).Info("Dead Letter Queue initialized")
+	})
 
-	queueArnAttributeName := "QueueArn"
-	deadLetterQueueAttributes, err := queue.GetAttributesByQueueURL(queue.DeadLetterQueueURL, []*string{&queueArnAttributeName})
-	if err != nil {
+	if err = queue.applyTags(ctx, queue.DeadLetterQueueURL); err != nil {
 		return
 	}
-	redrivePolicy := &RedrivePolicy{
-		MaxReceiveCount:     MaxReceiveCountBeforeDead,
-		DeadLetterTargetArn: *deadLetterQueueAttributes.Attributes[queueArnAttributeName],
+
+	return queue.getDeadLetterRedrivePolicy(ctx, "")
+}
+
+// attachDeadLetterQueue resolves an existing dead letter queue by name or ARN and returns the redrive policy pointing to it.
+// Nothing is created, an error is returned when the dead letter queue does not exist.
+func (queue *Queue) attachDeadLetterQueue(ctx context.Context, nameOrArn string) (redrivePolicyString *string, err error) {
+	name, accountID, deadLetterQueueArn := nameOrArn, "", ""
+	if strings.HasPrefix(nameOrArn, "arn:") {
+		if accountID, name, err = parseQueueArn(nameOrArn); err != nil {
+			return nil, err
+		}
+		deadLetterQueueArn = nameOrArn
 	}
-	redrivePolicyString, err := redrivePolicy.GetAsAWSString()
+
+	deadLetterQueueURL, err := getQueueURL(ctx, queue.GetClient(), name, accountID)
 	if err != nil {
+		queue.GetLogger().Error("Resolving the dead letter queue", Fields{
+			"queueName":       queue.Name,
+			"deadLetterQueue": nameOrArn,
+			"error":           err,
+		})
 		return
 	}
-	params = &sqs.CreateQueueInput{
-		QueueName: aws.String(queue.Name),
-		Attributes: map[string]*string{
-			"RedrivePolicy":          redrivePolicyString,
-			"MessageRetentionPeriod": aws.String("1209600"),
-		},
+
+	queue.DeadLetterQueueURL = deadLetterQueueURL
+	queue.GetLogger().Info("Dead Letter Queue attached", Fields{
+		"QueueUrl": queue.DeadLetterQueueURL,
+	})
+
+	return queue.getDeadLetterRedrivePolicy(ctx, deadLetterQueueArn)
+}
+
+// parseQueueArn returns the account ID and the name of a queue from it's ARN.
+// The ARN has the form arn:aws:sqs:{region}:{account-id}:{name}.
+func parseQueueArn(arn string) (accountID string, name string, err error) {
+	arnParts := strings.Split(arn, ":")
+	if len(arnParts) != 6 || arnParts[0] != "arn" {
+		return "", "", fmt.Errorf("invalid queue ARN %q", arn)
+	}
+
+	return arnParts[4], arnParts[5], nil
+}
+
+// getDeadLetterRedrivePolicy returns the redrive policy pointing to the dead letter queue.
+// The ARN of the dead letter queue is fetched when it is not known.
+func (queue *Queue) getDeadLetterRedrivePolicy(ctx context.Context, deadLetterQueueArn string) (redrivePolicyString *string, err error) {
+	if deadLetterQueueArn == "" {
+		queueArnAttributeName := "QueueArn"
+		deadLetterQueueAttributes, err := queue.getAttributesByQueueURL(ctx, queue.DeadLetterQueueURL, []*string{&queueArnAttributeName})
+		if err != nil {
+			return nil, err
+		}
+		deadLetterQueueArn = *deadLetterQueueAttributes.Attributes[queueArnAttributeName]
 	}
-	resp, err = client.CreateQueue(params)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"queueName": queue.Name,
-			"error":     err,
-		}).Error("Createing the queue")
+
+	redrivePolicy := &RedrivePolicy{
+		MaxReceiveCount:     queue.getMaxReceiveCount(),
+		DeadLetterTargetArn: deadLetterQueueArn,
+	}
+
+	return redrivePolicy.GetAsAWSString()
+}
+
+// getVisibilityTimeoutSeconds returns the visibility timeout of received messages in seconds.
+func (queue *Queue) getVisibilityTimeoutSeconds() int64 {
+	if queue.visibilityTimeout == nil {
+		return int64(defaultVisibilityTimeout / time.Second)
+	}
+
+	return int64(*queue.visibilityTimeout / time.Second)
+}
+
+// getWaitTimeSeconds returns the long poll wait time of receive calls in seconds, 0 means short polling.
+func (queue *Queue) getWaitTimeSeconds() int64 {
+	if queue.waitTime == nil {
+		return int64(defaultWaitTime / time.Second)
+	}
+
+	return int64(*queue.waitTime / time.Second)
+}
+
+// allAttributeNames requests every attribute on receive, shared by all the receive calls.
+var allAttributeNames = []*string{aws.String(sqs.QueueAttributeNameAll)}
+
+// getReceiveAttributeNames returns the system attributes requested for received messages, all of them by default.
+// AWSTraceHeader is always requested, for linking the traces of the handlers to the producers.
+func (queue *Queue) getReceiveAttributeNames() []*string {
+	if len(queue.attributeNames) == 0 {
+		return allAttributeNames
+	}
+
+	return aws.StringSlice(withTraceHeaderAttribute(queue.attributeNames))
+}
+
+// getRegion returns the region of the queue.
+func (queue *Queue) getRegion() string {
+	if queue.Region == "" {
+		return sqsRegion
+	}
+
+	return queue.Region
+}
+
+// getDeadLetterQueueName returns the name of the dead letter queue created for the queue.
+// FIFO dead letter queues keep the .fifo suffix at the end of the name.
+func (queue *Queue) getDeadLetterQueueName() string {
+	if queue.fifo {
+		return strings.TrimSuffix(queue.Name, fifoSuffix) + queue.getDeadLetterSuffix() + fifoSuffix
+	}
+
+	return queue.Name + queue.getDeadLetterSuffix()
+}
+
+// getDeadLetterQueueAttributes returns the attributes for creating the dead letter queue.
+func (queue *Queue) getDeadLetterQueueAttributes() map[string]*string {
+	attributes := map[string]*string{
+		"MessageRetentionPeriod": queue.getRetentionPeriodString(),
+	}
+	queue.setFIFOAttributes(attributes)
+	queue.setEncryptionAttributes(attributes)
+	queue.setReceiveAttributes(attributes)
+
+	return attributes
+}
+
+// setFIFOAttributes adds the FIFO attributes for creating a FIFO queue.
+func (queue *Queue) setFIFOAttributes(attributes map[string]*string) {
+	if !queue.fifo {
 		return
 	}
 
-	queue.URL = *resp.QueueUrl
-	log.WithFields(log.Fields{
-		"QueueUrl": queue.URL,
-	}).Info("Queue initialized")
+	attributes[sqs.QueueAttributeNameFifoQueue] = aws.String("true")
+	if queue.contentBasedDeduplication {
+		attributes[sqs.QueueAttributeNameContentBasedDeduplication] = aws.String("true")
+	}
+	if queue.highThroughput {
+		attributes[queueAttributeNameDeduplicationScope] = aws.String(deduplicationScopeMessageGroup)
+		attributes[queueAttributeNameFifoThroughputLimit] = aws.String(fifoThroughputLimitPerMessageGroupID)
+	}
+}
 
-	return
+// getQueueAttributes returns the attributes for creating the queue with the given redrive policy.
+// The RedrivePolicy attribute is left out when there is no policy.
+func (queue *Queue) getQueueAttributes(redrivePolicy *string) map[string]*string {
+	attributes := map[string]*string{
+		"MessageRetentionPeriod": queue.getRetentionPeriodString(),
+	}
+	if redrivePolicy != nil {
+		attributes["RedrivePolicy"] = redrivePolicy
+	}
+	queue.setFIFOAttributes(attributes)
+	queue.setEncryptionAttributes(attributes)
+	queue.setReceiveAttributes(attributes)
+	if queue.delay != nil {
+		attributes[sqs.QueueAttributeNameDelaySeconds] = aws.String(strconv.FormatInt(int64(*queue.delay/time.Second), 10))
+	}
+
+	return attributes
+}
+
+// setReceiveAttributes adds the long polling and visibility timeout attributes, for the queue and it's dead letter queue.
+func (queue *Queue) setReceiveAttributes(attributes map[string]*string) {
+	if queue.queueWaitTimeSeconds != nil {
+		attributes[sqs.QueueAttributeNameReceiveMessageWaitTimeSeconds] = aws.String(strconv.Itoa(*queue.queueWaitTimeSeconds))
+	}
+	if queue.queueVisibilityTimeout != nil {
+		attributes[sqs.QueueAttributeNameVisibilityTimeout] = aws.String(strconv.FormatInt(int64(*queue.queueVisibilityTimeout/time.Second), 10))
+	}
+}
+
+// setEncryptionAttributes adds the server-side encryption attributes for creating an encrypted queue.
+func (queue *Queue) setEncryptionAttributes(attributes map[string]*string) {
+	switch {
+	case queue.kmsKeyID != "":
+		attributes[sqs.QueueAttributeNameKmsMasterKeyId] = aws.String(queue.kmsKeyID)
+		if queue.kmsDataKeyReusePeriod > 0 {
+			attributes[sqs.QueueAttributeNameKmsDataKeyReusePeriodSeconds] = aws.String(strconv.FormatInt(int64(queue.kmsDataKeyReusePeriod/time.Second), 10))
+		}
+	case queue.sqsManagedSSE:
+		attributes[queueAttributeNameSqsManagedSseEnabled] = aws.String("true")
+	}
+}
+
+// getRetentionPeriodString returns the message retention period in seconds as an sqs attribute.
+func (queue *Queue) getRetentionPeriodString() *string {
+	retentionPeriod := queue.retentionPeriod
+	if retentionPeriod == 0 {
+		retentionPeriod = defaultRetentionPeriod
+	}
+
+	return aws.String(strconv.FormatInt(int64(retentionPeriod/time.Second), 10))
+}
+
+// getMaxReceiveCount returns the receive count before a message is sent to the dead letter queue.
+func (queue *Queue) getMaxReceiveCount() int {
+	if queue.maxReceiveCount == 0 {
+		return MaxReceiveCountBeforeDead
+	}
+
+	return queue.maxReceiveCount
+}
+
+// getDeadLetterSuffix returns the name suffix of the dead letter queue.
+func (queue *Queue) getDeadLetterSuffix() string {
+	if queue.deadLetterSuffix == "" {
+		return deadLetterQueueSuffix
+	}
+
+	return queue.deadLetterSuffix
+}
+
+// SetClient sets the SQS client used by the queue, e.g. a fake in tests.
+func (queue *Queue) SetClient(client sqsiface.SQSAPI) {
+	queue.clientMutex.Lock()
+	defer queue.clientMutex.Unlock()
+
+	queue.Client = client
 }
 
 // GetClient returns an SQS client with a live session.
-func (queue *Queue) GetClient() *sqs.SQS {
+// The client is created once and reused for the lifetime of the queue, it is safe for concurrent use.
+func (queue *Queue) GetClient() sqsiface.SQSAPI {
+	queue.clientMutex.Lock()
+	defer queue.clientMutex.Unlock()
+
+	if queue.Client == nil {
+		queue.Client = queue.newClient()
+	}
+
+	return queue.Client
+}
+
+// getConfig returns the AWS config for the client of the queue.
+func (queue *Queue) getConfig() *aws.Config {
 	config := &aws.Config{
-		Region: aws.String(sqsRegion),
+		Region:      aws.String(queue.getRegion()),
+		Credentials: queue.credentials,
+	}
+	if queue.Endpoint != "" {
+		config.Endpoint = aws.String(queue.Endpoint)
+		config.DisableSSL = aws.Bool(strings.HasPrefix(queue.Endpoint, "http://"))
+	}
+	if queue.maxRetries != nil {
+		config.MaxRetries = aws.Int(*queue.maxRetries)
 	}
-	return sqs.New(session.New(config))
+	if queue.httpClient != nil {
+		config.HTTPClient = queue.httpClient
+	}
+	if queue.retryer != nil {
+		config = request.WithRetryer(config, queue.retryer)
+	}
+
+	return config
 }
 
 // SendMessage will send message to the queue with the file path.
 func (queue *Queue) SendMessage(messageBody interface{}) (resp *sqs.SendMessageOutput, err error) {
-	msg, err := json.Marshal(messageBody)
+	return queue.SendMessageContext(aws.BackgroundContext(), messageBody)
+}
+
+// SendMessageContext will send message to the queue within the context.
+func (queue *Queue) SendMessageContext(ctx context.Context, messageBody interface{}) (resp *sqs.SendMessageOutput, err error) {
+	msg, err := queue.marshalMessageBody(messageBody)
 	if err != nil {
-		log.WithFields(log.Fields{
+		return
+	}
+	params := &sqs.SendMessageInput{
+		MessageBody: aws.String(msg),
+		QueueUrl:    aws.String(queue.URL),
+	}
+
+	return queue.sendMessageInput(ctx, params)
+}
+
+// SendMessageDelayed will send message to the queue, visible only after the delay.
+// ErrInvalidDelaySeconds is returned when the delay is negative or over the SQS maximum of 15 minutes,
+// so callers can fall back to their own scheduling.
+func (queue *Queue) SendMessageDelayed(messageBody interface{}, delay time.Duration) (resp *sqs.SendMessageOutput, err error) {
+	if delay < 0 || delay > MaxDelaySeconds*time.Second {
+		return nil, ErrInvalidDelaySeconds
+	}
+
+	msg, err := queue.marshalMessageBody(messageBody)
+	if err != nil {
+		return
+	}
+	params := &sqs.SendMessageInput{
+		MessageBody:  aws.String(msg),
+		QueueUrl:     aws.String(queue.URL),
+		DelaySeconds: aws.Int64(int64(delay / time.Second)),
+	}
+
+	return queue.sendMessageInput(aws.BackgroundContext(), params)
+}
+
+// SendMessageFIFO will send message to a FIFO queue within the message group.
+// The deduplicationID may be empty when the queue uses content based deduplication.
+func (queue *Queue) SendMessageFIFO(messageBody interface{}, groupID string, deduplicationID string) (resp *sqs.SendMessageOutput, err error) {
+	msg, err := queue.marshalMessageBody(messageBody)
+	if err != nil {
+		return
+	}
+	params := &sqs.SendMessageInput{
+		MessageBody:    aws.String(msg),
+		QueueUrl:       aws.String(queue.URL),
+		MessageGroupId: aws.String(groupID),
+	}
+	if deduplicationID != "" {
+		params.MessageDeduplicationId = aws.String(deduplicationID)
+	}
+
+	return queue.sendMessageInput(aws.BackgroundContext(), params)
+}
+
+// SendRawMessage will send the body to the queue as it is, without marshaling.
+// A *MessageTooLargeError is returned for bodies over MaxMessageSize.
+func (queue *Queue) SendRawMessage(body string) (resp *sqs.SendMessageOutput, err error) {
+	return queue.sendRawMessage(aws.BackgroundContext(), body, nil)
+}
+
+// SendRawMessageBytes will send the body to the queue as it is, without marshaling.
+// A *MessageTooLargeError is returned for bodies over MaxMessageSize.
+func (queue *Queue) SendRawMessageBytes(body []byte) (resp *sqs.SendMessageOutput, err error) {
+	return queue.sendRawMessage(aws.BackgroundContext(), string(body), nil)
+}
+
+// sendRawMessage sends the body as it is, with the message attributes.
+func (queue *Queue) sendRawMessage(ctx context.Context, body string, attributes map[string]*sqs.MessageAttributeValue) (resp *sqs.SendMessageOutput, err error) {
+	params := &sqs.SendMessageInput{
+		MessageBody: aws.String(body),
+		QueueUrl:    aws.String(queue.URL),
+	}
+	if len(attributes) > 0 {
+		params.MessageAttributes = attributes
+	}
+
+	return queue.sendMessageInput(ctx, params)
+}
+
+// marshalMessageBody returns the message body encoded for the queue.
+func (queue *Queue) marshalMessageBody(messageBody interface{}) (msg string, err error) {
+	if queue.validator != nil {
+		if err = queue.validator.ValidateOutgoing(messageBody); err != nil {
+			return
+		}
+	}
+	msg, err = queue.getMarshaller().Marshal(messageBody)
+	if err != nil {
+		queue.GetLogger().Error("Marshal the message body for the queue", Fields{
 			"queueName":   queue.Name,
 			"error":       err,
 			"messageBody": messageBody,
-		}).Error("Marshal the message body for the queue")
+		})
+	}
+
+	return
+}
+
+// sendMessageInput prepares the input and sends it to the queue.
+// Messages over MaxMessageSize, counting the message attributes, are rejected without calling SQS.
+func (queue *Queue) sendMessageInput(ctx context.Context, params *sqs.SendMessageInput) (resp *sqs.SendMessageOutput, err error) {
+	if queue.URL == "" {
+		return nil, ErrQueueNotInitialized
+	}
+	if err = queue.prepareMessageInput(ctx, params); err != nil {
 		return
 	}
+
+	return queue.sendPreparedMessageInput(ctx, params)
+}
+
+// sendPreparedMessageInput sends the input prepared by prepareMessageInput.
+func (queue *Queue) sendPreparedMessageInput(ctx context.Context, params *sqs.SendMessageInput) (resp *sqs.SendMessageOutput, err error) {
 	client := queue.GetClient()
-	params := &sqs.SendMessageInput{
-		MessageBody: aws.String(string(msg)),
-		QueueUrl:    aws.String(queue.URL),
-	}
-	resp, err = client.SendMessage(params)
+	resp, err = client.SendMessageWithContext(ctx, params)
 
 	if err != nil {
-		log.WithFields(log.Fields{
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		queue.GetLogger().Error("Sending message to queue", Fields{
 			"queueName": queue.Name,
 			"error":     err,
-		}).Error("Sending message to queue")
+		})
 		return
 	}
 
-	log.WithFields(log.Fields{
+	queue.GetLogger().Info("Message sent to the queue", Fields{
 		"messageID": resp.MessageId,
-	}).Info("Message sent to the queue")
+	})
+
+	return
+}
+
+// prepareMessageInput runs the send hooks and adds the trace header to the input, encrypts and offloads the body
+// as configured, then adds the deduplication ID of the body as sent. It is shared by single and batch sends,
+// a *MessageTooLargeError is returned when the prepared message is over MaxMessageSize.
+func (queue *Queue) prepareMessageInput(ctx context.Context, params *sqs.SendMessageInput) (err error) {
+	if len(queue.sendHooks) > 0 {
+		if params.MessageAttributes == nil {
+			params.MessageAttributes = make(map[string]*sqs.MessageAttributeValue)
+		}
+		for _, hook := range queue.sendHooks {
+			hook(ctx, params.MessageAttributes)
+		}
+	}
+	queue.setTraceHeader(ctx, params)
+	if err = queue.encryptPayload(params); err != nil {
+		return
+	}
+	if err = queue.offloadLargePayload(ctx, params); err != nil {
+		return
+	}
+	queue.setDeduplicationID(params)
+	if size := messageSize(aws.StringValue(params.MessageBody), params.MessageAttributes); size > MaxMessageSize {
+		err = &MessageTooLargeError{Size: size}
+		queue.GetLogger().Error("Sending message to queue", Fields{
+			"queueName": queue.Name,
+			"error":     err,
+		})
+	}
 
 	return
 }
 
 // ReceiveMessage will return one message and it's body from the queue.
 func (queue *Queue) ReceiveMessage() (message *sqs.Message, err error) {
+	return queue.ReceiveMessageContext(aws.BackgroundContext())
+}
+
+// ReceiveMessageContext will return one message from the queue within the context.
+// The long poll returns promptly with the context error when the context is cancelled.
+func (queue *Queue) ReceiveMessageContext(ctx context.Context) (message *sqs.Message, err error) {
+	messages, err := queue.ReceiveMessagesContext(ctx, 1)
+	if err != nil || len(messages) < 1 {
+		return
+	}
+
+	message = messages[0]
+
+	return
+}
+
+// ReceiveMessages will return up to max messages from the queue with a single receive call.
+// ErrInvalidMaxMessages is returned when max is not between 1 and 10.
+func (queue *Queue) ReceiveMessages(max int64) (messages []*sqs.Message, err error) {
+	return queue.ReceiveMessagesContext(aws.BackgroundContext(), max)
+}
+
+// ReceiveMessagesContext will return up to max messages from the queue within the context.
+func (queue *Queue) ReceiveMessagesContext(ctx context.Context, max int64) (messages []*sqs.Message, err error) {
+	if max < 1 || max > MaxBatchSize {
+		return nil, ErrInvalidMaxMessages
+	}
+
+	return queue.receiveMessages(ctx, max, queue.getWaitTimeSeconds())
+}
+
+// receiveMessages returns up to maxMessages messages from the queue, long polling for waitTimeSeconds.
+func (queue *Queue) receiveMessages(ctx context.Context, maxMessages int64, waitTimeSeconds int64) (messages []*sqs.Message, err error) {
+	if queue.URL == "" {
+		return nil, ErrQueueNotInitialized
+	}
 	client := queue.GetClient()
 	params := &sqs.ReceiveMessageInput{
-		QueueUrl:            aws.String(queue.URL),
-		MaxNumberOfMessages: aws.Int64(1),
-		VisibilityTimeout:   aws.Int64(600),
-		WaitTimeSeconds:     aws.Int64(20),
+		QueueUrl:                    aws.String(queue.URL),
+		MaxNumberOfMessages:         aws.Int64(maxMessages),
+		VisibilityTimeout:           aws.Int64(queue.getVisibilityTimeoutSeconds()),
+		WaitTimeSeconds:             aws.Int64(waitTimeSeconds),
+		MessageSystemAttributeNames: queue.getReceiveAttributeNames(),
+		MessageAttributeNames:       allAttributeNames,
 	}
 
-	resp, err := client.ReceiveMessage(params)
+	resp, err := client.ReceiveMessageWithContext(ctx, params)
 	if err != nil {
-		log.WithFields(log.Fields{
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		queue.GetLogger().Error("Receiving message from queue", Fields{
 			"queueName": queue.Name,
 			"error":     err,
-		}).Error("Receiving message from queue")
-		return
-	}
-
-	if len(resp.Messages) < 1 {
+		})
 		return
 	}
 
-	message = resp.Messages[0]
+	messages = resp.Messages
 
 	return
 }
 
 // DeleteMessage removes a message from the Queue.
 func (queue *Queue) DeleteMessage(message *sqs.Message) (resp *sqs.DeleteMessageOutput, err error) {
-	resp, err = queue.DeleteMessageByReceiptHandle(message.ReceiptHandle)
+	return queue.DeleteMessageContext(aws.BackgroundContext(), message)
+}
+
+// DeleteMessageContext removes a message from the Queue within the context.
+func (queue *Queue) DeleteMessageContext(ctx context.Context, message *sqs.Message) (resp *sqs.DeleteMessageOutput, err error) {
+	resp, err = queue.deleteMessageByReceiptHandle(ctx, message.ReceiptHandle)
 	if err != nil {
-		log.WithFields(log.Fields{
+		queue.GetLogger().Error("Deleting message from queue", Fields{
 			"queueName": queue.Name,
 			"messageID": message.MessageId,
 			"error":     err,
-		}).Error("Deleting message from queue")
+		})
 		return
 	}
 
-	log.WithFields(log.Fields{
+	queue.GetLogger().Info("Message deleted from queue", Fields{
 		"queueName": queue.Name,
 		"messageID": message.MessageId,
-	}).Info("Message deleted from queue")
+	})
+	queue.deleteLargePayload(ctx, message)
 
 	return
 }
 
 // DeleteMessageByReceiptHandle removes a message from the Queue by it's receiptHandle.
 func (queue *Queue) DeleteMessageByReceiptHandle(receiptHandle *string) (resp *sqs.DeleteMessageOutput, err error) {
+	return queue.deleteMessageByReceiptHandle(aws.BackgroundContext(), receiptHandle)
+}
+
+// deleteMessageByReceiptHandle removes a message from the Queue by it's receiptHandle within the context.
+func (queue *Queue) deleteMessageByReceiptHandle(ctx context.Context, receiptHandle *string) (resp *sqs.DeleteMessageOutput, err error) {
+	if queue.URL == "" {
+		return nil, ErrQueueNotInitialized
+	}
 	client := queue.GetClient()
 	params := &sqs.DeleteMessageInput{
 		QueueUrl:      aws.String(queue.URL),
 		ReceiptHandle: aws.String(*receiptHandle),
 	}
-	resp, err = client.DeleteMessage(params)
+	resp, err = client.DeleteMessageWithContext(ctx, params)
 
 	return
 }
 
 // GetAttributesByQueueURL returns queue attributes by it's URL.
 func (queue *Queue) GetAttributesByQueueURL(url string, attributeNames []*string) (resp *sqs.GetQueueAttributesOutput, err error) {
+	return queue.getAttributesByQueueURL(aws.BackgroundContext(), url, attributeNames)
+}
+
+// getAttributesByQueueURL returns queue attributes by it's URL within the context.
+func (queue *Queue) getAttributesByQueueURL(ctx context.Context, url string, attributeNames []*string) (resp *sqs.GetQueueAttributesOutput, err error) {
 	client := queue.GetClient()
 	params := &sqs.GetQueueAttributesInput{
 		QueueUrl:       aws.String(url),
 		AttributeNames: attributeNames,
 	}
-	resp, err = client.GetQueueAttributes(params)
+	resp, err = client.GetQueueAttributesWithContext(ctx, params)
 
 	if err != nil {
-		log.WithFields(log.Fields{
+		queue.GetLogger().Error("Getting queue attributes", Fields{
 			"queueName": queue.Name,
 			"queueUrl":  url,
 			"error":     err,
-		}).Error("Getting queue attributes")
+		})
 		return
 	}
 
 	return
 }
 
+// GetAccountID returns the AWS account ID parsed from the queue URL.
+// The URL has the form https://sqs.{region}.amazonaws.com/{account-id}/{name}.
+func (queue *Queue) GetAccountID() (accountID string, err error) {
+	parsed, err := url.Parse(queue.URL)
+	if err != nil {
+		return "", ErrInvalidQueueURL
+	}
+
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) != 2 || segments[0] == "" {
+		return "", ErrInvalidQueueURL
+	}
+
+	accountID = segments[0]
+	return
+}
+
+// GetQueueRegion returns the region parsed from the queue URL.
+// The URL has the form https://sqs.{region}.amazonaws.com/{account-id}/{name}, the result is cached.
+func (queue *Queue) GetQueueRegion() (region string, err error) {
+	queue.urlRegionMutex.Lock()
+	defer queue.urlRegionMutex.Unlock()
+
+	if queue.urlRegion != "" {
+		return queue.urlRegion, nil
+	}
+
+	parsed, err := url.Parse(queue.URL)
+	if err != nil {
+		return "", ErrInvalidQueueURL
+	}
+
+	hostParts := strings.Split(parsed.Hostname(), ".")
+	switch {
+	case len(hostParts) >= 3 && hostParts[0] == "sqs":
+		region = hostParts[1]
+	case len(hostParts) >= 3 && hostParts[1] == "queue":
+		// Legacy https://{region}.queue.amazonaws.com URLs.
+		region = hostParts[0]
+	default:
+		return "", ErrInvalidQueueURL
+	}
+
+	queue.urlRegion = region
+	return
+}
+
+// UnmarshalJSON decodes a RedrivePolicy, accepting the maxReceiveCount as a number or a string as SQS returns both.
+func (policy *RedrivePolicy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		MaxReceiveCount     json.Number `json:"maxReceiveCount"`
+		DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	policy.DeadLetterTargetArn = raw.DeadLetterTargetArn
+	policy.MaxReceiveCount = 0
+	if raw.MaxReceiveCount != "" {
+		maxReceiveCount, err := raw.MaxReceiveCount.Int64()
+		if err != nil {
+			return err
+		}
+		policy.MaxReceiveCount = int(maxReceiveCount)
+	}
+
+	return nil
+}
+
 // GetAsAWSString returns the RedrivePolicy as a JSON string poninter for sqs attribute.
 func (policy RedrivePolicy) GetAsAWSString() (policyString *string, err error) {
 	jsonBytes, err := json.Marshal(policy)
 	if err != nil {
-		log.WithFields(log.Fields{
+		NewLogrusLogger(nil).Error("Marshal the RedrivePolicy", Fields{
 			"error": err,
-		}).Error("Marshal the RedrivePolicy")
+		})
 		return
 	}
 
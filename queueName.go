@@ -0,0 +1,93 @@
+package queue
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// MaxQueueNameLength is the SQS limit of queue names, counting the .fifo suffix.
+const MaxQueueNameLength = 80
+
+// ErrInvalidQueueName is returned for queue names SQS would reject.
+// The returned error is a *QueueNameError holding the name and the rule it breaks.
+var ErrInvalidQueueName = errors.New("invalid queue name")
+
+// A QueueNameError reports a queue name and the naming rule it breaks.
+type QueueNameError struct {
+	Name   string
+	Reason string
+}
+
+// Error returns the name and the broken rule.
+func (err *QueueNameError) Error() string {
+	return ErrInvalidQueueName.Error() + " " + strconv.Quote(err.Name) + ": " + err.Reason
+}
+
+// Is makes errors.Is(err, ErrInvalidQueueName) true.
+func (err *QueueNameError) Is(target error) bool {
+	return target == ErrInvalidQueueName
+}
+
+// validateQueueName checks the name against the SQS naming rules, the .fifo suffix is required only for FIFO queues.
+func validateQueueName(name string, fifo bool) error {
+	if name == "" {
+		return &QueueNameError{Name: name, Reason: "name is empty"}
+	}
+	if len(name) > MaxQueueNameLength {
+		return &QueueNameError{Name: name, Reason: "name is " + strconv.Itoa(len(name)) + " characters, over the limit of " + strconv.Itoa(MaxQueueNameLength)}
+	}
+
+	base := name
+	switch {
+	case fifo && !strings.HasSuffix(name, fifoSuffix):
+		return &QueueNameError{Name: name, Reason: "FIFO queue name must end with " + fifoSuffix}
+	case fifo:
+		base = strings.TrimSuffix(name, fifoSuffix)
+	case strings.HasSuffix(name, fifoSuffix):
+		return &QueueNameError{Name: name, Reason: "only FIFO queue names may end with " + fifoSuffix}
+	}
+	if base == "" {
+		return &QueueNameError{Name: name, Reason: "name is empty before the " + fifoSuffix + " suffix"}
+	}
+
+	for i, r := range base {
+		if !isQueueNameRune(r) {
+			return &QueueNameError{Name: name, Reason: "character " + strconv.QuoteRune(r) + " at position " + strconv.Itoa(i) + " is not allowed, only a-z, A-Z, 0-9, _ and -"}
+		}
+	}
+
+	return nil
+}
+
+// isQueueNameRune reports whether the character is allowed in queue names.
+func isQueueNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-'
+}
+
+// prepareName adds the .fifo suffix to the name of FIFO queues without one, then validates it.
+// It is shared by InitContext and EnsureContext.
+func (queue *Queue) prepareName() error {
+	if queue.fifo && !strings.HasSuffix(queue.Name, fifoSuffix) {
+		queue.Name += fifoSuffix
+	}
+
+	return queue.validateName()
+}
+
+// validateName checks the name of the queue, and of the dead letter queue derived from it when it is created too.
+func (queue *Queue) validateName() error {
+	if err := validateQueueName(queue.Name, queue.fifo); err != nil {
+		return err
+	}
+	if queue.withoutDeadLetterQueue || queue.deadLetterQueue != "" {
+		return nil
+	}
+
+	var nameErr *QueueNameError
+	if err := validateQueueName(queue.getDeadLetterQueueName(), queue.fifo); errors.As(err, &nameErr) {
+		return &QueueNameError{Name: queue.Name, Reason: "dead letter queue " + strconv.Quote(nameErr.Name) + ": " + nameErr.Reason}
+	}
+
+	return nil
+}
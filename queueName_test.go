@@ -0,0 +1,52 @@
+package queue_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+)
+
+func TestQueueNameValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []queue.Option
+		valid bool
+	}{
+		{name: "orders", valid: true},
+		{name: "Orders_2-eu", valid: true},
+		{name: strings.Repeat("a", queue.MaxQueueNameLength), opts: []queue.Option{queue.WithoutDeadLetterQueue()}, valid: true},
+		{name: strings.Repeat("a", queue.MaxQueueNameLength+1), opts: []queue.Option{queue.WithoutDeadLetterQueue()}},
+		// The dead letter queue name derived from the name is too long.
+		{name: strings.Repeat("a", queue.MaxQueueNameLength)},
+		{name: ""},
+		{name: "orders.v2"},
+		{name: "orders queue"},
+		{name: "örders"},
+		{name: "orders.fifo"},
+		{name: "orders", opts: []queue.Option{queue.WithFIFO(false)}, valid: true},
+		{name: "orders.fifo", opts: []queue.Option{queue.WithFIFO(false)}, valid: true},
+		{name: ".fifo", opts: []queue.Option{queue.WithFIFO(false)}},
+		{name: strings.Repeat("a", queue.MaxQueueNameLength-len(".fifo")+1), opts: []queue.Option{queue.WithFIFO(false), queue.WithoutDeadLetterQueue()}},
+	}
+
+	for _, test := range tests {
+		client := newFakeClient()
+		_, err := queue.New(test.name, append(test.opts, queue.WithClient(client))...)
+		if test.valid {
+			if err != nil {
+				t.Errorf("expected %q to be valid, got %v", test.name, err)
+			}
+			continue
+		}
+
+		var nameErr *queue.QueueNameError
+		if !errors.Is(err, queue.ErrInvalidQueueName) || !errors.As(err, &nameErr) || nameErr.Reason == "" {
+			t.Errorf("expected %q to be invalid with a reason, got %v", test.name, err)
+		}
+		if len(client.createQueueInputs) != 0 {
+			t.Errorf("expected no queue to be created for %q", test.name)
+		}
+	}
+}
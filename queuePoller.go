@@ -0,0 +1,96 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// A receiveFunc receives messages from the queue of the processor, long polling for waitTimeSeconds.
+type receiveFunc func(ctx context.Context, processor *Processor, waitTimeSeconds int64) ([]*sqs.Message, error)
+
+// receiveUpTo returns a receiveFunc receiving up to max messages with one call.
+func receiveUpTo(max int) receiveFunc {
+	return func(ctx context.Context, processor *Processor, waitTimeSeconds int64) ([]*sqs.Message, error) {
+		return processor.Queue.receiveMessages(ctx, int64(max), waitTimeSeconds)
+	}
+}
+
+// A queuePoller is the receive step of the processing loops, it receives from the queues in priority order.
+type queuePoller struct {
+	// processors handle the queues by decreasing priority, they share their state.
+	processors []*Processor
+	// recreated counts the times each queue was initialized again after it went missing.
+	recreated []int
+	failures  int
+}
+
+// newQueuePoller returns a poller of the queues of the processors, by decreasing priority.
+func newQueuePoller(processors []*Processor) *queuePoller {
+	return &queuePoller{processors: processors, recreated: make([]int, len(processors))}
+}
+
+// checkInitialized returns the unrecoverable error of a queue that was never initialized, unless it can be initialized now.
+func (poller *queuePoller) checkInitialized(ctx context.Context) error {
+	for i, processor := range poller.processors {
+		if processor.Queue.URL != "" {
+			continue
+		}
+		if err := processor.checkQueueExists(ctx, ErrQueueNotInitialized, &poller.recreated[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// poll receives from the first queue returning any message, and returns the processor of that queue with the messages.
+// Only the last queue is long polled, so an idle processor doesn't busy-wait, the others are short polled.
+// It waits for the receive jitter before polling, the empty receive pause after an empty poll and the backoff after an error.
+// When a queue is missing and can't be initialized again, it's error is returned as fatal.
+func (poller *queuePoller) poll(ctx context.Context, receive receiveFunc) (processor *Processor, messages []*sqs.Message, fatal error) {
+	state := poller.processors[0].getState()
+	poller.processors[0].jitterReceive(ctx)
+
+	var err error
+	for i := range poller.processors {
+		processor = poller.processors[i]
+		var waitTimeSeconds int64
+		if i == len(poller.processors)-1 {
+			waitTimeSeconds = processor.Queue.getWaitTimeSeconds()
+		}
+		processor.getLogger().Debug("Polling queue", Fields{
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+
+		state.polling.Store(true)
+		messages, err = receive(ctx, processor, waitTimeSeconds)
+		state.polling.Store(false)
+		processor.recordReceive(err)
+		if err != nil && ctx.Err() == nil {
+			if fatal = processor.checkQueueExists(ctx, err, &poller.recreated[i]); fatal != nil {
+				return
+			}
+		}
+		if err != nil || len(messages) > 0 {
+			break
+		}
+	}
+
+	switch {
+	case ctx.Err() != nil:
+		poller.failures = 0
+	case err != nil:
+		poller.failures++
+		processor.getMetrics().ReceiveError(err)
+		processor.backOffReceiving(ctx, poller.failures)
+	default:
+		poller.failures = 0
+		if len(messages) == 0 {
+			processor.pauseAfterEmptyReceive(ctx)
+		}
+	}
+
+	return
+}
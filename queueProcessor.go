@@ -1,79 +1,874 @@
 package queue
 
 import (
-	"encoding/json"
-	"strings"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/sqs"
-	log "github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
 // UnmarshalMessageBody will return a MessageBody struct from the given sqs.Message.
-func UnmarshalMessageBody(message *sqs.Message, v interface{}) (err error) {
-	reader := strings.NewReader(*message.Body)
-	err = json.NewDecoder(reader).Decode(v)
+// The JSON decoding is lenient unless configured with options, e.g. DecodeStrict or DecodeUseNumber.
+func UnmarshalMessageBody(message *sqs.Message, v interface{}, opts ...DecodeOption) (err error) {
+	return DecodeMessageBody(message, v, newJSONMarshaller(opts...))
+}
+
+// A DecodeError is the error of a message body the marshaller could not decode.
+// Retrying won't fix it, so the Processor moves the message to the dead letter queue.
+type DecodeError struct {
+	MessageID string
+	Err       error
+}
+
+// Error returns the ID of the message and the decoding error.
+func (err *DecodeError) Error() string {
+	return "decoding message " + err.MessageID + ": " + err.Err.Error()
+}
+
+// Unwrap returns the decoding error.
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
+// DecodeMessageBody will decode the body of the given sqs.Message with the marshaller.
+// Encrypted bodies can't be decrypted without the queue, a DecryptionError is returned for them.
+func DecodeMessageBody(message *sqs.Message, v interface{}, marshaller Marshaller) (err error) {
+	if _, ok := GetStringAttribute(message, ContentEncryptionAttribute); ok {
+		return &DecryptionError{Err: errEncryptionNotConfigured}
+	}
+
+	return decodeBody(message, *message.Body, v, marshaller, NewLogrusLogger(nil))
+}
+
+// decodeMessage decodes the body of the message, fetched from S3 when it was offloaded, with the marshaller of the processor.
+func (processor *Processor) decodeMessage(ctx context.Context, message *sqs.Message, v interface{}) (err error) {
+	body, err := processor.Queue.getMessageBody(ctx, message)
 	if err != nil {
-		log.WithFields(log.Fields{
+		return
+	}
+
+	return decodeBody(message, body, v, processor.getMarshaller(), processor.getLogger())
+}
+
+// decodeBody decodes the body of the message with the marshaller.
+// Bodies of SNS notifications are unwrapped, the inner message is decoded.
+// Failures are returned as a *DecodeError.
+func decodeBody(message *sqs.Message, body string, v interface{}, marshaller Marshaller, logger Logger) (err error) {
+	err = marshaller.Unmarshal(unwrapSNSEnvelope(body), decodeTarget(v))
+	if err != nil {
+		logger.Error("Unmarshal messageBody", Fields{
 			//"queueName":         GetQueueName(),
-			"messagID":          *message.MessageId,
-			"messageBodyString": *message.Body,
+			"messagID":          aws.StringValue(message.MessageId),
+			"messageBodyString": body,
 			"error":             err,
-		}).Error("Unmarshal messageBody")
+		})
+		err = &DecodeError{MessageID: aws.StringValue(message.MessageId), Err: err}
 	}
 
 	return
 }
 
+// decodeTarget returns the pointer held by v when v points to an interface holding one, e.g. a fresh body.
+// JSON decodes into such a pointer by itself, the other marshallers would get the interface.
+func decodeTarget(v interface{}) interface{} {
+	body, ok := v.(*interface{})
+	if !ok || body == nil {
+		return v
+	}
+	if value := reflect.ValueOf(*body); value.Kind() == reflect.Ptr && !value.IsNil() {
+		return *body
+	}
+
+	return v
+}
+
+// Default interval of the dependency health check.
+const defaultDependencyHealthCheckInterval = 10 * time.Second
+
+// Default time a shutdown waits for the in-flight messages.
+const defaultDrainTimeout = 30 * time.Second
+
+// ErrHandlerPanicked is the error of a message whose handler panicked.
+var ErrHandlerPanicked = errors.New("message handler panicked")
+
+// ErrDrainTimeout is returned when the in-flight messages were not handled within the drain timeout on shutdown.
+var ErrDrainTimeout = errors.New("in-flight messages were not handled within the drain timeout")
+
+// ErrConflictingHandlers is returned when processing with both HandleWithAck and HandleMessage set,
+// HandleMessage would handle the messages while deleting them is left to the Ack.
+var ErrConflictingHandlers = errors.New("HandleWithAck can not be combined with HandleMessage")
+
+// A processResult is the result of processing a received message.
+type processResult int
+
+// The results of processing a message.
+const (
+	processFailed processResult = iota
+	processHandled
+	// processSkipped is a duplicate deleted without handling it.
+	processSkipped
+)
+
+// checkHandlers returns ErrConflictingHandlers for handlers that can not be combined.
+func (processor *Processor) checkHandlers() error {
+	if processor.HandleWithAck != nil && (processor.HandleMessage != nil || processor.handleMessage != nil) {
+		return ErrConflictingHandlers
+	}
+
+	return nil
+}
+
+// A Handler handles the decoded body of incoming sqs messages.
+type Handler interface {
+	Handle(ctx context.Context, processor *Processor, body *interface{}) error
+}
+
+// HandlerFunc is an adapter to use ordinary functions as a Handler.
+type HandlerFunc func(ctx context.Context, processor *Processor, body *interface{}) error
+
+// Handle calls f(ctx, processor, body).
+func (f HandlerFunc) Handle(ctx context.Context, processor *Processor, body *interface{}) error {
+	return f(ctx, processor, body)
+}
+
 // Processor represents a method that handles incoming sqs messages.
 type Processor struct {
-	Queue             *Queue
+	Queue *Queue
+
+	// HandleMessage handles the messages with the raw message, for its ID and attributes.
+	// The context has the values of the context passed to ProcessWithContext. It is cancelled when extending
+	// the visibility fails, or when a shutdown's drain timeout runs out, not as soon as the shutdown starts,
+	// so the in-flight messages can finish.
+	HandleMessage MessageHandlerFunc
+
+	// Handler handles the messages when HandleMessage is not set. HandleMessageBody is used when neither is set.
+	Handler Handler
+
+	// Deprecated: HandleMessageBody gets no context and message, use HandleMessage instead.
 	HandleMessageBody func(Processor, *interface{}) error
+	handleMessage     MessageHandlerFunc
+
+	// HandleWithAck handles the messages instead of HandleMessageBody, they are not deleted when it returns nil.
+	// It can not be combined with HandleMessage, processing returns ErrConflictingHandlers then.
+	// The handler settles each message with the Ack, also asynchronously after returning.
+	HandleWithAck func(ctx context.Context, body interface{}, ack *Ack) error
+
+	// HandleAndForward handles the messages instead of HandleMessageBody, it's result is sent to ForwardTo
+	// before the message is deleted. A nil result is not forwarded, the message is still deleted.
+	HandleAndForward func(ctx context.Context, body interface{}, message *sqs.Message) (interface{}, error)
+	ForwardTo        *Queue
+	middleware       []Middleware
+
+	// OnPanic is called with the recovered value when handling a message panics.
+	// The message is not deleted, so it is redelivered and eventually dead-lettered.
+	OnPanic func(recovered interface{}, message *sqs.Message)
+
+	// OnUnrecoverableError is called with the error stopping ProcessWithContext, when the queue does not exist
+	// or was never initialized, before it is returned.
+	OnUnrecoverableError func(err error)
+
+	// RecreateOnMissing is how many times ProcessWithContext initializes a missing queue again before giving up.
+	RecreateOnMissing int
+
+	// OnCircuitOpen is called when the circuit breaker set with WithCircuitBreaker opens,
+	// with the number of consecutive failures and the last error.
+	OnCircuitOpen           func(failures int, err error)
+	circuitBreakerThreshold int
+	circuitBreakerCoolDown  time.Duration
+
+	// Metrics receives the events of the processor, e.g. InMemoryMetrics.
+	Metrics Metrics
+
+	// Archiver gets a copy of each message after handling it, successfully or not, e.g. a WriterArchiver,
+	// also in the batch modes. Archiving failures are logged and the message is still deleted, unless StrictArchiving is set,
+	// then a handled message that could not be archived is redelivered. With DeleteBeforeProcessing it is lost either way.
+	Archiver        Archiver
+	StrictArchiving bool
+
+	// Logger receives the log entries of the processor, it defaults to the logger of the Queue.
+	Logger Logger
+
+	// Marshaller decodes the message bodies, it defaults to the marshaller of the Queue.
+	Marshaller Marshaller
+
+	// NewBody returns the fresh value each message is decoded into.
+	// When it is not set, a new value of the type passed to Process is allocated for each message.
+	NewBody func() interface{}
+
+	// Concurrency is the number of messages handled at the same time, it defaults to 1.
+	// Each poll receives up to 10 messages, as many as there are idle workers.
+	Concurrency int
+
+	// DrainTimeout is how long a shutdown waits for the in-flight messages, it defaults to 30 seconds.
+	DrainTimeout time.Duration
+
+	// DeleteBeforeProcessing deletes each message after decoding it, before calling the handler, for at-most-once delivery.
+	// A message whose handler fails or crashes is lost instead of redelivered, it is neither retried nor dead-lettered.
+	// Use it only when a duplicate side effect is worse than a lost message. A message that can't be deleted is not handled.
+	DeleteBeforeProcessing bool
+
+	maxMessages int64
+
+	dependencyHealthCheck         func(ctx context.Context) error
+	dependencyHealthCheckInterval time.Duration
+
+	// HandleBatch handles the messages collected with WithBatchWindow instead of HandleMessageBody.
+	// The messages are deleted when it returns nil, otherwise all of them are redelivered.
+	HandleBatch      func(ctx context.Context, processor *Processor, messages []*sqs.Message) error
+	batchMaxMessages int
+	batchWindow      time.Duration
+
+	// HandleDecodedBatch handles the decoded messages of each receive, up to 10, instead of HandleMessageBody.
+	// The messages it reports as failed are redelivered, the others are deleted. When it returns an error all are redelivered.
+	HandleDecodedBatch func(ctx context.Context, messages []DecodedMessage) ([]Failed, error)
+
+	visibilityExtensionBuffer time.Duration
+	heartbeatInterval         time.Duration
+	heartbeatExtension        time.Duration
+	heartbeatMaxTotal         time.Duration
+
+	retryBaseDelay time.Duration
+
+	drainEmptyReceives int
+
+	rateLimiter *rate.Limiter
+
+	receiveBackoff    time.Duration
+	maxReceiveBackoff time.Duration
+
+	receiveJitter     time.Duration
+	emptyReceivePause time.Duration
+
+	deduplication *deduplicationCache
+
+	batchSize int
+
+	// MetricsHandler serves /metrics on the health endpoint, e.g. a Prometheus handler.
+	MetricsHandler     http.Handler
+	healthEndpointAddr string
+
+	state *processorState
+}
+
+// processorState is the state of a running processor shared by all copies of the Processor.
+type processorState struct {
+	processedMessages atomic.Int64
+	failedMessages    atomic.Int64
+	deletedMessages   atomic.Int64
+	handling          atomic.Int64
+	paused            atomic.Bool
+	processing        atomic.Bool
+	polling           atomic.Bool
+	// startedAt and lastReceive are Unix nanoseconds, zero before the first one.
+	startedAt   atomic.Int64
+	lastReceive atomic.Int64
+
+	// dependencyCheckedAt is only used by the polling goroutine.
+	dependencyCheckedAt time.Time
+
+	circuit circuitBreaker
+
+	// deletes batches the deletes of a running processor with a batch size, by queue.
+	deletes atomic.Pointer[map[*Queue]*deleteBatcher]
+
+	cancelMutex sync.Mutex
+	cancel      context.CancelFunc
+}
+
+// setCancel sets the function cancelling the running processor.
+func (state *processorState) setCancel(cancel context.CancelFunc) {
+	state.cancelMutex.Lock()
+	defer state.cancelMutex.Unlock()
+
+	state.cancel = cancel
+}
+
+// stop cancels the running processor.
+func (state *processorState) stop() {
+	state.cancelMutex.Lock()
+	defer state.cancelMutex.Unlock()
+
+	if state.cancel != nil {
+		state.cancel()
+	}
+}
+
+// stateMutex guards the lazy creation of the state of the processors, which may be read concurrently, e.g. by Stats.
+var stateMutex sync.Mutex
+
+// getState returns the state of the processor.
+func (processor *Processor) getState() *processorState {
+	stateMutex.Lock()
+	defer stateMutex.Unlock()
+
+	if processor.state == nil {
+		processor.state = new(processorState)
+	}
+
+	return processor.state
+}
+
+// WithMaxMessages stops the processor after maxTotal messages were processed successfully.
+// The count is shared by everything processing with this Processor, so it is a combined total.
+func (processor *Processor) WithMaxMessages(maxTotal int) *Processor {
+	processor.maxMessages = int64(maxTotal)
+
+	return processor
+}
+
+// WithDependencyHealthCheck pauses receiving while fn returns an error.
+// The check runs every interval (10 seconds when not positive), while it fails the messages stay in the queue.
+func (processor *Processor) WithDependencyHealthCheck(fn func(ctx context.Context) error, interval time.Duration) *Processor {
+	if interval <= 0 {
+		interval = defaultDependencyHealthCheckInterval
+	}
+	processor.dependencyHealthCheck = fn
+	processor.dependencyHealthCheckInterval = interval
+
+	return processor
+}
+
+// handle passes the decoded body through the middleware to the handler of the processor.
+func (processor *Processor) handle(ctx context.Context, message *sqs.Message, body *interface{}) error {
+	return processor.wrapMiddleware(processor.handleBody)(ctx, *body, message)
+}
+
+// handleBody passes the decoded body to the handler of the processor.
+func (processor *Processor) handleBody(ctx context.Context, body interface{}, message *sqs.Message) error {
+	if processor.handleMessage != nil {
+		return processor.handleMessage(ctx, body, message)
+	}
+	if processor.HandleMessage != nil {
+		return processor.HandleMessage(ctx, body, message)
+	}
+	if processor.HandleWithAck != nil {
+		return processor.HandleWithAck(ctx, body, &Ack{queue: processor.Queue, message: message})
+	}
+	if processor.HandleAndForward != nil {
+		return processor.handleAndForward(ctx, body, message)
+	}
+	if processor.Handler != nil {
+		return processor.Handler.Handle(ctx, processor, &body)
+	}
+
+	return processor.HandleMessageBody(*processor, &body)
+}
+
+// handleRecovering handles the message like handle, but recovers a panic of the handler and returns it as an error.
+func (processor *Processor) handleRecovering(ctx context.Context, message *sqs.Message, body *interface{}) (err error) {
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			return
+		}
+
+		processor.getLogger().Error("Panic handling message", Fields{
+			"panic":     recovered,
+			"stack":     string(debug.Stack()),
+			"messageID": aws.StringValue(message.MessageId),
+			"queueName": processor.Queue.Name,
+		})
+		if processor.OnPanic != nil {
+			processor.OnPanic(recovered, message)
+		}
+		err = fmt.Errorf("%w: %v", ErrHandlerPanicked, recovered)
+	}()
+
+	return processor.handle(ctx, message, body)
+}
+
+// maxMessagesReached reports whether the processor has processed its maximum number of messages.
+func (processor *Processor) maxMessagesReached() bool {
+	if processor.maxMessages <= 0 {
+		return false
+	}
+
+	return processor.getState().processedMessages.Load() >= processor.maxMessages
+}
+
+// remainingMessages returns how many more messages may be received without exceeding the maximum number of messages,
+// counting the pending ones as processed. Without a maximum it returns a full receive batch.
+func (processor *Processor) remainingMessages(pending int64) int {
+	if processor.maxMessages <= 0 {
+		return MaxBatchSize
+	}
+
+	remaining := processor.maxMessages - processor.getState().processedMessages.Load() - pending
+	if remaining <= 0 {
+		return 0
+	}
+	if remaining > MaxBatchSize {
+		return MaxBatchSize
+	}
+
+	return int(remaining)
+}
+
+// waitForHealthyDependencies blocks while the dependency health check fails.
+func (processor *Processor) waitForHealthyDependencies(ctx context.Context) {
+	state := processor.getState()
+	if processor.dependencyHealthCheck == nil || time.Since(state.dependencyCheckedAt) < processor.dependencyHealthCheckInterval {
+		return
+	}
+
+	for {
+		state.dependencyCheckedAt = time.Now()
+		err := processor.dependencyHealthCheck(ctx)
+		if err == nil {
+			if state.paused.Swap(false) {
+				processor.getLogger().Info("Dependencies healthy, processing resumed", Fields{
+					"queueName": processor.Queue.Name,
+				})
+			}
+			return
+		}
+
+		if !state.paused.Swap(true) {
+			processor.getLogger().Warn("Dependencies unhealthy, processing paused", Fields{
+				"queueName": processor.Queue.Name,
+				"error":     err,
+			})
+		}
+		if aws.SleepWithContext(ctx, processor.dependencyHealthCheckInterval) != nil {
+			return
+		}
+	}
 }
 
 // Process handles incoming sqs messages.
 // The body parameter is not typed, so we can decode the incoming message in a structure that is passed via this parameter.
 // On passing nil, the Json marshaller will marshall it as map[string]interface{}.
 //
-// Since we are passing the containing structure, this method is not threadsafe.
-// On the other hand multiple Processors can process the same sqs queues parallel without any problem.
+// Each message is decoded into a fresh value of the type of body, or the value returned by NewBody,
+// so fields of a previous message never leak into the next one.
+// Multiple Processors can process the same sqs queues parallel without any problem.
+// The error stopping it, e.g. ErrQueueNotFound, is logged at error level since it can not be returned.
 func (processor *Processor) Process(body interface{}) {
-	queueDetails := log.Fields{
+	if err := processor.ProcessWithContext(context.Background(), body); err != nil {
+		processor.getLogger().Error("Processing queue stopped with an error", Fields{
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+			"error":     err,
+		})
+	}
+}
+
+// ProcessWithContext handles incoming sqs messages like Process until the context is cancelled or Stop is called.
+// On shutdown it stops polling and waits up to DrainTimeout for the messages being handled, then returns.
+// ErrDrainTimeout is returned when the handler did not finish in time, in that case the handler's context is cancelled.
+// The handlers' context carries the values of ctx, e.g. the logger or trace of the caller, but not it's cancellation:
+// it is only cancelled when the drain timeout runs out or extending the visibility of the message fails.
+// When the queue does not exist or was never initialized, it stops and returns ErrQueueNotFound or ErrQueueNotInitialized.
+// ErrConflictingHandlers is returned before receiving when both HandleWithAck and HandleMessage are set.
+func (processor *Processor) ProcessWithContext(ctx context.Context, body interface{}) error {
+	return processQueues(ctx, body, []*Processor{processor})
+}
+
+// processQueues handles the messages of the queues of the processors like ProcessWithContext,
+// receiving from them in priority order. The processors share their state and settings, the first one's are used.
+func processQueues(ctx context.Context, body interface{}, processors []*Processor) error {
+	processor := processors[0]
+	if err := processor.checkHandlers(); err != nil {
+		return err
+	}
+	queueDetails := Fields{
 		"queueName": processor.Queue.Name,
 		"queueURL":  processor.Queue.URL,
 	}
+	if len(processors) > 1 {
+		queueDetails["queues"] = len(processors)
+	}
 
-	log.WithFields(queueDetails).Info("Processing queue started")
-	for {
-		log.WithFields(queueDetails).Info("Polling queue")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	state := processor.getState()
+	state.setCancel(cancel)
 
-		message, err := processor.Queue.ReceiveMessage()
-		if err != nil || message == nil {
-			continue
-		}
-		err = UnmarshalMessageBody(message, &body)
+	if processor.healthEndpointAddr != "" {
+		stopHealthEndpoint, err := processor.startHealthEndpoint()
 		if err != nil {
-			log.WithFields(log.Fields{
-				"error": err,
-				"body":  body,
-			}).Warning("Error unmarshalling message")
+			return err
+		}
+		defer stopHealthEndpoint()
+	}
+	state.processing.Store(true)
+	state.startedAt.Store(time.Now().UnixNano())
+	defer state.processing.Store(false)
+
+	poller := newQueuePoller(processors)
+	if err := poller.checkInitialized(ctx); err != nil {
+		return err
+	}
+
+	processor.getLogger().Info("Processing queue started", queueDetails)
 
+	// Handlers get their own context with the values of ctx, so a shutdown lets the in-flight messages finish.
+	handlerCtx, cancelHandlers := context.WithCancel(withoutCancel(ctx))
+	defer cancelHandlers()
+	if processor.HandleBatch != nil && processor.batchMaxMessages > 0 {
+		return processor.drainBatches(ctx, cancelHandlers, queueDetails, func() error {
+			return processor.processBatchWindows(ctx, handlerCtx, poller, queueDetails)
+		})
+	}
+	if processor.HandleDecodedBatch != nil {
+		return processor.drainBatches(ctx, cancelHandlers, queueDetails, func() error {
+			return processor.processDecodedBatches(ctx, handlerCtx, poller, body, queueDetails)
+		})
+	}
+	if processor.batchSize > 1 {
+		batchers := make(map[*Queue]*deleteBatcher, len(processors))
+		for _, queueProcessor := range processors {
+			batcher, stopDeletes := queueProcessor.startDeleteBatcher(handlerCtx)
+			batchers[queueProcessor.Queue] = batcher
+			defer stopDeletes()
+		}
+		state.deletes.Store(&batchers)
+		defer state.deletes.Store(nil)
+	}
+	var inFlight sync.WaitGroup
+	workers := make(chan struct{}, processor.getConcurrency())
+	// pending counts the received messages not handled yet, finished is signalled when one is.
+	var pending atomic.Int64
+	finished := make(chan struct{}, 1)
+	var fatal error
+	logger := processor.getLogger()
+	for ctx.Err() == nil {
+		if processor.maxMessagesReached() {
+			logger.Info("Processing queue stopped, maximum number of messages reached", queueDetails)
+			break
+		}
+
+		idle := acquireWorkers(ctx, workers)
+		if idle == 0 {
 			continue
 		}
-		if err = processor.HandleMessageBody(*processor, &body); err != nil {
-			log.WithFields(log.Fields{
-				"error":     err,
-				"message":   message,
-				"queueName": processor.Queue.Name,
-				"queueURL":  processor.Queue.URL,
-			}).Warning("Error processing message")
+		remaining := processor.remainingMessages(pending.Load())
+		if idle > remaining {
+			for i := remaining; i < idle; i++ {
+				<-workers
+			}
+			if remaining == 0 {
+				// The in-flight messages may reach the maximum, wait for one to finish.
+				select {
+				case <-finished:
+				case <-ctx.Done():
+				}
+				continue
+			}
+			idle = remaining
+		}
+		allowed := processor.waitForRate(ctx, idle)
+		for i := allowed; i < idle; i++ {
+			<-workers
+		}
+		if allowed == 0 {
 			continue
 		}
-		if _, err := processor.Queue.DeleteMessage(message); err != nil {
-			log.WithFields(log.Fields{
-				"message":   message,
-				"queueName": processor.Queue.Name,
-				"queueURL":  processor.Queue.URL,
-			}).Warning("Error deleting queue message")
+		idle = allowed
+
+		processor.waitForHealthyDependencies(ctx)
+		allowed, probing := processor.waitForCircuit(ctx, idle)
+		for i := allowed; i < idle; i++ {
+			<-workers
+		}
+		if allowed == 0 {
+			continue
+		}
+		idle = allowed
+
+		extra := 0
+		if !probing {
+			extra = processor.batchExtra(idle)
+		}
+		if extra > remaining-idle {
+			extra = remaining - idle
+		}
+
+		var received *Processor
+		var messages []*sqs.Message
+		received, messages, fatal = poller.poll(ctx, receiveUpTo(idle+extra))
+		if probing && len(messages) == 0 {
+			processor.releaseProbe()
+		}
+		if fatal != nil {
+			break
+		}
+		for i := len(messages); i < idle; i++ {
+			<-workers
+		}
+		processor.chargeRate(handlerCtx, len(messages))
+
+		pending.Add(int64(len(messages)))
+		for i, message := range messages {
+			received.recordReceived(message)
+			inFlight.Add(1)
+			go func(message *sqs.Message, hasWorker bool) {
+				defer inFlight.Done()
+				defer func() {
+					pending.Add(-1)
+					select {
+					case finished <- struct{}{}:
+					default:
+					}
+				}()
+				if !hasWorker {
+					// Messages over the idle workers of the batch size wait for one.
+					workers <- struct{}{}
+				}
+				defer func() { <-workers }()
+				if probing {
+					defer processor.releaseProbe()
+				}
+
+				received.processMessage(handlerCtx, message, body)
+			}(message, i < idle)
 		}
 	}
+
+	if err := processor.drain(&inFlight, cancelHandlers, queueDetails); fatal == nil {
+		return err
+	}
+
+	return fatal
+}
+
+// getConcurrency returns the number of messages handled at the same time.
+func (processor *Processor) getConcurrency() int {
+	if processor.Concurrency < 1 {
+		return 1
+	}
+
+	return processor.Concurrency
+}
+
+// acquireWorkers waits for an idle worker, then takes the other idle ones too, up to a receive batch.
+// It returns 0 when the context is done first.
+func acquireWorkers(ctx context.Context, workers chan struct{}) (idle int) {
+	select {
+	case workers <- struct{}{}:
+		idle++
+	case <-ctx.Done():
+		return
+	}
+
+	for idle < MaxBatchSize {
+		select {
+		case workers <- struct{}{}:
+			idle++
+		default:
+			return
+		}
+	}
+
+	return
+}
+
+// Stop makes a running ProcessWithContext stop polling and return after draining the in-flight messages.
+func (processor *Processor) Stop() {
+	processor.getState().stop()
+}
+
+// drain waits up to the drain timeout for the in-flight messages, then cancels their handlers.
+func (processor *Processor) drain(inFlight *sync.WaitGroup, cancelHandlers context.CancelFunc, queueDetails Fields) error {
+	drained := make(chan struct{})
+	go func() {
+		inFlight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		processor.getLogger().Info("Processing queue stopped", queueDetails)
+		return nil
+	case <-time.After(processor.getDrainTimeout()):
+		cancelHandlers()
+		processor.getLogger().Warn("Processing queue stopped before the in-flight messages were handled", queueDetails)
+		return ErrDrainTimeout
+	}
+}
+
+// valuesContext is a context with the values of it's parent, without it's deadline and cancellation.
+type valuesContext struct {
+	parent context.Context
+}
+
+// withoutCancel returns a context with the values of the parent that is not cancelled with it, like context.WithoutCancel.
+func withoutCancel(parent context.Context) context.Context {
+	return valuesContext{parent: parent}
+}
+
+// Deadline returns no deadline.
+func (valuesContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+// Done returns nil, the context is never cancelled.
+func (valuesContext) Done() <-chan struct{} {
+	return nil
+}
+
+// Err returns nil, the context is never cancelled.
+func (valuesContext) Err() error {
+	return nil
+}
+
+// Value returns the value of the parent.
+func (ctx valuesContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
+
+// getDrainTimeout returns how long a shutdown waits for the in-flight messages.
+func (processor *Processor) getDrainTimeout() time.Duration {
+	if processor.DrainTimeout <= 0 {
+		return defaultDrainTimeout
+	}
+
+	return processor.DrainTimeout
+}
+
+// newBody returns a fresh value for decoding a message, of the same type as the template passed to Process.
+func (processor *Processor) newBody(template interface{}) interface{} {
+	if processor.NewBody != nil {
+		return processor.NewBody()
+	}
+
+	value := reflect.ValueOf(template)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return nil
+	}
+
+	return reflect.New(value.Type().Elem()).Interface()
+}
+
+// processMessage decodes and handles one message, and deletes it when it was handled successfully.
+// It reports whether the message was handled successfully.
+func (processor *Processor) processMessage(ctx context.Context, message *sqs.Message, template interface{}) processResult {
+	ctx = context.WithValue(contextWithMessage(ctx, message), queueContextKey{}, processor.Queue)
+	state := processor.getState()
+	state.handling.Add(1)
+	defer state.handling.Add(-1)
+	if processor.skipDuplicate(ctx, message) {
+		return processSkipped
+	}
+	body := processor.newBody(template)
+	err := processor.decodeMessage(ctx, message, &body)
+	if err != nil {
+		processor.getLogger().Warn("Error unmarshalling message", Fields{
+			"error":     err,
+			"messageID": message.MessageId,
+			"body":      body,
+		})
+		processor.recordFailed(err)
+		var decodeErr *DecodeError
+		if errors.As(err, &decodeErr) {
+			processor.deadLetterMessage(ctx, message, err)
+		}
+
+		return processFailed
+	}
+	if err := processor.Queue.validateIncoming(body, message); err != nil {
+		processor.getLogger().Warn("Invalid message", Fields{
+			"error":     err,
+			"messageID": message.MessageId,
+			"queueName": processor.Queue.Name,
+		})
+		processor.recordFailed(err)
+		processor.deadLetterMessage(ctx, message, err)
+
+		return processFailed
+	}
+	if processor.DeleteBeforeProcessing {
+		if processor.processDeletedMessage(ctx, message, &body) {
+			return processHandled
+		}
+		return processFailed
+	}
+	handlerCtx, stopVisibilityExtension := processor.startVisibilityExtension(ctx, message)
+	started := time.Now()
+	err = processor.handleRecovering(handlerCtx, message, &body)
+	duration := time.Since(started)
+	stopVisibilityExtension()
+	processor.recordCircuitResult(err)
+	archiveErr := processor.archive(ctx, message, Outcome{Err: err, StartedAt: started, FinishedAt: started.Add(duration)})
+	if err != nil {
+		processor.recordFailed(err)
+		processor.getLogger().Warn("Error processing message", Fields{
+			"error":     err,
+			"message":   message,
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+		if IsPermanentError(err) {
+			processor.deadLetterMessage(ctx, message, err)
+		} else {
+			processor.retryMessageAfter(ctx, message, err)
+		}
+		return processFailed
+	}
+	processor.getMetrics().MessageProcessed(duration)
+	if archiveErr != nil && processor.StrictArchiving {
+		return processFailed
+	}
+	processor.recordHandled(message)
+	if processor.HandleWithAck != nil {
+		state.processedMessages.Add(1)
+		return processHandled
+	}
+	if err := processor.deleteMessage(ctx, message); err != nil {
+		processor.getLogger().Warn("Error deleting queue message", Fields{
+			"message":   message,
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+	} else {
+		processor.recordDeleted()
+	}
+	state.processedMessages.Add(1)
+
+	return processHandled
+}
+
+// processDeletedMessage deletes the decoded message, then handles it, for DeleteBeforeProcessing.
+// It reports whether the message was handled successfully.
+func (processor *Processor) processDeletedMessage(ctx context.Context, message *sqs.Message, body *interface{}) (handled bool) {
+	if _, err := processor.Queue.DeleteMessageContext(ctx, message); err != nil {
+		processor.recordFailed(err)
+		processor.getLogger().Warn("Error deleting queue message before processing, it is not handled", Fields{
+			"error":     err,
+			"messageID": message.MessageId,
+			"queueName": processor.Queue.Name,
+		})
+		return false
+	}
+	processor.recordDeleted()
+
+	started := time.Now()
+	err := processor.handleRecovering(ctx, message, body)
+	duration := time.Since(started)
+	processor.recordCircuitResult(err)
+	processor.archive(ctx, message, Outcome{Err: err, StartedAt: started, FinishedAt: started.Add(duration)})
+	if err != nil {
+		processor.recordFailed(err)
+		processor.getLogger().Warn("Error processing message, it was deleted before processing and is lost", Fields{
+			"error":     err,
+			"message":   message,
+			"queueName": processor.Queue.Name,
+			"queueURL":  processor.Queue.URL,
+		})
+		return false
+	}
+	processor.getMetrics().MessageProcessed(duration)
+	processor.recordHandled(message)
+	processor.getState().processedMessages.Add(1)
+
+	return true
 }
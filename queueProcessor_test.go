@@ -0,0 +1,494 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// contextKey is the type of the context values of the tests.
+type contextKey string
+
+func TestHandleMessageGetsMessageAndValues(t *testing.T) {
+	q, err := memqueue.New("handled", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := q.SendMessage("body")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var messageID, value interface{}
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			messageID = *message.MessageId
+			value = ctx.Value(contextKey("request"))
+			return nil
+		},
+	}
+	ctx := context.WithValue(context.Background(), contextKey("request"), "value")
+	if _, err := processor.ProcessN(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if messageID != *sent.MessageId {
+		t.Errorf("expected the received message %s, got %v", *sent.MessageId, messageID)
+	}
+	if value != "value" {
+		t.Errorf("expected the value of the context, got %v", value)
+	}
+}
+
+func TestHandlerContextCancelledAfterDrainTimeout(t *testing.T) {
+	q, err := memqueue.New("handled", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	started := make(chan struct{})
+	cancelled := make(chan interface{}, 1)
+	processor := &queue.Processor{
+		Queue:        q,
+		DrainTimeout: 50 * time.Millisecond,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			close(started)
+			<-ctx.Done()
+			cancelled <- ctx.Value(contextKey("request"))
+			return ctx.Err()
+		},
+	}
+	go func() {
+		<-started
+		processor.Stop()
+	}()
+
+	ctx := context.WithValue(context.Background(), contextKey("request"), "value")
+	if err := processor.ProcessWithContext(ctx, nil); err != queue.ErrDrainTimeout {
+		t.Errorf("expected ErrDrainTimeout, got %v", err)
+	}
+	select {
+	case value := <-cancelled:
+		if value != "value" {
+			t.Errorf("expected the value of the context, got %v", value)
+		}
+	case <-time.After(time.Second):
+		t.Error("expected the handler context to be cancelled")
+	}
+}
+
+func TestConflictingHandlers(t *testing.T) {
+	q, err := memqueue.New("handled", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	called := false
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			called = true
+			return nil
+		},
+		HandleWithAck: func(ctx context.Context, body interface{}, ack *queue.Ack) error {
+			called = true
+			return nil
+		},
+	}
+
+	if _, err := processor.ProcessN(context.Background(), 1); err != queue.ErrConflictingHandlers {
+		t.Errorf("expected ErrConflictingHandlers from ProcessN, got %v", err)
+	}
+	if err := processor.ProcessWithContext(context.Background(), nil); err != queue.ErrConflictingHandlers {
+		t.Errorf("expected ErrConflictingHandlers from ProcessWithContext, got %v", err)
+	}
+	if called {
+		t.Error("expected no handler to run")
+	}
+}
+
+func TestHandleWithAckDeletesOnAck(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "acked", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("keep")
+	q.SendMessage("ack")
+
+	processor := &queue.Processor{
+		Queue: q,
+		HandleWithAck: func(ctx context.Context, body interface{}, ack *queue.Ack) error {
+			if body == "ack" {
+				return ack.Ack()
+			}
+			return nil
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 2 {
+		t.Errorf("expected 2 processed messages, got %+v", summary)
+	}
+	if bodies := client.Messages(q.URL); len(bodies) != 1 || bodies[0] != `"keep"` {
+		t.Errorf("expected only the acknowledged message to be deleted, got %v", bodies)
+	}
+}
+
+func TestWithMaxMessagesStopsAllWorkers(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "limited", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		q.SendMessage(i)
+	}
+
+	var handled atomic.Int64
+	processor := (&queue.Processor{
+		Queue:       q,
+		Concurrency: 4,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled.Add(1)
+			return nil
+		},
+	}).WithMaxMessages(5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := processor.ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatal("expected the processor to stop by itself")
+	}
+	if count := handled.Load(); count != 5 {
+		t.Errorf("expected 5 handled messages, got %d", count)
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 15 {
+		t.Errorf("expected 15 messages to stay in the queue, got %d", remaining)
+	}
+}
+
+func TestStopDrainsRunningHandler(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "draining", queue.WithReceiveWaitTime(20*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	started := make(chan struct{})
+	processor := &queue.Processor{
+		Queue:        q,
+		DrainTimeout: 2 * time.Second,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			close(started)
+			time.Sleep(100 * time.Millisecond)
+			return ctx.Err()
+		},
+	}
+	go func() {
+		<-started
+		processor.Stop()
+	}()
+
+	begin := time.Now()
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatalf("expected the shutdown to drain the handler, got %v", err)
+	}
+
+	// The long poll of 20 seconds is cancelled, only the handler is waited for.
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("expected the shutdown within the drain timeout, took %s", elapsed)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 0 {
+		t.Errorf("expected the drained message to be deleted, got %v", remaining)
+	}
+}
+
+func TestContextCancelStopsLongPoll(t *testing.T) {
+	q, err := memqueue.New("idle", queue.WithReceiveWaitTime(20*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	begin := time.Now()
+	if err := processor.ProcessWithContext(ctx, nil); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Errorf("expected the long poll to be cancelled, took %s", elapsed)
+	}
+}
+
+// decodedUser is a body with an optional field.
+type decodedUser struct {
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+func TestEachMessageGetsFreshBody(t *testing.T) {
+	q, err := memqueue.New("fresh", queue.WithReceiveWaitTime(0), queue.WithFIFO(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessageFIFO(decodedUser{Name: "first", Email: "first@example.com"}, "users", "1")
+	q.SendMessageFIFO(decodedUser{Name: "second"}, "users", "2")
+
+	var received []decodedUser
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessageBody: func(processor queue.Processor, body *interface{}) error {
+			received = append(received, *(*body).(*decodedUser))
+			return nil
+		},
+	}).WithMaxMessages(2)
+	if err := processor.ProcessWithContext(context.Background(), &decodedUser{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 || received[0].Email != "first@example.com" {
+		t.Fatalf("expected both messages, got %+v", received)
+	}
+	if received[1].Name != "second" || received[1].Email != "" {
+		t.Errorf("expected the omitted field to be empty, got %+v", received[1])
+	}
+}
+
+func TestNewBodyAllocatesPerMessage(t *testing.T) {
+	q, err := memqueue.New("fresh", queue.WithReceiveWaitTime(0), queue.WithFIFO(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessageFIFO(decodedUser{Name: "first", Email: "first@example.com"}, "users", "1")
+	q.SendMessageFIFO(decodedUser{Name: "second"}, "users", "2")
+
+	var received []*decodedUser
+	processor := &queue.Processor{
+		Queue:   q,
+		NewBody: func() interface{} { return new(decodedUser) },
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			received = append(received, body.(*decodedUser))
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 2 || received[0] == received[1] {
+		t.Fatalf("expected a value for each message, got %+v", received)
+	}
+	if received[0].Email != "first@example.com" || received[1].Email != "" {
+		t.Errorf("expected the omitted field to be empty, got %+v and %+v", received[0], received[1])
+	}
+}
+
+func TestConcurrencyRunsHandlersInParallel(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "parallel", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const workers = 4
+	for i := 0; i < workers; i++ {
+		q.SendMessage(i)
+	}
+
+	// Each handler waits until all of them are running, which only finishes when they run in parallel.
+	var barrier sync.WaitGroup
+	barrier.Add(workers)
+	processor := &queue.Processor{
+		Queue:       q,
+		Concurrency: workers,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			barrier.Done()
+			waited := make(chan struct{})
+			go func() {
+				barrier.Wait()
+				close(waited)
+			}()
+			select {
+			case <-waited:
+			case <-time.After(5 * time.Second):
+				return errors.New("handlers did not run in parallel")
+			}
+			if body == float64(0) {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), workers)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != workers-1 || summary.Failed != 1 {
+		t.Errorf("expected one failed message, got %+v", summary)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 1 || remaining[0] != "0" {
+		t.Errorf("expected only the failed message to stay in the queue, got %v", remaining)
+	}
+}
+
+func TestPanicRecovered(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "panicking", queue.WithReceiveWaitTime(0), queue.WithFIFO(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessageFIFO("panic", "group", "1")
+	q.SendMessageFIFO("next", "other", "2")
+
+	var handled []interface{}
+	var recovered interface{}
+	var panicked *sqs.Message
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			if body == "panic" {
+				var m map[string]int
+				m["write"] = 1
+			}
+			handled = append(handled, body)
+			return nil
+		},
+		OnPanic: func(value interface{}, message *sqs.Message) {
+			recovered = value
+			panicked = message
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Failed != 1 || summary.Processed != 1 || len(handled) != 1 || handled[0] != "next" {
+		t.Errorf("expected the loop to handle the next message, got %+v %v", summary, handled)
+	}
+	if recovered == nil || panicked == nil {
+		t.Fatal("expected OnPanic to get the panic and the message")
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 1 || remaining[0] != `"panic"` {
+		t.Errorf("expected the panicked message not to be deleted, got %v", remaining)
+	}
+}
+
+func TestDeleteBeforeProcessing(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "atMostOnce", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("fail")
+
+	calls := 0
+	var queuedWhileHandling []string
+	processor := &queue.Processor{
+		Queue:                  q,
+		DeleteBeforeProcessing: true,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			calls++
+			queuedWhileHandling = client.Messages(q.URL)
+			return errors.New("failed")
+		},
+	}
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Failed != 1 {
+		t.Errorf("expected the message to fail, got %+v", summary)
+	}
+	if len(queuedWhileHandling) != 0 {
+		t.Errorf("expected the message to be deleted before the handler ran, got %v", queuedWhileHandling)
+	}
+	// The failed message would be visible again right away without the deletion.
+	summary, err = processor.Drain(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || summary != (queue.ProcessSummary{}) {
+		t.Errorf("expected the failed message not to be redelivered, got %d calls and %+v", calls, summary)
+	}
+}
+
+func TestDependencyHealthCheckPausesReceiving(t *testing.T) {
+	client := &scheduledReceiveClient{fakeClient: newFakeClient(), failures: make([]bool, 1)}
+	logger := &recordingLogger{}
+	q, err := queue.New("dependent", queue.WithClient(client), queue.WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The dependency is down for the first three checks.
+	var checks []time.Time
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}).WithDependencyHealthCheck(func(ctx context.Context) error {
+		checks = append(checks, time.Now())
+		if len(checks) <= 3 {
+			return errors.New("database unreachable")
+		}
+		return nil
+	}, 30*time.Millisecond)
+	client.stop = processor.Stop
+
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(checks) < 4 {
+		t.Fatalf("expected the check to be repeated until it succeeded, got %d checks", len(checks))
+	}
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if len(client.calls) == 0 || client.calls[0].Before(checks[3]) {
+		t.Errorf("expected no receive before the dependency recovered, got %v", client.calls)
+	}
+	for i := 1; i < 4; i++ {
+		if wait := checks[i].Sub(checks[i-1]); wait < 30*time.Millisecond {
+			t.Errorf("expected the failed check %d to be repeated after the interval, got %s", i, wait)
+		}
+	}
+	if paused := logger.leveled("warn"); len(paused) != 1 || paused[0]["msg"] != "Dependencies unhealthy, processing paused" {
+		t.Errorf("expected the pause to be logged once, got %v", paused)
+	}
+	resumed := false
+	for _, entry := range logger.leveled("info") {
+		resumed = resumed || entry["msg"] == "Dependencies healthy, processing resumed"
+	}
+	if !resumed {
+		t.Error("expected the resume to be logged")
+	}
+}
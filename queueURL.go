@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// ErrQueueNotFound is returned when the queue does not exist.
+// errors.Is matches it, while errors.As still finds the underlying AWS error.
+var ErrQueueNotFound = errors.New("queue does not exist")
+
+// queueNotFoundError wraps the AWS error of a queue that does not exist.
+type queueNotFoundError struct {
+	name string
+	err  error
+}
+
+// Error returns the name of the queue and the AWS error.
+func (err *queueNotFoundError) Error() string {
+	return "queue " + err.name + " does not exist: " + err.err.Error()
+}
+
+// Is makes errors.Is(err, ErrQueueNotFound) true.
+func (err *queueNotFoundError) Is(target error) bool {
+	return target == ErrQueueNotFound
+}
+
+// Unwrap returns the AWS error.
+func (err *queueNotFoundError) Unwrap() error {
+	return err.err
+}
+
+// isQueueNotFound reports whether the AWS error is about a queue that does not exist.
+func isQueueNotFound(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	return aerr.Code() == sqs.ErrCodeQueueDoesNotExist || aerr.Code() == "AWS.SimpleQueueService.NonExistentQueue"
+}
+
+// GetQueueURL returns the URL of the queue by it's name.
+// ErrQueueNotFound is returned, wrapping the AWS error, when the queue does not exist.
+func GetQueueURL(ctx context.Context, client sqsiface.SQSAPI, name string) (string, error) {
+	return getQueueURL(ctx, client, name, "")
+}
+
+// getQueueURL returns the URL of the queue by it's name, owned by the account if the account ID is not empty.
+func getQueueURL(ctx context.Context, client sqsiface.SQSAPI, name string, accountID string) (string, error) {
+	params := &sqs.GetQueueUrlInput{
+		QueueName: aws.String(name),
+	}
+	if accountID != "" {
+		params.QueueOwnerAWSAccountId = aws.String(accountID)
+	}
+	resp, err := client.GetQueueUrlWithContext(ctx, params)
+	if err != nil {
+		if isQueueNotFound(err) {
+			return "", &queueNotFoundError{name: name, err: err}
+		}
+		return "", err
+	}
+
+	return aws.StringValue(resp.QueueUrl), nil
+}
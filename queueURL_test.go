@@ -0,0 +1,37 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestGetQueueURL(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	url, err := queue.GetQueueURL(context.Background(), client, "orders")
+	if err != nil || url != q.URL {
+		t.Errorf("expected %s, got %q and %v", q.URL, url, err)
+	}
+}
+
+func TestGetQueueURLNotFound(t *testing.T) {
+	url, err := queue.GetQueueURL(context.Background(), memqueue.NewClient(), "missing")
+
+	if url != "" || !errors.Is(err, queue.ErrQueueNotFound) {
+		t.Fatalf("expected ErrQueueNotFound, got %q and %v", url, err)
+	}
+	var awsErr awserr.Error
+	if !errors.As(err, &awsErr) || awsErr.Code() != sqs.ErrCodeQueueDoesNotExist {
+		t.Errorf("expected the AWS error to be wrapped, got %v", err)
+	}
+}
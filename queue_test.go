@@ -0,0 +1,605 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs/sqsiface"
+)
+
+// fakeClient is a hand-rolled SQS client recording the inputs of the calls it implements,
+// received messages are served from the sent ones, the other calls fail.
+type fakeClient struct {
+	sqsiface.SQSAPI
+	mutex               sync.Mutex
+	createQueueInputs   []*sqs.CreateQueueInput
+	getAttributesInputs []*sqs.GetQueueAttributesInput
+	sendInputs          []*sqs.SendMessageInput
+	sendBatchInputs     []*sqs.SendMessageBatchInput
+	receiveInputs       []*sqs.ReceiveMessageInput
+	deleteInputs        []*sqs.DeleteMessageInput
+	deleteBatchInputs   []*sqs.DeleteMessageBatchInput
+	visibilityInputs    []*sqs.ChangeMessageVisibilityInput
+	messages            []*sqs.Message
+	sent                int
+	// attributes are returned by GetQueueAttributes for the queue URLs instead of the defaults.
+	attributes map[string]map[string]string
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{SQSAPI: queue.NotImplementedClient()}
+}
+
+// fakeQueueURL returns the URL the fake client gives to the queue name.
+func fakeQueueURL(name string) string {
+	return "https://sqs.us-east-1.amazonaws.com/000000000000/" + name
+}
+
+func (client *fakeClient) CreateQueueWithContext(ctx aws.Context, input *sqs.CreateQueueInput, opts ...request.Option) (*sqs.CreateQueueOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.createQueueInputs = append(client.createQueueInputs, input)
+	return &sqs.CreateQueueOutput{QueueUrl: aws.String(fakeQueueURL(*input.QueueName))}, nil
+}
+
+func (client *fakeClient) GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.getAttributesInputs = append(client.getAttributesInputs, input)
+	if attributes, ok := client.attributes[*input.QueueUrl]; ok {
+		return &sqs.GetQueueAttributesOutput{Attributes: aws.StringMap(attributes)}, nil
+	}
+	name := (*input.QueueUrl)[strings.LastIndex(*input.QueueUrl, "/")+1:]
+	return &sqs.GetQueueAttributesOutput{Attributes: map[string]*string{
+		sqs.QueueAttributeNameQueueArn:                              aws.String("arn:aws:sqs:us-east-1:000000000000:" + name),
+		sqs.QueueAttributeNameApproximateNumberOfMessages:           aws.String(fmt.Sprint(len(client.messages))),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesNotVisible: aws.String("0"),
+		sqs.QueueAttributeNameApproximateNumberOfMessagesDelayed:    aws.String("0"),
+	}}, nil
+}
+
+func (client *fakeClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.sendInputs = append(client.sendInputs, input)
+	id := client.push(input.MessageBody, input.MessageAttributes)
+	return &sqs.SendMessageOutput{MessageId: aws.String(id)}, nil
+}
+
+func (client *fakeClient) SendMessageBatchWithContext(ctx aws.Context, input *sqs.SendMessageBatchInput, opts ...request.Option) (*sqs.SendMessageBatchOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.sendBatchInputs = append(client.sendBatchInputs, input)
+	output := &sqs.SendMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		id := client.push(entry.MessageBody, entry.MessageAttributes)
+		output.Successful = append(output.Successful, &sqs.SendMessageBatchResultEntry{Id: entry.Id, MessageId: aws.String(id)})
+	}
+	return output, nil
+}
+
+// push adds a sent message to the received ones and returns it's ID.
+func (client *fakeClient) push(body *string, attributes map[string]*sqs.MessageAttributeValue) string {
+	client.sent++
+	id := fmt.Sprintf("message-%d", client.sent)
+	client.messages = append(client.messages, &sqs.Message{
+		MessageId:         aws.String(id),
+		ReceiptHandle:     aws.String("receipt-" + id),
+		Body:              body,
+		MessageAttributes: attributes,
+	})
+	return id
+}
+
+func (client *fakeClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.receiveInputs = append(client.receiveInputs, input)
+	count := len(client.messages)
+	if input.MaxNumberOfMessages != nil && int(*input.MaxNumberOfMessages) < count {
+		count = int(*input.MaxNumberOfMessages)
+	}
+	messages := client.messages[:count]
+	client.messages = client.messages[count:]
+	return &sqs.ReceiveMessageOutput{Messages: messages}, nil
+}
+
+func (client *fakeClient) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.deleteInputs = append(client.deleteInputs, input)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (client *fakeClient) DeleteMessageBatchWithContext(ctx aws.Context, input *sqs.DeleteMessageBatchInput, opts ...request.Option) (*sqs.DeleteMessageBatchOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.deleteBatchInputs = append(client.deleteBatchInputs, input)
+	output := &sqs.DeleteMessageBatchOutput{}
+	for _, entry := range input.Entries {
+		output.Successful = append(output.Successful, &sqs.DeleteMessageBatchResultEntry{Id: entry.Id})
+	}
+	return output, nil
+}
+
+func (client *fakeClient) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.visibilityInputs = append(client.visibilityInputs, input)
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+// contextClient records the contexts of the queue management calls.
+type contextClient struct {
+	*memqueue.Client
+	contexts []aws.Context
+}
+
+func (client *contextClient) SetQueueAttributesWithContext(ctx aws.Context, input *sqs.SetQueueAttributesInput, opts ...request.Option) (*sqs.SetQueueAttributesOutput, error) {
+	client.contexts = append(client.contexts, ctx)
+	return client.Client.SetQueueAttributesWithContext(ctx, input, opts...)
+}
+
+func (client *contextClient) TagQueueWithContext(ctx aws.Context, input *sqs.TagQueueInput, opts ...request.Option) (*sqs.TagQueueOutput, error) {
+	client.contexts = append(client.contexts, ctx)
+	return client.Client.TagQueueWithContext(ctx, input, opts...)
+}
+
+func (client *contextClient) ListQueueTagsWithContext(ctx aws.Context, input *sqs.ListQueueTagsInput, opts ...request.Option) (*sqs.ListQueueTagsOutput, error) {
+	client.contexts = append(client.contexts, ctx)
+	return client.Client.ListQueueTagsWithContext(ctx, input, opts...)
+}
+
+func (client *contextClient) PurgeQueueWithContext(ctx aws.Context, input *sqs.PurgeQueueInput, opts ...request.Option) (*sqs.PurgeQueueOutput, error) {
+	client.contexts = append(client.contexts, ctx)
+	return client.Client.PurgeQueueWithContext(ctx, input, opts...)
+}
+
+func (client *contextClient) DeleteQueueWithContext(ctx aws.Context, input *sqs.DeleteQueueInput, opts ...request.Option) (*sqs.DeleteQueueOutput, error) {
+	client.contexts = append(client.contexts, ctx)
+	return client.Client.DeleteQueueWithContext(ctx, input, opts...)
+}
+
+func TestReceiveMessageContextCancelled(t *testing.T) {
+	q, err := memqueue.New("receive", queue.WithReceiveWaitTime(20*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	started := time.Now()
+	message, err := q.ReceiveMessageContext(ctx)
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if message != nil {
+		t.Errorf("expected no message, got %v", message)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("expected the long poll to return promptly, took %s", elapsed)
+	}
+}
+
+func TestManagementCallsUseContext(t *testing.T) {
+	client := &contextClient{Client: memqueue.NewClient()}
+	q, err := queue.New("managed", queue.WithClient(client), queue.WithoutDeadLetterQueue(), queue.WithTags(map[string]string{"team": "core"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.contexts = nil
+	ctx := context.WithValue(context.Background(), contextKey("request"), "value")
+	if err := q.InitContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.SetAttributesContext(ctx, map[string]string{sqs.QueueAttributeNameDelaySeconds: "5"}); err != nil {
+		t.Fatal(err)
+	}
+	tags, err := q.TagsContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tags["team"] != "core" {
+		t.Errorf("expected the configured tags, got %v", tags)
+	}
+	if err := q.PurgeContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.DeleteContext(ctx, false); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(client.contexts) != 5 {
+		t.Fatalf("expected 5 context-aware calls, got %d", len(client.contexts))
+	}
+	for i, callCtx := range client.contexts {
+		if callCtx.Value(contextKey("request")) != "value" {
+			t.Errorf("call %d did not get the caller's context", i)
+		}
+	}
+}
+
+func TestOperationsUseInjectedClient(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("injected", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.URL != fakeQueueURL("injected") {
+		t.Errorf("expected the URL of the fake client, got %s", q.URL)
+	}
+	if len(client.createQueueInputs) != 2 || *client.createQueueInputs[0].QueueName != "injected-deadMessages" || *client.createQueueInputs[1].QueueName != "injected" {
+		t.Fatalf("expected the dead letter queue and the queue to be created, got %v", client.createQueueInputs)
+	}
+	if len(client.getAttributesInputs) != 1 || *client.getAttributesInputs[0].QueueUrl != fakeQueueURL("injected-deadMessages") {
+		t.Fatalf("expected the dead letter queue ARN to be fetched, got %v", client.getAttributesInputs)
+	}
+
+	if _, err := q.SendMessage(map[string]string{"hello": "world"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sendInputs) != 1 || *client.sendInputs[0].QueueUrl != q.URL {
+		t.Fatalf("expected one message sent to the queue, got %v", client.sendInputs)
+	}
+
+	message, err := q.ReceiveMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message == nil || *message.Body != `{"hello":"world"}` {
+		t.Fatalf("expected the sent message, got %v", message)
+	}
+
+	if _, err := q.DeleteMessage(message); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.deleteInputs) != 1 || *client.deleteInputs[0].ReceiptHandle != *message.ReceiptHandle {
+		t.Fatalf("expected the received message to be deleted, got %v", client.deleteInputs)
+	}
+
+	resp, err := q.GetAttributesByQueueURL(q.URL, []*string{aws.String(sqs.QueueAttributeNameQueueArn)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *resp.Attributes[sqs.QueueAttributeNameQueueArn] != "arn:aws:sqs:us-east-1:000000000000:injected" {
+		t.Errorf("expected the ARN of the fake client, got %v", resp.Attributes)
+	}
+}
+
+func TestGetClientCachedConcurrently(t *testing.T) {
+	q := &queue.Queue{Name: "cached", Region: "eu-west-1"}
+
+	clients := make(chan sqsiface.SQSAPI, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(clients); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clients <- q.GetClient()
+		}()
+	}
+	wg.Wait()
+	close(clients)
+
+	first := q.GetClient()
+	for client := range clients {
+		if client != first {
+			t.Fatal("expected every call to return the same client")
+		}
+	}
+}
+
+// BenchmarkGetClientPerCall measures building a new client for every call, like before the client was cached.
+func BenchmarkGetClientPerCall(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		q := &queue.Queue{Name: "bench", Region: "eu-west-1"}
+		q.GetClient()
+	}
+}
+
+// BenchmarkGetClientCached measures reusing the client of the queue.
+func BenchmarkGetClientCached(b *testing.B) {
+	q := &queue.Queue{Name: "bench", Region: "eu-west-1"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.GetClient()
+	}
+}
+
+// benchClient answers the calls of the benchmarks without recording them, every receive returns the same full batch.
+type benchClient struct {
+	*fakeClient
+	batch []*sqs.Message
+}
+
+func newBenchClient() *benchClient {
+	client := &benchClient{fakeClient: newFakeClient()}
+	for i := 0; i < queue.MaxBatchSize; i++ {
+		id := fmt.Sprintf("message-%d", i)
+		client.batch = append(client.batch, &sqs.Message{
+			MessageId:     aws.String(id),
+			ReceiptHandle: aws.String("receipt-" + id),
+			Body:          aws.String(`{"name":"bench","count":1}`),
+		})
+	}
+	return client
+}
+
+func (client *benchClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	return &sqs.SendMessageOutput{MessageId: aws.String("message")}, nil
+}
+
+func (client *benchClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	return &sqs.ReceiveMessageOutput{Messages: client.batch[:aws.Int64Value(input.MaxNumberOfMessages)]}, nil
+}
+
+func (client *benchClient) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+// BenchmarkSendMessage measures marshalling and sending a message with a cached client.
+func BenchmarkSendMessage(b *testing.B) {
+	q, err := queue.New("bench", queue.WithClient(newBenchClient()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	body := struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}{Name: "bench", Count: 1}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := q.SendMessage(body); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkProcessLoop measures receiving, decoding, handling and deleting b.N messages.
+func BenchmarkProcessLoop(b *testing.B) {
+	q, err := queue.New("bench", queue.WithClient(newBenchClient()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	processor := &queue.Processor{
+		Queue:       q,
+		Concurrency: queue.MaxBatchSize,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if _, err := processor.ProcessN(context.Background(), b.N); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func TestSendMessageDelayed(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("delayed", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, seconds := range []int64{0, 900} {
+		if _, err := q.SendMessageDelayed("body", time.Duration(seconds)*time.Second); err != nil {
+			t.Fatalf("expected a delay of %d seconds to be sent, got %v", seconds, err)
+		}
+		input := client.sendInputs[len(client.sendInputs)-1]
+		if aws.Int64Value(input.DelaySeconds) != seconds {
+			t.Errorf("expected DelaySeconds %d, got %v", seconds, input.DelaySeconds)
+		}
+	}
+
+	if _, err := q.SendMessageDelayed("body", 901*time.Second); err != queue.ErrInvalidDelaySeconds {
+		t.Errorf("expected ErrInvalidDelaySeconds for 901 seconds, got %v", err)
+	}
+	if _, err := q.SendMessageDelayed("body", -time.Second); err != queue.ErrInvalidDelaySeconds {
+		t.Errorf("expected ErrInvalidDelaySeconds for a negative delay, got %v", err)
+	}
+	if len(client.sendInputs) != 2 {
+		t.Errorf("expected the invalid delays not to be sent, got %d sends", len(client.sendInputs))
+	}
+}
+
+func TestSendRawMessage(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("raw", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendRawMessage(`{"already":"json"}`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendRawMessageBytes([]byte("<command>restart</command>")); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := aws.StringValue(client.sendInputs[0].MessageBody); body != `{"already":"json"}` {
+		t.Errorf("expected the JSON string without extra quoting, got %s", body)
+	}
+	if body := aws.StringValue(client.sendInputs[1].MessageBody); body != "<command>restart</command>" {
+		t.Errorf("expected the bytes untouched, got %s", body)
+	}
+	if _, err := q.SendMessage(`{"already":"json"}`); err != nil {
+		t.Fatal(err)
+	}
+	if body := aws.StringValue(client.sendInputs[2].MessageBody); body != `"{\"already\":\"json\"}"` {
+		t.Errorf("expected SendMessage to encode the string, got %s", body)
+	}
+}
+
+func TestSendMessageTooLarge(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("large", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := strings.Repeat("a", 300*1024)
+
+	_, err = q.SendRawMessage(body)
+	var tooLarge *queue.MessageTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, queue.ErrMessageTooLarge) {
+		t.Fatalf("expected a *MessageTooLargeError, got %v", err)
+	}
+	if tooLarge.Size != len(body) {
+		t.Errorf("expected the size %d, got %d", len(body), tooLarge.Size)
+	}
+	if _, err := q.SendMessage(body); !errors.Is(err, queue.ErrMessageTooLarge) {
+		t.Errorf("expected ErrMessageTooLarge from SendMessage, got %v", err)
+	}
+
+	// The attributes count toward the limit too.
+	attributes := map[string]*sqs.MessageAttributeValue{"padding": queue.BinaryAttribute(make([]byte, queue.MaxMessageSize))}
+	if _, err := q.SendMessageWithAttributeValues("small", attributes); !errors.Is(err, queue.ErrMessageTooLarge) {
+		t.Errorf("expected ErrMessageTooLarge with large attributes, got %v", err)
+	}
+	if len(client.sendInputs) != 0 {
+		t.Errorf("expected no SDK call, got %d sends", len(client.sendInputs))
+	}
+}
+
+func TestReceiveMessages(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("receive", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages, err := q.ReceiveMessages(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("expected no messages from an empty queue, got %d", len(messages))
+	}
+
+	for i := 0; i < 12; i++ {
+		q.SendMessage(i)
+	}
+	messages, err = q.ReceiveMessages(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 10 {
+		t.Errorf("expected 10 messages, got %d", len(messages))
+	}
+	if max := aws.Int64Value(client.receiveInputs[1].MaxNumberOfMessages); max != 10 {
+		t.Errorf("expected MaxNumberOfMessages 10, got %d", max)
+	}
+	if len(client.receiveInputs) != 2 {
+		t.Errorf("expected a single receive call for each, got %d", len(client.receiveInputs))
+	}
+
+	for _, max := range []int64{0, 11} {
+		if _, err := q.ReceiveMessages(max); err != queue.ErrInvalidMaxMessages {
+			t.Errorf("expected ErrInvalidMaxMessages for %d, got %v", max, err)
+		}
+	}
+	if len(client.receiveInputs) != 2 {
+		t.Errorf("expected no receive call for the invalid max, got %d", len(client.receiveInputs))
+	}
+}
+
+func TestGetAccountID(t *testing.T) {
+	tests := []struct {
+		url       string
+		accountID string
+		err       error
+	}{
+		{url: "https://sqs.us-east-1.amazonaws.com/123456789012/orders", accountID: "123456789012"},
+		{url: "https://sqs.eu-west-1.amazonaws.com/123456789012/orders.fifo", accountID: "123456789012"},
+		{url: "", err: queue.ErrInvalidQueueURL},
+		{url: "https://sqs.us-east-1.amazonaws.com/orders", err: queue.ErrInvalidQueueURL},
+		{url: "https://sqs.us-east-1.amazonaws.com/123456789012/orders/extra", err: queue.ErrInvalidQueueURL},
+		{url: "://sqs.us-east-1.amazonaws.com/123456789012/orders", err: queue.ErrInvalidQueueURL},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			accountID, err := (&queue.Queue{URL: test.url}).GetAccountID()
+			if accountID != test.accountID || err != test.err {
+				t.Errorf("expected %q and %v, got %q and %v", test.accountID, test.err, accountID, err)
+			}
+		})
+	}
+}
+
+func TestGetQueueRegion(t *testing.T) {
+	tests := []struct {
+		url    string
+		region string
+		err    error
+	}{
+		{url: "https://sqs.us-east-1.amazonaws.com/123456789012/orders", region: "us-east-1"},
+		{url: "https://sqs.eu-west-1.amazonaws.com/123456789012/orders.fifo", region: "eu-west-1"},
+		{url: "https://ap-south-1.queue.amazonaws.com/123456789012/orders", region: "ap-south-1"},
+		{url: "", err: queue.ErrInvalidQueueURL},
+		{url: "https://localhost:9324/123456789012/orders", err: queue.ErrInvalidQueueURL},
+		{url: "://sqs.us-east-1.amazonaws.com/123456789012/orders", err: queue.ErrInvalidQueueURL},
+	}
+	for _, test := range tests {
+		t.Run(test.url, func(t *testing.T) {
+			region, err := (&queue.Queue{URL: test.url}).GetQueueRegion()
+			if region != test.region || err != test.err {
+				t.Errorf("expected %q and %v, got %q and %v", test.region, test.err, region, err)
+			}
+		})
+	}
+}
+
+func TestGetQueueRegionCached(t *testing.T) {
+	q := &queue.Queue{URL: "https://sqs.us-west-2.amazonaws.com/123456789012/orders"}
+	if _, err := q.GetQueueRegion(); err != nil {
+		t.Fatal(err)
+	}
+
+	q.URL = "https://localhost:9324/123456789012/orders"
+	if region, err := q.GetQueueRegion(); region != "us-west-2" || err != nil {
+		t.Errorf("expected the cached region, got %q and %v", region, err)
+	}
+}
+
+func TestNewFIFOWithHighThroughput(t *testing.T) {
+	for _, contentBasedDeduplication := range []bool{true, false} {
+		client := newFakeClient()
+		if _, err := queue.NewFIFOWithHighThroughput("orders.fifo", contentBasedDeduplication, queue.WithClient(client)); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"orders-deadMessages.fifo", "orders.fifo"} {
+			attributes := createdAttributes(t, client, name)
+			if attributes["FifoQueue"] != "true" || attributes["DeduplicationScope"] != "messageGroup" || attributes["FifoThroughputLimit"] != "perMessageGroupId" {
+				t.Errorf("expected %s to be a FIFO queue in high throughput mode, got %v", name, attributes)
+			}
+			if _, ok := attributes["ContentBasedDeduplication"]; ok != contentBasedDeduplication {
+				t.Errorf("expected content based deduplication of %s to be %t, got %v", name, contentBasedDeduplication, attributes)
+			}
+		}
+	}
+}
+
+func TestNewFIFOWithHighThroughputRequiresSuffix(t *testing.T) {
+	client := newFakeClient()
+	if _, err := queue.NewFIFOWithHighThroughput("orders", true, queue.WithClient(client)); err != queue.ErrFIFOSuffixRequired {
+		t.Errorf("expected ErrFIFOSuffixRequired, got %v", err)
+	}
+	if len(client.createQueueInputs) != 0 {
+		t.Errorf("expected no queue to be created, got %v", client.createQueueInputs)
+	}
+}
@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"golang.org/x/time/rate"
+)
+
+// WithRateLimit limits the handled messages to perSecond on average, allowing bursts of burst messages.
+// The limit is shared by all workers and charged for each received message, also in the batch modes.
+// Receiving is deferred until tokens are available and receives no more messages than there are tokens,
+// so the processor doesn't hold messages while waiting and their visibility timeout doesn't run out.
+func (processor *Processor) WithRateLimit(perSecond float64, burst int) *Processor {
+	if burst < 1 {
+		burst = 1
+	}
+	processor.rateLimiter = rate.NewLimiter(rate.Limit(perSecond), burst)
+
+	return processor
+}
+
+// waitForRate waits until at least one message may be handled and returns how many may be received, up to max.
+// The tokens are not spent, the received messages are charged with chargeRate. Without a rate limit it returns max.
+// It returns 0 when the context is done first.
+func (processor *Processor) waitForRate(ctx context.Context, max int) (allowed int) {
+	if processor.rateLimiter == nil {
+		return max
+	}
+
+	for {
+		tokens := processor.rateLimiter.Tokens()
+		if tokens >= 1 {
+			allowed = int(tokens)
+			if allowed > max {
+				allowed = max
+			}
+			return
+		}
+
+		wait := time.Second
+		if limit := processor.rateLimiter.Limit(); limit > 0 {
+			wait = time.Duration((1 - tokens) / float64(limit) * float64(time.Second))
+		}
+		if aws.SleepWithContext(ctx, wait) != nil {
+			return 0
+		}
+	}
+}
+
+// availableRate returns how many more messages may be received right away, over the reserved ones.
+// Without a rate limit it returns max.
+func (processor *Processor) availableRate(reserved int, max int) (available int) {
+	if processor.rateLimiter == nil {
+		return max
+	}
+
+	available = int(processor.rateLimiter.Tokens()) - reserved
+	if available < 0 {
+		return 0
+	}
+	if available > max {
+		return max
+	}
+
+	return
+}
+
+// chargeRate spends a token for each of the n received messages, waiting while there are not enough of them.
+// It returns the error of the context when it is done first.
+func (processor *Processor) chargeRate(ctx context.Context, n int) error {
+	if processor.rateLimiter == nil {
+		return nil
+	}
+
+	for i := 0; i < n; i++ {
+		if err := processor.rateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
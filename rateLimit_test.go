@@ -0,0 +1,104 @@
+package queue_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Rate and burst of the rate limit tests, with the tolerance of their timing.
+const (
+	testRate      = 20
+	testBurst     = 2
+	rateTolerance = 15 * time.Millisecond
+)
+
+// checkRate fails the test when a call came earlier than the rate limit allows after the start.
+func checkRate(t *testing.T, start time.Time, calls []time.Time) {
+	t.Helper()
+
+	interval := time.Second / testRate
+	for i, call := range calls {
+		earliest := time.Duration(i-testBurst+1) * interval
+		if elapsed := call.Sub(start); elapsed < earliest-rateTolerance {
+			t.Errorf("expected call %d after %s, got it after %s", i, earliest, elapsed)
+		}
+	}
+}
+
+func TestRateLimitPerMessageWithBatchSize(t *testing.T) {
+	q, err := memqueue.New("limited", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const messages = 6
+	for i := 0; i < messages; i++ {
+		q.SendMessage(i)
+	}
+
+	var mutex sync.Mutex
+	var calls []time.Time
+	processor := (&queue.Processor{
+		Queue:       q,
+		Concurrency: 4,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			calls = append(calls, time.Now())
+			return nil
+		},
+	}).WithBatchSize(10).WithRateLimit(testRate, testBurst).WithMaxMessages(messages)
+
+	start := time.Now()
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(calls) != messages {
+		t.Fatalf("expected %d handled messages, got %d", messages, len(calls))
+	}
+	checkRate(t, start, calls)
+}
+
+func TestRateLimitPerMessageInDecodedBatches(t *testing.T) {
+	q, err := memqueue.New("limited", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const messages = 6
+	for i := 0; i < messages; i++ {
+		q.SendMessage(i)
+	}
+
+	var calls []time.Time
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleDecodedBatch: func(ctx context.Context, batch []queue.DecodedMessage) ([]queue.Failed, error) {
+			for range batch {
+				calls = append(calls, time.Now())
+			}
+			return nil, nil
+		},
+	}).WithRateLimit(testRate, testBurst)
+
+	start := time.Now()
+	summary, err := processor.ProcessN(context.Background(), messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != messages {
+		t.Fatalf("expected %d processed messages, got %+v", messages, summary)
+	}
+	// The messages of a batch are handled at once, after all of them were charged.
+	if elapsed := calls[len(calls)-1].Sub(start); elapsed < (messages-testBurst)*time.Second/testRate-rateTolerance {
+		t.Errorf("expected the batches to be limited per message, took %s", elapsed)
+	}
+}
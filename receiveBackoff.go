@@ -0,0 +1,57 @@
+package queue
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Default backoff after consecutive receive errors.
+const (
+	defaultReceiveBackoff    = time.Second
+	defaultMaxReceiveBackoff = 60 * time.Second
+)
+
+// WithReceiveBackoff sets the wait after consecutive receive errors, it starts at base and doubles up to max.
+// The wait is reset by the first successful receive, empty receives don't back off.
+func (processor *Processor) WithReceiveBackoff(base time.Duration, max time.Duration) *Processor {
+	processor.receiveBackoff = base
+	processor.maxReceiveBackoff = max
+
+	return processor
+}
+
+// getReceiveBackoff returns the wait after the given number of consecutive receive errors, without the jitter.
+func (processor *Processor) getReceiveBackoff(failures int) time.Duration {
+	backoff, max := processor.receiveBackoff, processor.maxReceiveBackoff
+	if backoff <= 0 {
+		backoff = defaultReceiveBackoff
+	}
+	if max <= 0 {
+		max = defaultMaxReceiveBackoff
+	}
+
+	for i := 1; i < failures && backoff < max; i++ {
+		backoff *= 2
+	}
+	if backoff > max {
+		backoff = max
+	}
+
+	return backoff
+}
+
+// backOffReceiving waits after a receive error, with a random jitter of up to half of the backoff.
+func (processor *Processor) backOffReceiving(ctx context.Context, failures int) {
+	backoff := processor.getReceiveBackoff(failures)
+	backoff = backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+
+	processor.getLogger().Warn("Backing off receiving from queue", Fields{
+		"queueName": processor.Queue.Name,
+		"failures":  failures,
+		"backoff":   backoff,
+	})
+	aws.SleepWithContext(ctx, backoff)
+}
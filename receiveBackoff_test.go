@@ -0,0 +1,82 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// scheduledReceiveClient fails the receives scheduled to fail and records the time of each,
+// the processor is stopped after the schedule.
+type scheduledReceiveClient struct {
+	*fakeClient
+	mutex    sync.Mutex
+	failures []bool
+	calls    []time.Time
+	stop     func()
+}
+
+func (client *scheduledReceiveClient) ReceiveMessageWithContext(ctx aws.Context, input *sqs.ReceiveMessageInput, opts ...request.Option) (*sqs.ReceiveMessageOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	call := len(client.calls)
+	client.calls = append(client.calls, time.Now())
+	if call >= len(client.failures) {
+		client.stop()
+		return &sqs.ReceiveMessageOutput{}, nil
+	}
+	if client.failures[call] {
+		return nil, errors.New("receive failed")
+	}
+	return &sqs.ReceiveMessageOutput{}, nil
+}
+
+func TestReceiveBackoffGrowsAndResets(t *testing.T) {
+	client := &scheduledReceiveClient{
+		fakeClient: newFakeClient(),
+		// Three errors, an empty receive resetting the backoff, then one more error.
+		failures: []bool{true, true, true, false, true},
+	}
+	q, err := queue.New("failing", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}).WithReceiveBackoff(40*time.Millisecond, 160*time.Millisecond)
+	client.stop = processor.Stop
+
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if len(client.calls) != 6 {
+		t.Fatalf("expected 6 receives, got %d", len(client.calls))
+	}
+	gap := func(i int) time.Duration { return client.calls[i].Sub(client.calls[i-1]) }
+	// The jitter waits between half and all of the backoff.
+	for i, minimum := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond} {
+		if wait := gap(i + 1); wait < minimum {
+			t.Errorf("expected the wait after error %d to be at least %s, got %s", i+1, minimum, wait)
+		}
+	}
+	if wait := gap(4); wait >= 20*time.Millisecond {
+		t.Errorf("expected no backoff after the successful receive, got %s", wait)
+	}
+	if wait := gap(5); wait < 20*time.Millisecond || wait >= 80*time.Millisecond {
+		t.Errorf("expected the backoff to start over after the successful receive, got %s", wait)
+	}
+}
@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// WithReceiveJitter waits a random time of up to max before each receive of ProcessWithContext,
+// so replicas started together don't poll SQS in lockstep. There is no jitter by default.
+func (processor *Processor) WithReceiveJitter(max time.Duration) *Processor {
+	processor.receiveJitter = max
+
+	return processor
+}
+
+// WithEmptyReceivePause waits for pause after each receive of ProcessWithContext returning no messages,
+// so short polling queues, with a wait time of 0, don't spin. There is no pause by default, long polling waits already.
+func (processor *Processor) WithEmptyReceivePause(pause time.Duration) *Processor {
+	processor.emptyReceivePause = pause
+
+	return processor
+}
+
+// jitterReceive waits a random time of up to the receive jitter.
+func (processor *Processor) jitterReceive(ctx context.Context) {
+	if processor.receiveJitter <= 0 {
+		return
+	}
+
+	aws.SleepWithContext(ctx, time.Duration(rand.Int63n(int64(processor.receiveJitter)+1)))
+}
+
+// pauseAfterEmptyReceive waits for the empty receive pause.
+func (processor *Processor) pauseAfterEmptyReceive(ctx context.Context) {
+	if processor.emptyReceivePause <= 0 {
+		return
+	}
+
+	aws.SleepWithContext(ctx, processor.emptyReceivePause)
+}
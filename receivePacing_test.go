@@ -0,0 +1,73 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Tolerance of the timing of the receives.
+const pacingTolerance = 15 * time.Millisecond
+
+// pacedProcessor returns a processor of the client stopping it after it's schedule of empty receives.
+func pacedProcessor(t *testing.T, client *scheduledReceiveClient) *queue.Processor {
+	t.Helper()
+
+	q, err := queue.New("paced", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return nil
+		},
+	}
+	client.stop = processor.Stop
+
+	return processor
+}
+
+func TestEmptyReceivePause(t *testing.T) {
+	client := &scheduledReceiveClient{fakeClient: newFakeClient(), failures: make([]bool, 3)}
+	processor := pacedProcessor(t, client).WithEmptyReceivePause(30 * time.Millisecond)
+
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if len(client.calls) != 4 {
+		t.Fatalf("expected 4 receives, got %d", len(client.calls))
+	}
+	for i := 1; i < len(client.calls); i++ {
+		if wait := client.calls[i].Sub(client.calls[i-1]); wait < 30*time.Millisecond {
+			t.Errorf("expected a pause of at least 30ms after empty receive %d, got %s", i, wait)
+		}
+	}
+}
+
+func TestReceiveJitterWithinBound(t *testing.T) {
+	client := &scheduledReceiveClient{fakeClient: newFakeClient(), failures: make([]bool, 10)}
+	processor := pacedProcessor(t, client).WithReceiveJitter(20 * time.Millisecond)
+
+	if err := processor.ProcessWithContext(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if len(client.calls) != 11 {
+		t.Fatalf("expected 11 receives, got %d", len(client.calls))
+	}
+	// Without an empty receive pause, the jitter is the only wait between the receives.
+	for i := 1; i < len(client.calls); i++ {
+		if wait := client.calls[i].Sub(client.calls[i-1]); wait > 20*time.Millisecond+pacingTolerance {
+			t.Errorf("expected a jitter of at most 20ms before receive %d, got %s", i, wait)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// RedriveOptions select the dead letter messages moved by RedriveDeadLetters.
+type RedriveOptions struct {
+	// MaxMessages is the maximum number of messages moved, all of them when not positive.
+	MaxMessages int
+	// Filter selects the messages to move, all of them when nil.
+	// Skipped messages stay invisible in the dead letter queue until their visibility timeout runs out.
+	Filter func(message *sqs.Message) bool
+}
+
+// RedriveResult counts the messages handled by RedriveDeadLetters.
+type RedriveResult struct {
+	Moved   int
+	Failed  int
+	Skipped int
+}
+
+// RedriveDeadLetters moves the messages of the dead letter queue back to the queue with their body and attributes,
+// until the dead letter queue is empty. A message is deleted from the dead letter queue only after it was sent.
+// ErrNoDeadLetterQueue is returned when the queue has none.
+func (queue *Queue) RedriveDeadLetters(ctx context.Context, opts RedriveOptions) (result RedriveResult, err error) {
+	if queue.DeadLetterQueueURL == "" {
+		return result, ErrNoDeadLetterQueue
+	}
+
+	deadLetterQueue := queue.getDeadLetterQueue()
+	for opts.MaxMessages <= 0 || result.Moved < opts.MaxMessages {
+		receiveSize := MaxBatchSize
+		if remaining := opts.MaxMessages - result.Moved; opts.MaxMessages > 0 && remaining < receiveSize {
+			receiveSize = remaining
+		}
+
+		messages, err := deadLetterQueue.ReceiveMessagesContext(ctx, int64(receiveSize))
+		if err != nil {
+			return result, err
+		}
+		if len(messages) == 0 {
+			return result, nil
+		}
+
+		for _, message := range messages {
+			if opts.Filter != nil && !opts.Filter(message) {
+				result.Skipped++
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			if _, err := queue.sendMessageInput(ctx, queue.resendInput(message, message.MessageAttributes)); err != nil {
+				result.Failed++
+				continue
+			}
+			// The message is deleted without it's S3 payload, the redriven message refers to it.
+			if _, err := deadLetterQueue.deleteMessageByReceiptHandle(ctx, message.ReceiptHandle); err != nil {
+				queue.GetLogger().Warn("Message redriven but not deleted from dead letter queue", Fields{
+					"queueName": queue.Name,
+					"messageID": message.MessageId,
+					"error":     err,
+				})
+			}
+			result.Moved++
+		}
+	}
+
+	return
+}
@@ -0,0 +1,133 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// redriveClient records the sends and deletes in order, failing the sends while failSends is set.
+type redriveClient struct {
+	*memqueue.Client
+	mutex     sync.Mutex
+	calls     []string
+	failSends bool
+}
+
+func (client *redriveClient) record(call string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.calls = append(client.calls, call)
+}
+
+func (client *redriveClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	if client.failSends {
+		client.record("failed send")
+		return nil, errors.New("send failed")
+	}
+	client.record("send")
+	return client.Client.SendMessageWithContext(ctx, input, opts...)
+}
+
+func (client *redriveClient) DeleteMessageWithContext(ctx aws.Context, input *sqs.DeleteMessageInput, opts ...request.Option) (*sqs.DeleteMessageOutput, error) {
+	client.record("delete")
+	return client.Client.DeleteMessageWithContext(ctx, input, opts...)
+}
+
+func TestRedriveDeadLetters(t *testing.T) {
+	client := &redriveClient{Client: memqueue.NewClient()}
+	q, err := queue.New("redriven",
+		queue.WithClient(client),
+		queue.WithReceiveWaitTime(0),
+		queue.WithReceiveVisibilityTimeout(0),
+		queue.WithMaxReceiveCount(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"first", "second"} {
+		if _, err := q.SendMessage(body); err != nil {
+			t.Fatal(err)
+		}
+		deadLetter(t, q)
+	}
+	client.calls = nil
+
+	result, err := q.RedriveDeadLetters(context.Background(), queue.RedriveOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != (queue.RedriveResult{Moved: 2}) {
+		t.Errorf("expected 2 moved messages, got %+v", result)
+	}
+	if !reflect.DeepEqual(client.calls, []string{"send", "delete", "send", "delete"}) {
+		t.Errorf("expected each message to be deleted after it was sent, got %v", client.calls)
+	}
+	if remaining := client.Messages(q.DeadLetterQueueURL); len(remaining) != 0 {
+		t.Errorf("expected the dead letter queue to be empty, got %v", remaining)
+	}
+	if bodies := client.Messages(q.URL); !reflect.DeepEqual(bodies, []string{`"first"`, `"second"`}) {
+		t.Errorf("expected the messages back in the queue, got %v", bodies)
+	}
+}
+
+func TestRedriveDeadLettersKeepsUnsent(t *testing.T) {
+	client := &redriveClient{Client: memqueue.NewClient()}
+	q, err := queue.New("redriven",
+		queue.WithClient(client),
+		queue.WithReceiveWaitTime(0),
+		queue.WithReceiveVisibilityTimeout(0),
+		queue.WithMaxReceiveCount(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage("body"); err != nil {
+		t.Fatal(err)
+	}
+	deadLetter(t, q)
+	// The failed message has to stay invisible, or the redrive receives it again.
+	redriven, err := queue.New("redriven", queue.WithClient(client), queue.WithReceiveWaitTime(0), queue.WithMaxReceiveCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.calls = nil
+	client.failSends = true
+
+	result, err := redriven.RedriveDeadLetters(context.Background(), queue.RedriveOptions{MaxMessages: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result != (queue.RedriveResult{Failed: 1}) {
+		t.Errorf("expected the send to fail, got %+v", result)
+	}
+	for _, call := range client.calls {
+		if call == "delete" {
+			t.Fatalf("expected no delete without a successful send, got %v", client.calls)
+		}
+	}
+	if remaining := client.Messages(q.DeadLetterQueueURL); len(remaining) != 1 {
+		t.Errorf("expected the message to stay in the dead letter queue, got %v", remaining)
+	}
+}
+
+func TestRedriveWithoutDeadLetterQueue(t *testing.T) {
+	q, err := memqueue.New("standalone", queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.RedriveDeadLetters(context.Background(), queue.RedriveOptions{}); err != queue.ErrNoDeadLetterQueue {
+		t.Errorf("expected ErrNoDeadLetterQueue, got %v", err)
+	}
+}
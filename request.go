@@ -0,0 +1,338 @@
+package queue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Message attributes of requests and replies.
+const (
+	ReplyToAttribute       = "ReplyTo"
+	CorrelationIDAttribute = "CorrelationId"
+)
+
+// Suffix of the reply queue names, before the random process ID.
+const replyQueueSuffix = "-reply-"
+
+// Time after the last request when the reply queue is deleted, unless set with WithReplyQueueIdleTimeout.
+const defaultReplyQueueIdleTimeout = 10 * time.Minute
+
+// ErrRequestTimeout is returned by Request when no reply arrived in time.
+var ErrRequestTimeout = errors.New("no reply to the request within the timeout")
+
+// ErrNoReplyTo is returned by Reply for messages that are not requests.
+var ErrNoReplyTo = errors.New("message has no reply queue")
+
+// ErrReplyToNotAllowed is returned by Reply when the reply queue is in another account or region than the queue,
+// and was not allowed with WithAllowedReplyQueues.
+var ErrReplyToNotAllowed = errors.New("reply queue is not allowed")
+
+// WithAllowedReplyQueues allows Reply to send to the queue URLs starting with one of the prefixes,
+// besides the queues in the account and region of the queue.
+func WithAllowedReplyQueues(prefixes ...string) Option {
+	return func(queue *Queue) error {
+		queue.allowedReplyQueues = append(queue.allowedReplyQueues, prefixes...)
+		return nil
+	}
+}
+
+// WithReplyQueueIdleTimeout sets how long the reply queue of Request is kept after the last request, 10 minutes by default.
+// 0 keeps it until CloseReplyQueue.
+func WithReplyQueueIdleTimeout(timeout time.Duration) Option {
+	return func(queue *Queue) error {
+		queue.replyQueueIdleTimeout = &timeout
+		return nil
+	}
+}
+
+// replyListener receives the replies of a process and passes them to the waiting requests.
+type replyListener struct {
+	owner  *Queue
+	queue  *Queue
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mutex    sync.Mutex
+	pending  map[string]chan *sqs.Message
+	lastUsed time.Time
+}
+
+// Request sends the body with a reply queue and a correlation ID, then waits for the matching reply.
+// The reply queue is created on the first request and shared by the concurrent requests of the queue.
+// It is deleted when no request was made for the idle timeout of WithReplyQueueIdleTimeout, or by CloseReplyQueue,
+// which should be called on shutdown. SQS deletes the reply queues of crashed processes after 30 days of inactivity.
+func (queue *Queue) Request(ctx context.Context, body interface{}, timeout time.Duration) (*sqs.Message, error) {
+	msg, err := queue.marshalMessageBody(body)
+	if err != nil {
+		return nil, err
+	}
+	correlationID, err := newCorrelationID()
+	if err != nil {
+		return nil, err
+	}
+
+	replies, reply, err := queue.registerRequest(ctx, correlationID)
+	if err != nil {
+		return nil, err
+	}
+	defer replies.unregister(correlationID)
+
+	attributes := map[string]*sqs.MessageAttributeValue{
+		ReplyToAttribute:       StringAttribute(replies.queue.URL),
+		CorrelationIDAttribute: StringAttribute(correlationID),
+	}
+	if _, err := queue.sendRawMessage(ctx, msg, attributes); err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case message := <-reply:
+		return message, nil
+	case <-timer.C:
+		return nil, ErrRequestTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Reply sends the body to the reply queue of the request with it's correlation ID.
+// ErrNoReplyTo is returned when the message is not a request, ErrReplyToNotAllowed when the reply queue
+// is in another account or region and not allowed with WithAllowedReplyQueues.
+func (queue *Queue) Reply(request *sqs.Message, body interface{}) error {
+	replyTo, ok := GetStringAttribute(request, ReplyToAttribute)
+	if !ok || replyTo == "" {
+		return ErrNoReplyTo
+	}
+	if !queue.replyAllowed(replyTo) {
+		queue.GetLogger().Warn("Rejecting the reply queue of a request", Fields{
+			"queueName": queue.Name,
+			"replyTo":   replyTo,
+			"messageID": request.MessageId,
+		})
+		return ErrReplyToNotAllowed
+	}
+	correlationID, _ := GetStringAttribute(request, CorrelationIDAttribute)
+
+	replyQueue := &Queue{
+		Name:       queue.Name + replyQueueSuffix,
+		URL:        replyTo,
+		Client:     queue.GetClient(),
+		Logger:     queue.Logger,
+		Marshaller: queue.Marshaller,
+	}
+	msg, err := replyQueue.marshalMessageBody(body)
+	if err != nil {
+		return err
+	}
+	_, err = replyQueue.sendRawMessage(aws.BackgroundContext(), msg, map[string]*sqs.MessageAttributeValue{
+		CorrelationIDAttribute: StringAttribute(correlationID),
+	})
+
+	return err
+}
+
+// CloseReplyQueue stops receiving replies and deletes the reply queue created by Request.
+func (queue *Queue) CloseReplyQueue() error {
+	queue.replyMutex.Lock()
+	replies := queue.replies
+	queue.replies = nil
+	queue.replyMutex.Unlock()
+	if replies == nil {
+		return nil
+	}
+
+	replies.cancel()
+	<-replies.done
+
+	return replies.queue.deleteQueueByURL(aws.BackgroundContext(), replies.queue.URL)
+}
+
+// replyAllowed reports whether the reply queue URL is in the account and region of the queue, or allowed explicitly.
+func (queue *Queue) replyAllowed(replyTo string) bool {
+	for _, prefix := range queue.allowedReplyQueues {
+		if strings.HasPrefix(replyTo, prefix) {
+			return true
+		}
+	}
+
+	own, err := url.Parse(queue.URL)
+	if err != nil {
+		return false
+	}
+	reply, err := url.Parse(replyTo)
+	if err != nil {
+		return false
+	}
+	ownPath := strings.Split(strings.Trim(own.Path, "/"), "/")
+	replyPath := strings.Split(strings.Trim(reply.Path, "/"), "/")
+
+	// Queue URLs are scheme://host/account/name, the host holds the region.
+	return reply.Scheme == own.Scheme && reply.Host == own.Host && reply.User == nil && reply.RawQuery == "" &&
+		len(ownPath) == 2 && len(replyPath) == 2 && replyPath[0] == ownPath[0] && replyPath[1] != ""
+}
+
+// registerRequest returns the reply listener of the queue with the channel receiving the reply with the correlation ID,
+// creating the reply queue on the first call, or after the previous one was deleted.
+func (queue *Queue) registerRequest(ctx context.Context, correlationID string) (*replyListener, chan *sqs.Message, error) {
+	queue.replyMutex.Lock()
+	defer queue.replyMutex.Unlock()
+
+	if queue.replies == nil {
+		if err := queue.startReplyListener(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return queue.replies, queue.replies.register(correlationID), nil
+}
+
+// startReplyListener creates the reply queue and starts receiving the replies, the reply mutex is held by the caller.
+func (queue *Queue) startReplyListener(ctx context.Context) error {
+	processID, err := newCorrelationID()
+	if err != nil {
+		return err
+	}
+	// The name of the queue is shortened to fit the suffix, so the owner of the reply queue stays recognizable.
+	base, suffix := strings.TrimSuffix(queue.Name, fifoSuffix), replyQueueSuffix+processID[:12]
+	if len(base)+len(suffix) > MaxQueueNameLength {
+		base = base[:MaxQueueNameLength-len(suffix)]
+	}
+	name := base + suffix
+	resp, err := queue.GetClient().CreateQueueWithContext(ctx, &sqs.CreateQueueInput{
+		QueueName: aws.String(name),
+	})
+	if err != nil {
+		return err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	queue.replies = &replyListener{
+		owner: queue,
+		queue: &Queue{
+			Name:       name,
+			URL:        aws.StringValue(resp.QueueUrl),
+			Client:     queue.GetClient(),
+			Logger:     queue.Logger,
+			Marshaller: queue.Marshaller,
+			waitTime:   queue.waitTime,
+		},
+		cancel:   cancel,
+		done:     make(chan struct{}),
+		pending:  make(map[string]chan *sqs.Message),
+		lastUsed: time.Now(),
+	}
+	go queue.replies.listen(listenCtx)
+
+	return nil
+}
+
+// getReplyQueueIdleTimeout returns the idle timeout of the reply queue, 0 when it is kept until CloseReplyQueue.
+func (queue *Queue) getReplyQueueIdleTimeout() time.Duration {
+	if queue.replyQueueIdleTimeout == nil {
+		return defaultReplyQueueIdleTimeout
+	}
+
+	return *queue.replyQueueIdleTimeout
+}
+
+// listen receives the replies until the context is cancelled, or the reply queue was idle for too long,
+// and passes them to the waiting requests. Replies nobody waits for anymore are deleted.
+func (replies *replyListener) listen(ctx context.Context) {
+	defer close(replies.done)
+
+	for ctx.Err() == nil {
+		if replies.owner.closeIdleReplyQueue(replies) {
+			return
+		}
+
+		messages, err := replies.queue.ReceiveMessagesContext(ctx, MaxBatchSize)
+		if err != nil {
+			aws.SleepWithContext(ctx, defaultReceiveBackoff)
+			continue
+		}
+
+		for _, message := range messages {
+			correlationID, _ := GetStringAttribute(message, CorrelationIDAttribute)
+			replies.deliver(correlationID, message)
+			replies.queue.deleteMessageByReceiptHandle(ctx, message.ReceiptHandle)
+		}
+	}
+}
+
+// closeIdleReplyQueue deletes the reply queue of the listener when no request waited for a reply within the idle timeout.
+// Later requests create a new reply queue.
+func (queue *Queue) closeIdleReplyQueue(replies *replyListener) bool {
+	timeout := queue.getReplyQueueIdleTimeout()
+	if timeout <= 0 {
+		return false
+	}
+
+	queue.replyMutex.Lock()
+	replies.mutex.Lock()
+	idle := queue.replies == replies && len(replies.pending) == 0 && time.Since(replies.lastUsed) >= timeout
+	replies.mutex.Unlock()
+	if idle {
+		queue.replies = nil
+	}
+	queue.replyMutex.Unlock()
+	if !idle {
+		return false
+	}
+
+	replies.queue.deleteQueueByURL(aws.BackgroundContext(), replies.queue.URL)
+
+	return true
+}
+
+// register returns the channel receiving the reply with the correlation ID.
+func (replies *replyListener) register(correlationID string) chan *sqs.Message {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+
+	reply := make(chan *sqs.Message, 1)
+	replies.pending[correlationID] = reply
+	replies.lastUsed = time.Now()
+
+	return reply
+}
+
+// unregister stops waiting for the reply with the correlation ID.
+func (replies *replyListener) unregister(correlationID string) {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+
+	delete(replies.pending, correlationID)
+	replies.lastUsed = time.Now()
+}
+
+// deliver passes the reply to the request waiting for it, if there is one.
+func (replies *replyListener) deliver(correlationID string, message *sqs.Message) {
+	replies.mutex.Lock()
+	defer replies.mutex.Unlock()
+
+	if reply, ok := replies.pending[correlationID]; ok {
+		reply <- message
+		delete(replies.pending, correlationID)
+	}
+}
+
+// newCorrelationID returns a random hex ID.
+func newCorrelationID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(id), nil
+}
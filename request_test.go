@@ -0,0 +1,203 @@
+package queue_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// deletingClient records the URLs of the deleted queues.
+type deletingClient struct {
+	*memqueue.Client
+	mutex   sync.Mutex
+	deleted []string
+}
+
+func (client *deletingClient) DeleteQueueWithContext(ctx aws.Context, input *sqs.DeleteQueueInput, opts ...request.Option) (*sqs.DeleteQueueOutput, error) {
+	client.mutex.Lock()
+	client.deleted = append(client.deleted, aws.StringValue(input.QueueUrl))
+	client.mutex.Unlock()
+
+	return client.Client.DeleteQueueWithContext(ctx, input, opts...)
+}
+
+func (client *deletingClient) deletedQueues() []string {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return append([]string(nil), client.deleted...)
+}
+
+// respond replies to the requests of the queue with their body prefixed by "re: " until the context is done.
+func respond(ctx context.Context, t *testing.T, server *queue.Queue) {
+	for ctx.Err() == nil {
+		message, err := server.ReceiveMessageContext(ctx)
+		if err != nil || message == nil {
+			continue
+		}
+		var body string
+		if err := json.Unmarshal([]byte(*message.Body), &body); err != nil {
+			t.Error(err)
+		}
+		if err := server.Reply(message, "re: "+body); err != nil {
+			t.Error(err)
+		}
+		server.DeleteMessage(message)
+	}
+}
+
+func TestRequestReply(t *testing.T) {
+	client := &deletingClient{Client: memqueue.NewClient()}
+	requester, err := queue.New("rpc", queue.WithClient(client), queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, err := queue.New("rpc", queue.WithClient(client), queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go respond(ctx, t, server)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			reply, err := requester.Request(ctx, fmt.Sprint("request ", i), 5*time.Second)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			var body string
+			json.Unmarshal([]byte(*reply.Body), &body)
+			if body != fmt.Sprint("re: request ", i) {
+				t.Errorf("request %d got the reply %q", i, body)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := requester.CloseReplyQueue(); err != nil {
+		t.Fatal(err)
+	}
+	if deleted := client.deletedQueues(); len(deleted) != 1 || !strings.Contains(deleted[0], "rpc-reply-") {
+		t.Errorf("expected the reply queue to be deleted, got %v", deleted)
+	}
+}
+
+func TestReplyRejectsForeignQueues(t *testing.T) {
+	client := memqueue.NewClient()
+	server, err := memqueue.NewWithClient(client, "rpc", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	own := strings.TrimSuffix(server.URL, "rpc") + "replies"
+	if _, err := client.CreateQueue(&sqs.CreateQueueInput{QueueName: aws.String("replies")}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, replyTo := range []string{
+		"https://sqs.us-east-1.amazonaws.com/111111111111/replies",
+		"https://sqs.eu-west-1.amazonaws.com/000000000000/replies",
+		"https://attacker.example.com/000000000000/replies",
+	} {
+		request := &sqs.Message{MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			queue.ReplyToAttribute: queue.StringAttribute(replyTo),
+		}}
+		if err := server.Reply(request, "body"); err != queue.ErrReplyToNotAllowed {
+			t.Errorf("%s: expected ErrReplyToNotAllowed, got %v", replyTo, err)
+		}
+	}
+
+	request := &sqs.Message{MessageAttributes: map[string]*sqs.MessageAttributeValue{
+		queue.ReplyToAttribute: queue.StringAttribute(own),
+	}}
+	if err := server.Reply(request, "body"); err != nil {
+		t.Errorf("expected replies to the own account to be allowed, got %v", err)
+	}
+	if bodies := client.Messages(own); len(bodies) != 1 {
+		t.Errorf("expected the reply to be sent, got %v", bodies)
+	}
+}
+
+func TestReplyAllowedQueues(t *testing.T) {
+	q, err := memqueue.New("rpc", queue.WithAllowedReplyQueues("https://sqs.eu-west-1.amazonaws.com/111111111111/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request := &sqs.Message{MessageAttributes: map[string]*sqs.MessageAttributeValue{
+		queue.ReplyToAttribute: queue.StringAttribute("https://sqs.eu-west-1.amazonaws.com/111111111111/replies"),
+	}}
+	// The allowed queue does not exist in memory, so the send fails after the check.
+	if err := q.Reply(request, "body"); err == queue.ErrReplyToNotAllowed {
+		t.Error("expected the allowed reply queue to pass the check")
+	}
+}
+
+func TestReplyQueueIdleTimeout(t *testing.T) {
+	client := &deletingClient{Client: memqueue.NewClient()}
+	requester, err := queue.New("rpc", queue.WithClient(client), queue.WithReceiveWaitTime(0), queue.WithReplyQueueIdleTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := requester.Request(context.Background(), "request", 10*time.Millisecond); err != queue.ErrRequestTimeout {
+		t.Fatalf("expected ErrRequestTimeout without a server, got %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for len(client.deletedQueues()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the idle reply queue to be deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := requester.Request(context.Background(), "request", 10*time.Millisecond); err != queue.ErrRequestTimeout {
+		t.Fatalf("expected a new reply queue for the next request, got %v", err)
+	}
+	if err := requester.CloseReplyQueue(); err != nil {
+		t.Fatal(err)
+	}
+	if deleted := client.deletedQueues(); len(deleted) != 2 || deleted[0] == deleted[1] {
+		t.Errorf("expected two reply queues to be deleted, got %v", deleted)
+	}
+}
+
+func TestReplyQueueNameKeepsOwner(t *testing.T) {
+	client := &deletingClient{Client: memqueue.NewClient()}
+	name := "applications-" + strings.Repeat("a", queue.MaxQueueNameLength-len("applications-"))
+	requester, err := queue.New(name, queue.WithClient(client), queue.WithoutDeadLetterQueue(), queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := requester.Request(context.Background(), "request", 50*time.Millisecond); err != queue.ErrRequestTimeout {
+		t.Fatalf("expected ErrRequestTimeout without a server, got %v", err)
+	}
+	if err := requester.CloseReplyQueue(); err != nil {
+		t.Fatal(err)
+	}
+
+	deleted := client.deletedQueues()
+	if len(deleted) != 1 {
+		t.Fatalf("expected the reply queue to be deleted, got %v", deleted)
+	}
+	replyName := deleted[0][strings.LastIndex(deleted[0], "/")+1:]
+	if len(replyName) != queue.MaxQueueNameLength || !strings.HasPrefix(replyName, "applications-") || !strings.Contains(replyName, "-reply-") {
+		t.Errorf("expected the truncated name of the queue with the reply suffix, got %q", replyName)
+	}
+}
@@ -0,0 +1,46 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// SendMessageRetrying will send message to the queue, retrying up to maxAttempts times on throttling and connection errors.
+// The wait between attempts starts at backoff and doubles after each attempt, other errors are returned immediately.
+func (queue *Queue) SendMessageRetrying(ctx context.Context, messageBody interface{}, maxAttempts int, backoff time.Duration) (resp *sqs.SendMessageOutput, err error) {
+	msg, err := queue.marshalMessageBody(messageBody)
+	if err != nil {
+		return
+	}
+
+	for attempt := 1; ; attempt++ {
+		params := &sqs.SendMessageInput{
+			MessageBody: aws.String(msg),
+			QueueUrl:    aws.String(queue.URL),
+		}
+		resp, err = queue.sendMessageInput(ctx, params)
+		if err == nil || attempt >= maxAttempts || !isRetryableError(err) {
+			return
+		}
+
+		queue.GetLogger().Warn("Retrying sending message to queue", Fields{
+			"queueName": queue.Name,
+			"attempt":   attempt,
+			"backoff":   backoff,
+			"error":     err,
+		})
+		if sleepErr := aws.SleepWithContext(ctx, backoff); sleepErr != nil {
+			return nil, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// isRetryableError reports whether the AWS error is transient, like throttling or a connection error.
+func isRetryableError(err error) bool {
+	return request.IsErrorThrottle(err) || request.IsErrorRetryable(err)
+}
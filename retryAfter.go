@@ -0,0 +1,83 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// Default delay of the first retry of a message returned with RetryAfter without a delay.
+const defaultRetryBaseDelay = 10 * time.Second
+
+// RetryAfterError is a handler error asking the Processor to redeliver the message after Delay.
+type RetryAfterError struct {
+	Delay time.Duration
+	Err   error
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("retry after %s: %v", e.Delay, e.Err)
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// RetryAfter returns a handler error that makes the message visible again after delay.
+// When delay is not positive, it grows exponentially with the receive count of the message.
+func RetryAfter(delay time.Duration, err error) error {
+	return &RetryAfterError{Delay: delay, Err: err}
+}
+
+// WithRetryBaseDelay sets the delay of the first retry of messages returned with RetryAfter without a delay,
+// it doubles with each receive.
+func (processor *Processor) WithRetryBaseDelay(base time.Duration) *Processor {
+	processor.retryBaseDelay = base
+
+	return processor
+}
+
+// retryMessageAfter changes the visibility timeout of the message to the delay requested by the handler error.
+// It reports whether the error asked for a delay.
+func (processor *Processor) retryMessageAfter(ctx context.Context, message *sqs.Message, err error) bool {
+	var retryAfter *RetryAfterError
+	if !errors.As(err, &retryAfter) {
+		return false
+	}
+
+	delay := retryAfter.Delay
+	if delay <= 0 {
+		delay = processor.getRetryDelay(message)
+	}
+	if delay > maxVisibilityTimeout {
+		delay = maxVisibilityTimeout
+	}
+
+	if err := processor.Queue.ChangeMessageVisibilityContext(ctx, message, delay); err != nil {
+		processor.getLogger().Warn("Error delaying message retry", Fields{
+			"queueName": processor.Queue.Name,
+			"messageID": message.MessageId,
+			"error":     err,
+		})
+	}
+
+	return true
+}
+
+// getRetryDelay returns the exponential retry delay for the receive count of the message.
+func (processor *Processor) getRetryDelay(message *sqs.Message) time.Duration {
+	delay := processor.retryBaseDelay
+	if delay <= 0 {
+		delay = defaultRetryBaseDelay
+	}
+
+	receiveCount, _ := ReceiveCount(message)
+	for i := 1; i < receiveCount && delay < maxVisibilityTimeout; i++ {
+		delay *= 2
+	}
+
+	return delay
+}
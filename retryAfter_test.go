@@ -0,0 +1,74 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// retriedVisibility processes the message with the handler error and returns the visibility timeouts set.
+func retriedVisibility(t *testing.T, processor *queue.Processor, client *fakeClient, message *sqs.Message, handlerErr error) []int64 {
+	t.Helper()
+	client.messages = []*sqs.Message{message}
+	processor.HandleMessage = failWith(handlerErr)
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	var timeouts []int64
+	for _, input := range visibilityChanges(client) {
+		timeouts = append(timeouts, *input.VisibilityTimeout)
+	}
+	client.visibilityInputs = nil
+	return timeouts
+}
+
+// receivedMessage returns a message received the given number of times.
+func receivedMessage(receiveCount string) *sqs.Message {
+	return &sqs.Message{
+		MessageId:     aws.String("message"),
+		ReceiptHandle: aws.String("receipt"),
+		Body:          aws.String(`"body"`),
+		Attributes:    map[string]*string{sqs.MessageSystemAttributeNameApproximateReceiveCount: aws.String(receiveCount)},
+	}
+}
+
+func TestRetryAfterExplicitDelay(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("retried", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := &queue.Processor{Queue: q}
+
+	if timeouts := retriedVisibility(t, processor, client, receivedMessage("1"), queue.RetryAfter(30*time.Second, errors.New("rate limited"))); len(timeouts) != 1 || timeouts[0] != 30 {
+		t.Errorf("expected the visibility timeout of 30 seconds, got %v", timeouts)
+	}
+	if timeouts := retriedVisibility(t, processor, client, receivedMessage("1"), queue.RetryAfter(13*time.Hour, errors.New("rate limited"))); len(timeouts) != 1 || timeouts[0] != 12*60*60 {
+		t.Errorf("expected the delay to be clamped to 12 hours, got %v", timeouts)
+	}
+	if timeouts := retriedVisibility(t, processor, client, receivedMessage("1"), errors.New("failed")); len(timeouts) != 0 {
+		t.Errorf("expected no visibility change for other errors, got %v", timeouts)
+	}
+}
+
+func TestRetryAfterComputedDelay(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("retried", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processor := (&queue.Processor{Queue: q}).WithRetryBaseDelay(5 * time.Second)
+
+	for receiveCount, expected := range map[string]int64{"1": 5, "3": 20, "20": 12 * 60 * 60} {
+		timeouts := retriedVisibility(t, processor, client, receivedMessage(receiveCount), queue.RetryAfter(0, errors.New("unavailable")))
+		if len(timeouts) != 1 || timeouts[0] != expected {
+			t.Errorf("expected the visibility timeout %d for receive count %s, got %v", expected, receiveCount, timeouts)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// flakySendClient fails the sends with the errors in order, then sends the messages.
+type flakySendClient struct {
+	*fakeClient
+	errs     []error
+	attempts int
+}
+
+func (client *flakySendClient) SendMessageWithContext(ctx aws.Context, input *sqs.SendMessageInput, opts ...request.Option) (*sqs.SendMessageOutput, error) {
+	client.attempts++
+	if len(client.errs) > 0 {
+		err := client.errs[0]
+		client.errs = client.errs[1:]
+		return nil, err
+	}
+	return client.fakeClient.SendMessageWithContext(ctx, input, opts...)
+}
+
+func TestSendMessageRetrying(t *testing.T) {
+	throttled := awserr.New("Throttling", "Rate exceeded", nil)
+	invalid := awserr.New(sqs.ErrCodeInvalidMessageContents, "Invalid characters", nil)
+	tests := []struct {
+		name     string
+		errs     []error
+		err      error
+		attempts int
+	}{
+		{name: "success after retryable failure", errs: []error{throttled}, attempts: 2},
+		{name: "non-retryable error", errs: []error{invalid, throttled}, err: invalid, attempts: 1},
+		{name: "attempts exhausted", errs: []error{throttled, throttled, throttled, throttled}, err: throttled, attempts: 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := &flakySendClient{fakeClient: newFakeClient(), errs: test.errs}
+			q, err := queue.New("retried", queue.WithClient(client))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := q.SendMessageRetrying(context.Background(), "body", 3, time.Millisecond)
+
+			if err != test.err {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+			if test.err == nil && (resp == nil || aws.StringValue(resp.MessageId) == "") {
+				t.Errorf("expected the sent message, got %v", resp)
+			}
+			if client.attempts != test.attempts {
+				t.Errorf("expected %d attempts, got %d", test.attempts, client.attempts)
+			}
+		})
+	}
+}
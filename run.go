@@ -0,0 +1,61 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// A RunOption configures Run.
+type RunOption func(settings *runSettings)
+
+// runSettings are the settings of Run.
+type runSettings struct {
+	queueOptions     []Option
+	processorOptions []ProcessorOption
+	existing         bool
+}
+
+// WithQueueOptions configures the queue of Run, e.g. with WithFIFO or WithLogger.
+func WithQueueOptions(opts ...Option) RunOption {
+	return func(settings *runSettings) {
+		settings.queueOptions = append(settings.queueOptions, opts...)
+	}
+}
+
+// WithProcessorOptions configures the processor of Run, e.g. it's Concurrency, receive backoff or Metrics.
+func WithProcessorOptions(opts ...ProcessorOption) RunOption {
+	return func(settings *runSettings) {
+		settings.processorOptions = append(settings.processorOptions, opts...)
+	}
+}
+
+// WithExistingQueue makes Run open the existing queue with Open instead of creating it.
+func WithExistingQueue() RunOption {
+	return func(settings *runSettings) {
+		settings.existing = true
+	}
+}
+
+// Run creates the queue of the name and handles it's messages, decoded into a new T, until the context is cancelled.
+// Errors of creating the queue are returned right away, errors of handling go to the logger and metrics of the processor.
+// On cancellation it drains the in-flight messages like ProcessWithContext and returns ErrDrainTimeout when they didn't finish.
+func Run[T any](ctx context.Context, name string, handler func(ctx context.Context, body T, message *sqs.Message) error, opts ...RunOption) error {
+	var settings runSettings
+	for _, opt := range opts {
+		opt(&settings)
+	}
+
+	var queue *Queue
+	var err error
+	if settings.existing {
+		queue, err = Open(name, settings.queueOptions...)
+	} else {
+		queue, err = New(name, settings.queueOptions...)
+	}
+	if err != nil {
+		return err
+	}
+
+	return Subscribe(queue, handler, settings.processorOptions...).ProcessWithContext(ctx, nil)
+}
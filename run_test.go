@@ -0,0 +1,83 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestRunLifecycle(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "run", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage(subscribedEvent{ID: "a", Count: 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handled := make(chan subscribedEvent, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- queue.Run(ctx, "run", func(ctx context.Context, body subscribedEvent, message *sqs.Message) error {
+			handled <- body
+			return nil
+		}, queue.WithExistingQueue(), queue.WithQueueOptions(queue.WithClient(client), queue.WithReceiveWaitTime(0)))
+	}()
+
+	select {
+	case body := <-handled:
+		if body != (subscribedEvent{ID: "a", Count: 2}) {
+			t.Errorf("expected the decoded body, got %+v", body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to handle the message")
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Run to shut down cleanly, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected Run to return after the context was cancelled")
+	}
+	if remaining := len(client.Messages(q.URL)); remaining != 0 {
+		t.Errorf("expected the handled message to be deleted, got %d messages", remaining)
+	}
+}
+
+func TestRunReturnsQueueError(t *testing.T) {
+	err := queue.Run(context.Background(), "invalid name", func(ctx context.Context, body subscribedEvent, message *sqs.Message) error {
+		return nil
+	}, queue.WithQueueOptions(queue.WithClient(memqueue.NewClient())))
+
+	if !errors.Is(err, queue.ErrInvalidQueueName) {
+		t.Errorf("expected ErrInvalidQueueName right away, got %v", err)
+	}
+}
+
+func ExampleRun() {
+	// The in-memory client stands in for SQS, Run creates the queue with it.
+	client := memqueue.NewClient()
+	q, _ := memqueue.NewWithClient(client, "orders")
+	q.SendMessage(subscribedEvent{ID: "order-1", Count: 3})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := queue.Run(ctx, "orders", func(ctx context.Context, order subscribedEvent, message *sqs.Message) error {
+		fmt.Printf("handled %s of %d items\n", order.ID, order.Count)
+		cancel()
+		return nil
+	}, queue.WithQueueOptions(queue.WithClient(client), queue.WithReceiveWaitTime(time.Second)))
+	fmt.Println("stopped:", err)
+	// Output:
+	// handled order-1 of 3 items
+	// stopped: <nil>
+}
@@ -0,0 +1,90 @@
+package queue_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// memoryS3 is an S3 client keeping the objects in memory, the other calls of s3iface.S3API panic.
+type memoryS3 struct {
+	s3iface.S3API
+
+	mutex   sync.Mutex
+	objects map[string][]byte
+	// putErr fails the puts when set.
+	putErr error
+}
+
+// newMemoryS3 returns an S3 client without objects.
+func newMemoryS3() *memoryS3 {
+	return &memoryS3{objects: make(map[string][]byte)}
+}
+
+// PutObjectWithContext stores the object.
+func (client *memoryS3) PutObjectWithContext(ctx aws.Context, input *s3.PutObjectInput, opts ...request.Option) (*s3.PutObjectOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.putErr != nil {
+		return nil, client.putErr
+	}
+	body, err := ioutil.ReadAll(input.Body)
+	if err != nil {
+		return nil, err
+	}
+	client.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)] = body
+
+	return &s3.PutObjectOutput{}, nil
+}
+
+// GetObjectWithContext returns the stored object.
+func (client *memoryS3) GetObjectWithContext(ctx aws.Context, input *s3.GetObjectInput, opts ...request.Option) (*s3.GetObjectOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	body, ok := client.objects[aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key)]
+	if !ok {
+		return nil, errors.New("NoSuchKey")
+	}
+
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(body))}, nil
+}
+
+// DeleteObjectWithContext deletes the stored object.
+func (client *memoryS3) DeleteObjectWithContext(ctx aws.Context, input *s3.DeleteObjectInput, opts ...request.Option) (*s3.DeleteObjectOutput, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	delete(client.objects, aws.StringValue(input.Bucket)+"/"+aws.StringValue(input.Key))
+
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+// keys returns the sorted bucket/key names of the stored objects.
+func (client *memoryS3) keys() (keys []string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	for key := range client.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return
+}
+
+// object returns the stored object of the bucket/key name.
+func (client *memoryS3) object(key string) []byte {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return client.objects[key]
+}
@@ -0,0 +1,54 @@
+// Package schema validates queue message bodies against a JSON schema.
+//
+//	validator, err := schema.New(candidateSchema)
+//	q, err := queue.New("your-queue-name", queue.WithValidator(validator))
+package schema
+
+import (
+	"encoding/json"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// A Validator is a queue.Validator checking the JSON encoding of the bodies against a JSON schema.
+type Validator struct {
+	schema *jsonschema.Schema
+}
+
+// New returns a Validator for the JSON schema document.
+func New(schema string) (*Validator, error) {
+	compiled, err := jsonschema.CompileString("schema.json", schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Validator{schema: compiled}, nil
+}
+
+// ValidateOutgoing checks the body to be sent against the schema.
+func (validator *Validator) ValidateOutgoing(body interface{}) error {
+	return validator.validate(body)
+}
+
+// ValidateIncoming checks the decoded body against the schema.
+func (validator *Validator) ValidateIncoming(decoded interface{}, raw *sqs.Message) error {
+	return validator.validate(decoded)
+}
+
+// validate checks the JSON encoding of the value against the schema.
+func (validator *Validator) validate(v interface{}) error {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		return err
+	}
+
+	return validator.schema.Validate(document)
+}
+
+var _ queue.Validator = (*Validator)(nil)
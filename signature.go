@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// MessageSignatureAttribute is the message attribute holding the HMAC-SHA256 signature of the body.
+const MessageSignatureAttribute = "X-Message-Signature"
+
+// ErrMissingSignature is returned when a message has no signature attribute.
+var ErrMissingSignature = errors.New("message signature is missing")
+
+// ErrInvalidSignature is returned when the message signature does not match its body.
+var ErrInvalidSignature = errors.New("message signature is invalid")
+
+// SendMessageWithSignature sends the message with an HMAC-SHA256 signature of the body as sent.
+// The signature is sent as the X-Message-Signature message attribute. It covers the body after encryption
+// and offloading, i.e. the ciphertext or the S3 pointer, so consumers verify it before resolving the body.
+func (queue *Queue) SendMessageWithSignature(ctx context.Context, messageBody interface{}, secret string) (resp *sqs.SendMessageOutput, err error) {
+	if queue.URL == "" {
+		return nil, ErrQueueNotInitialized
+	}
+	msg, err := queue.marshalMessageBody(messageBody)
+	if err != nil {
+		return
+	}
+	// The placeholder has the length of the signature, so the size checks of the preparation include it.
+	signature := &sqs.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(signMessageBody("", secret)),
+	}
+	params := &sqs.SendMessageInput{
+		MessageBody: aws.String(msg),
+		QueueUrl:    aws.String(queue.URL),
+		MessageAttributes: map[string]*sqs.MessageAttributeValue{
+			MessageSignatureAttribute: signature,
+		},
+	}
+	if err = queue.prepareMessageInput(ctx, params); err != nil {
+		return
+	}
+	signature.StringValue = aws.String(signMessageBody(aws.StringValue(params.MessageBody), secret))
+
+	return queue.sendPreparedMessageInput(ctx, params)
+}
+
+// VerifyMessageSignature checks the X-Message-Signature attribute of the message against its body as received,
+// before decrypting it or fetching it from S3.
+func VerifyMessageSignature(message *sqs.Message, secret string) error {
+	attribute, ok := message.MessageAttributes[MessageSignatureAttribute]
+	if !ok || attribute == nil || attribute.StringValue == nil {
+		return ErrMissingSignature
+	}
+
+	expected := signMessageBody(aws.StringValue(message.Body), secret)
+	if !hmac.Equal([]byte(*attribute.StringValue), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// signMessageBody returns the hex encoded HMAC-SHA256 of the body.
+func signMessageBody(body string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
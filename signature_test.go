@@ -0,0 +1,73 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestMessageSignature(t *testing.T) {
+	q, err := memqueue.New("signed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessageWithSignature(context.Background(), "body", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	message, err := q.ReceiveMessage()
+	if err != nil || message == nil {
+		t.Fatalf("expected the message, got %v, %v", message, err)
+	}
+
+	if err := queue.VerifyMessageSignature(message, "secret"); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+	if err := queue.VerifyMessageSignature(message, "other"); err != queue.ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for another secret, got %v", err)
+	}
+	message.Body = aws.String(`"tampered"`)
+	if err := queue.VerifyMessageSignature(message, "secret"); err != queue.ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature for a tampered body, got %v", err)
+	}
+	delete(message.MessageAttributes, queue.MessageSignatureAttribute)
+	if err := queue.VerifyMessageSignature(message, "secret"); err != queue.ErrMissingSignature {
+		t.Errorf("expected ErrMissingSignature, got %v", err)
+	}
+}
+
+func TestMessageSignatureEncrypted(t *testing.T) {
+	q, err := memqueue.New("signed", queue.WithReceiveWaitTime(0), queue.WithPayloadEncryption(encryptionTestKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessageWithSignature(context.Background(), encryptedTestMessage{Email: "user@example.com"}, "secret"); err != nil {
+		t.Fatal(err)
+	}
+	messages, err := q.Receive(context.Background(), 1)
+	if err != nil || len(messages) != 1 {
+		t.Fatalf("expected a message, got %d, %v", len(messages), err)
+	}
+
+	if err := queue.VerifyMessageSignature(messages[0].Message, "secret"); err != nil {
+		t.Errorf("expected the signature of the encrypted body to be valid, got %v", err)
+	}
+	var body encryptedTestMessage
+	if err := messages[0].Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Email != "user@example.com" {
+		t.Errorf("expected the decrypted body, got %+v", body)
+	}
+}
+
+func TestSendMessageWithSignatureNotInitialized(t *testing.T) {
+	q := &queue.Queue{Name: "uninitialized"}
+	q.SetClient(memqueue.NewClient())
+
+	if _, err := q.SendMessageWithSignature(context.Background(), "body", "secret"); err != queue.ErrQueueNotInitialized {
+		t.Errorf("expected ErrQueueNotInitialized, got %v", err)
+	}
+}
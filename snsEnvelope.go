@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// An SNSEnvelope is the body of a message delivered by SNS without raw message delivery.
+type SNSEnvelope struct {
+	Type              string                         `json:"Type"`
+	MessageID         string                         `json:"MessageId"`
+	TopicArn          string                         `json:"TopicArn"`
+	Subject           string                         `json:"Subject"`
+	Message           string                         `json:"Message"`
+	Timestamp         string                         `json:"Timestamp"`
+	MessageAttributes map[string]SNSMessageAttribute `json:"MessageAttributes"`
+}
+
+// An SNSMessageAttribute is a message attribute in an SNSEnvelope.
+type SNSMessageAttribute struct {
+	Type  string `json:"Type"`
+	Value string `json:"Value"`
+}
+
+// SNSNotification returns the SNS envelope of the message, if it was delivered by SNS without raw message delivery.
+// The topic ARN and the SNS message attributes are only available this way, the decoded body is the inner message.
+func SNSNotification(message *sqs.Message) (*SNSEnvelope, bool) {
+	return parseSNSEnvelope(aws.StringValue(message.Body))
+}
+
+// parseSNSEnvelope parses the body as an SNS notification envelope.
+func parseSNSEnvelope(body string) (*SNSEnvelope, bool) {
+	trimmed := strings.TrimSpace(body)
+	if !strings.HasPrefix(trimmed, "{") || !strings.Contains(trimmed, `"TopicArn"`) {
+		return nil, false
+	}
+
+	envelope := new(SNSEnvelope)
+	if err := json.Unmarshal([]byte(trimmed), envelope); err != nil {
+		return nil, false
+	}
+	if envelope.Type != "Notification" || envelope.TopicArn == "" {
+		return nil, false
+	}
+
+	return envelope, true
+}
+
+// unwrapSNSEnvelope returns the inner message of an SNS notification body, other bodies are returned unchanged.
+func unwrapSNSEnvelope(body string) string {
+	if envelope, ok := parseSNSEnvelope(body); ok {
+		return envelope.Message
+	}
+
+	return body
+}
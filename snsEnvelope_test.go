@@ -0,0 +1,105 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// snsNotificationFixture is the body of a message delivered by SNS without raw message delivery.
+const snsNotificationFixture = `{
+  "Type" : "Notification",
+  "MessageId" : "22b80b92-fdea-4c2c-8f9d-bdfb0c7bf324",
+  "TopicArn" : "arn:aws:sns:eu-central-1:123456789012:orders",
+  "Subject" : "Order created",
+  "Message" : "{\"orderID\":\"1234\",\"total\":42}",
+  "Timestamp" : "2026-10-14T12:00:00.000Z",
+  "SignatureVersion" : "1",
+  "Signature" : "EXAMPLEpH+DcEwjAPg8O9mY8dReBSwksfg2S7WKQcikcNKWLQjwu6A4VbeS0QHVCkhRS7fUQvi2egU3N858fiTDN6bkkOxYDVrY0Ad8L10Hs3zH81mtnPk5uvvolIC1CXGu43obcgFxeL3khZl8IKvO61GWB6jI9b5+gLPoBc1Q=",
+  "SigningCertURL" : "https://sns.eu-central-1.amazonaws.com/SimpleNotificationService-0000000000000000000000.pem",
+  "UnsubscribeURL" : "https://sns.eu-central-1.amazonaws.com/?Action=Unsubscribe&SubscriptionArn=arn:aws:sns:eu-central-1:123456789012:orders:2bcfbf39-05c3-41de-beaa-fcfcc21c8f55",
+  "MessageAttributes" : {
+    "source" : {"Type":"String","Value":"checkout"}
+  }
+}`
+
+// snsOrder is the inner message of snsNotificationFixture.
+type snsOrder struct {
+	OrderID string `json:"orderID"`
+	Total   int    `json:"total"`
+}
+
+func TestUnmarshalSNSNotification(t *testing.T) {
+	message := &sqs.Message{Body: aws.String(snsNotificationFixture)}
+
+	var order snsOrder
+	if err := queue.UnmarshalMessageBody(message, &order); err != nil {
+		t.Fatal(err)
+	}
+	if order != (snsOrder{OrderID: "1234", Total: 42}) {
+		t.Errorf("expected the inner message, got %+v", order)
+	}
+
+	envelope, ok := queue.SNSNotification(message)
+	if !ok {
+		t.Fatal("expected an SNS notification")
+	}
+	if envelope.TopicArn != "arn:aws:sns:eu-central-1:123456789012:orders" || envelope.MessageAttributes["source"].Value != "checkout" {
+		t.Errorf("expected the topic ARN and the SNS message attributes, got %+v", envelope)
+	}
+}
+
+func TestUnmarshalPlainMessage(t *testing.T) {
+	var order snsOrder
+	if err := queue.UnmarshalMessageBody(&sqs.Message{Body: aws.String(`{"orderID":"1234","total":42}`)}, &order); err != nil {
+		t.Fatal(err)
+	}
+	if order != (snsOrder{OrderID: "1234", Total: 42}) {
+		t.Errorf("expected the raw body to be decoded unchanged, got %+v", order)
+	}
+
+	// A body mentioning a TopicArn is not an envelope without the notification type.
+	var body map[string]interface{}
+	if err := queue.UnmarshalMessageBody(&sqs.Message{Body: aws.String(`{"TopicArn":"arn:aws:sns:eu-central-1:123456789012:orders"}`)}, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["TopicArn"] == nil {
+		t.Errorf("expected the body to be decoded unchanged, got %v", body)
+	}
+	if _, ok := queue.SNSNotification(&sqs.Message{Body: aws.String(`"plain"`)}); ok {
+		t.Error("expected a plain message not to be an SNS notification")
+	}
+}
+
+func TestProcessorUnwrapsSNSNotification(t *testing.T) {
+	q, err := memqueue.New("subscribed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendRawMessage(snsNotificationFixture)
+
+	var order *snsOrder
+	var topicArn string
+	processor := &queue.Processor{
+		Queue:   q,
+		NewBody: func() interface{} { return new(snsOrder) },
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			order = body.(*snsOrder)
+			if envelope, ok := queue.SNSNotification(message); ok {
+				topicArn = envelope.TopicArn
+			}
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if order == nil || order.OrderID != "1234" || topicArn == "" {
+		t.Errorf("expected the handler to get the inner message and the topic, got %+v %q", order, topicArn)
+	}
+}
@@ -0,0 +1,75 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// AllowSNSTopic adds a statement to the queue policy allowing the SNS topic to send messages to the queue.
+// The other statements of the policy are kept, and calling it again for the same topic changes nothing.
+func (queue *Queue) AllowSNSTopic(topicArn string) error {
+	resp, err := queue.GetAttributesByQueueURL(queue.URL, aws.StringSlice([]string{
+		sqs.QueueAttributeNameQueueArn,
+		sqs.QueueAttributeNamePolicy,
+	}))
+	if err != nil {
+		return err
+	}
+	queueArn := aws.StringValue(resp.Attributes[sqs.QueueAttributeNameQueueArn])
+
+	policy := map[string]interface{}{}
+	if policyString := aws.StringValue(resp.Attributes[sqs.QueueAttributeNamePolicy]); policyString != "" {
+		if err = json.Unmarshal([]byte(policyString), &policy); err != nil {
+			return fmt.Errorf("parsing queue attribute %s: %w", sqs.QueueAttributeNamePolicy, err)
+		}
+	}
+	if _, ok := policy["Version"]; !ok {
+		policy["Version"] = "2012-10-17"
+	}
+
+	var statements []interface{}
+	switch existing := policy["Statement"].(type) {
+	case []interface{}:
+		statements = existing
+	case map[string]interface{}:
+		// A single statement may be given without a list.
+		statements = []interface{}{existing}
+	}
+
+	sid := "AllowSNSTopic-" + topicArn
+	for _, statement := range statements {
+		if statementMap, ok := statement.(map[string]interface{}); ok && statementMap["Sid"] == sid {
+			return nil
+		}
+	}
+	policy["Statement"] = append(statements, map[string]interface{}{
+		"Sid":       sid,
+		"Effect":    "Allow",
+		"Principal": map[string]interface{}{"Service": "sns.amazonaws.com"},
+		"Action":    "sqs:SendMessage",
+		"Resource":  queueArn,
+		"Condition": map[string]interface{}{
+			"ArnEquals": map[string]interface{}{"aws:SourceArn": topicArn},
+		},
+	})
+
+	policyJSON, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	if err = queue.setAttributesByQueueURL(aws.BackgroundContext(), queue.URL, map[string]*string{
+		sqs.QueueAttributeNamePolicy: aws.String(string(policyJSON)),
+	}); err != nil {
+		return err
+	}
+
+	queue.GetLogger().Info("SNS topic allowed to send to the queue", Fields{
+		"queueName": queue.Name,
+		"topicArn":  topicArn,
+	})
+
+	return nil
+}
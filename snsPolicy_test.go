@@ -0,0 +1,99 @@
+package queue_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// policyStatements returns the statements of the queue policy.
+func policyStatements(t *testing.T, q *queue.Queue) []map[string]interface{} {
+	t.Helper()
+	resp, err := q.GetAttributesByQueueURL(q.URL, []*string{aws.String(sqs.QueueAttributeNamePolicy)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policy struct {
+		Version   string
+		Statement []map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(aws.StringValue(resp.Attributes[sqs.QueueAttributeNamePolicy])), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if policy.Version != "2012-10-17" {
+		t.Errorf("expected the policy version, got %q", policy.Version)
+	}
+	return policy.Statement
+}
+
+const testTopicArn = "arn:aws:sns:eu-central-1:000000000000:events"
+
+func TestAllowSNSTopicEmptyPolicy(t *testing.T) {
+	q, err := memqueue.New("subscribed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	arn, err := q.ARN()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.AllowSNSTopic(testTopicArn); err != nil {
+		t.Fatal(err)
+	}
+
+	statements := policyStatements(t, q)
+	if len(statements) != 1 {
+		t.Fatalf("expected one statement, got %v", statements)
+	}
+	statement := statements[0]
+	condition, _ := statement["Condition"].(map[string]interface{})
+	arnEquals, _ := condition["ArnEquals"].(map[string]interface{})
+	if statement["Action"] != "sqs:SendMessage" || statement["Resource"] != arn || arnEquals["aws:SourceArn"] != testTopicArn {
+		t.Errorf("expected the topic to be allowed to send to the queue, got %v", statement)
+	}
+}
+
+func TestAllowSNSTopicKeepsOtherStatements(t *testing.T) {
+	q, err := memqueue.New("subscribed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing := `{"Version":"2012-10-17","Statement":{"Sid":"AllowAccount","Effect":"Allow","Principal":{"AWS":"000000000000"},"Action":"sqs:*"}}`
+	if err := q.SetAttributes(map[string]string{sqs.QueueAttributeNamePolicy: existing}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.AllowSNSTopic(testTopicArn); err != nil {
+		t.Fatal(err)
+	}
+
+	statements := policyStatements(t, q)
+	if len(statements) != 2 || statements[0]["Sid"] != "AllowAccount" || statements[1]["Sid"] != "AllowSNSTopic-"+testTopicArn {
+		t.Errorf("expected the existing statement to be kept, got %v", statements)
+	}
+}
+
+func TestAllowSNSTopicIdempotent(t *testing.T) {
+	q, err := memqueue.New("subscribed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := q.AllowSNSTopic(testTopicArn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := q.AllowSNSTopic("arn:aws:sns:eu-central-1:000000000000:other"); err != nil {
+		t.Fatal(err)
+	}
+
+	if statements := policyStatements(t, q); len(statements) != 2 {
+		t.Errorf("expected one statement for each topic, got %v", statements)
+	}
+}
@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ProcessorOption configures a Processor created by Subscribe.
+type ProcessorOption func(processor *Processor)
+
+// Subscribe returns a Processor that decodes each message of the queue into a new T and passes it to the handler
+// together with the raw message. Run it with Process or ProcessWithContext, the body passed there is not used.
+func Subscribe[T any](queue *Queue, handler func(ctx context.Context, msg T, raw *sqs.Message) error, opts ...ProcessorOption) *Processor {
+	processor := &Processor{
+		Queue: queue,
+		NewBody: func() interface{} {
+			return new(T)
+		},
+		handleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			// A null body decodes to nil, the handler gets the zero value then.
+			var msg T
+			if value, ok := body.(*T); ok && value != nil {
+				msg = *value
+			}
+
+			return handler(ctx, msg, message)
+		},
+	}
+	for _, opt := range opts {
+		opt(processor)
+	}
+
+	return processor
+}
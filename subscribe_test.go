@@ -0,0 +1,103 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// subscribedEvent is the typed body of the Subscribe tests.
+type subscribedEvent struct {
+	ID    string `json:"id"`
+	Count int    `json:"count"`
+}
+
+func TestSubscribe(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "typed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent, err := q.SendMessage(subscribedEvent{ID: "a", Count: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received subscribedEvent
+	var messageID string
+	processor := queue.Subscribe(q, func(ctx context.Context, msg subscribedEvent, raw *sqs.Message) error {
+		received = msg
+		messageID = *raw.MessageId
+		return nil
+	})
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Processed != 1 || received != (subscribedEvent{ID: "a", Count: 2}) || messageID != *sent.MessageId {
+		t.Errorf("expected the typed message and the raw message, got %+v %+v %s", summary, received, messageID)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 0 {
+		t.Errorf("expected the handled message to be deleted, got %v", remaining)
+	}
+}
+
+func TestSubscribeDecodeFailure(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "typed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendRawMessage(`{"id":1}`)
+
+	called := false
+	processor := queue.Subscribe(q, func(ctx context.Context, msg subscribedEvent, raw *sqs.Message) error {
+		called = true
+		return nil
+	})
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if called || summary.Failed != 1 {
+		t.Errorf("expected the undecodable message not to be handled, got %+v", summary)
+	}
+	if remaining := client.Messages(q.DeadLetterQueueURL); len(remaining) != 1 {
+		t.Errorf("expected the undecodable message in the dead letter queue, got %v", remaining)
+	}
+}
+
+func TestSubscribeHandlerError(t *testing.T) {
+	client := memqueue.NewClient()
+	q, err := memqueue.NewWithClient(client, "typed", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage(subscribedEvent{ID: "a"})
+
+	processor := queue.Subscribe(q, func(ctx context.Context, msg subscribedEvent, raw *sqs.Message) error {
+		return errors.New("downstream unavailable")
+	}, func(processor *queue.Processor) {
+		processor.Concurrency = 2
+	})
+	if processor.Concurrency != 2 {
+		t.Errorf("expected the processor option to be applied, got %d", processor.Concurrency)
+	}
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if summary.Failed != 1 || summary.Processed != 0 {
+		t.Errorf("expected the message to fail, got %+v", summary)
+	}
+	if remaining := client.Messages(q.URL); len(remaining) != 1 {
+		t.Errorf("expected the failed message to stay in the queue, got %v", remaining)
+	}
+}
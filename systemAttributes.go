@@ -0,0 +1,47 @@
+package queue
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// messageContextKey is the context key of the message being handled.
+type messageContextKey struct{}
+
+// MessageFromContext returns the raw message handled within the context, e.g. for it's attributes in a Handler.
+func MessageFromContext(ctx context.Context) (message *sqs.Message, ok bool) {
+	message, ok = ctx.Value(messageContextKey{}).(*sqs.Message)
+
+	return
+}
+
+// contextWithMessage returns the context of handling the message.
+func contextWithMessage(ctx context.Context, message *sqs.Message) context.Context {
+	return context.WithValue(ctx, messageContextKey{}, message)
+}
+
+// ReceiveCount returns how many times the message was received, from it's ApproximateReceiveCount attribute.
+// It reports false when the attribute is missing or malformed.
+func ReceiveCount(message *sqs.Message) (count int, ok bool) {
+	count, err := strconv.Atoi(aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameApproximateReceiveCount]))
+	if err != nil {
+		return 0, false
+	}
+
+	return count, true
+}
+
+// SentTime returns when the message was sent, from it's SentTimestamp attribute.
+// It reports false when the attribute is missing or malformed.
+func SentTime(message *sqs.Message) (sentAt time.Time, ok bool) {
+	timestamp, err := strconv.ParseInt(aws.StringValue(message.Attributes[sqs.MessageSystemAttributeNameSentTimestamp]), 10, 64)
+	if err != nil {
+		return
+	}
+
+	return time.Unix(0, timestamp*int64(time.Millisecond)), true
+}
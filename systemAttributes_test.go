@@ -0,0 +1,86 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestReceiveRequestsSystemAttributes(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []queue.Option
+		expected []string
+	}{
+		{name: "default", expected: []string{sqs.QueueAttributeNameAll}},
+		{
+			name:     "configured",
+			opts:     []queue.Option{queue.WithReceiveAttributeNames(sqs.MessageSystemAttributeNameApproximateReceiveCount)},
+			expected: []string{sqs.MessageSystemAttributeNameApproximateReceiveCount, queue.AWSTraceHeaderAttribute},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := newFakeClient()
+			q, err := queue.New("attributes", append(test.opts, queue.WithClient(client))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.ReceiveMessage(); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(client.receiveInputs) != 1 {
+				t.Fatalf("expected 1 receive, got %d", len(client.receiveInputs))
+			}
+			if names := aws.StringValueSlice(client.receiveInputs[0].MessageSystemAttributeNames); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected the system attributes %v to be requested, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestHandlerReadsReceiveCount(t *testing.T) {
+	q, err := memqueue.New("counted", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := time.Now().Add(-time.Millisecond)
+	q.SendMessage("retried")
+
+	var counts []int
+	var sentAt time.Time
+	processor := &queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled, _ := queue.MessageFromContext(ctx)
+			count, ok := queue.ReceiveCount(handled)
+			if !ok {
+				t.Error("expected the receive count of the message")
+			}
+			counts = append(counts, count)
+			sentAt, _ = queue.SentTime(handled)
+			if count == 1 {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	if _, err := processor.ProcessN(context.Background(), 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(counts, []int{1, 2}) {
+		t.Errorf("expected the receive counts 1 and 2, got %v", counts)
+	}
+	if sentAt.Before(before) || sentAt.After(time.Now()) {
+		t.Errorf("expected the sent time of the message, got %s", sentAt)
+	}
+}
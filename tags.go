@@ -0,0 +1,95 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ErrNoDeadLetterQueue is returned for dead letter queue operations on a queue without one.
+var ErrNoDeadLetterQueue = errors.New("queue has no dead letter queue")
+
+// Tags returns the tags of the queue.
+func (queue *Queue) Tags() (map[string]string, error) {
+	return queue.TagsContext(aws.BackgroundContext())
+}
+
+// TagsContext returns the tags of the queue within the context.
+func (queue *Queue) TagsContext(ctx context.Context) (map[string]string, error) {
+	return queue.listQueueTagsByURL(ctx, queue.URL)
+}
+
+// applyTags adds the tags configured with WithTags to the queue by it's URL.
+func (queue *Queue) applyTags(ctx context.Context, url string) error {
+	if len(queue.tags) == 0 {
+		return nil
+	}
+
+	if err := queue.tagQueueByURL(ctx, url, queue.tags); err != nil {
+		return fmt.Errorf("tagging queue %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// TagDeadLetterQueue adds the tags to the dead letter queue.
+func (queue *Queue) TagDeadLetterQueue(tags map[string]string) error {
+	if queue.DeadLetterQueueURL == "" {
+		return ErrNoDeadLetterQueue
+	}
+
+	return queue.tagQueueByURL(aws.BackgroundContext(), queue.DeadLetterQueueURL, tags)
+}
+
+// GetDeadLetterQueueTags returns the tags of the dead letter queue.
+func (queue *Queue) GetDeadLetterQueueTags() (map[string]string, error) {
+	if queue.DeadLetterQueueURL == "" {
+		return nil, ErrNoDeadLetterQueue
+	}
+
+	return queue.listQueueTagsByURL(aws.BackgroundContext(), queue.DeadLetterQueueURL)
+}
+
+// tagQueueByURL adds the tags to the queue by it's URL within the context.
+func (queue *Queue) tagQueueByURL(ctx context.Context, url string, tags map[string]string) (err error) {
+	client := queue.GetClient()
+	params := &sqs.TagQueueInput{
+		QueueUrl: aws.String(url),
+		Tags:     aws.StringMap(tags),
+	}
+	_, err = client.TagQueueWithContext(ctx, params)
+
+	if err != nil {
+		queue.GetLogger().Error("Tagging queue", Fields{
+			"queueName": queue.Name,
+			"queueUrl":  url,
+			"error":     err,
+		})
+	}
+
+	return
+}
+
+// listQueueTagsByURL returns the tags of the queue by it's URL within the context.
+func (queue *Queue) listQueueTagsByURL(ctx context.Context, url string) (tags map[string]string, err error) {
+	client := queue.GetClient()
+	params := &sqs.ListQueueTagsInput{
+		QueueUrl: aws.String(url),
+	}
+	resp, err := client.ListQueueTagsWithContext(ctx, params)
+
+	if err != nil {
+		queue.GetLogger().Error("Listing queue tags", Fields{
+			"queueName": queue.Name,
+			"queueUrl":  url,
+			"error":     err,
+		})
+		return
+	}
+
+	tags = aws.StringValueMap(resp.Tags)
+	return
+}
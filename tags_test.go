@@ -0,0 +1,73 @@
+package queue_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+func TestTags(t *testing.T) {
+	client := memqueue.NewClient()
+	expected := map[string]string{"team": "payments", "env": "test"}
+	q, err := memqueue.NewWithClient(client, "tagged", queue.WithTags(expected))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := q.Tags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected the tags %v, got %v", expected, tags)
+	}
+
+	if _, err := client.DeleteQueueWithContext(context.Background(), &sqs.DeleteQueueInput{QueueUrl: aws.String(q.URL)}); err != nil {
+		t.Fatal(err)
+	}
+	if tags, err := q.Tags(); err == nil || tags != nil {
+		t.Errorf("expected the error of a deleted queue, got %v and %v", tags, err)
+	}
+}
+
+func TestTagDeadLetterQueue(t *testing.T) {
+	q, err := memqueue.New("tagged")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"team": "payments", "env": "test"}
+	if err := q.TagDeadLetterQueue(expected); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := q.GetDeadLetterQueueTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Errorf("expected the tags %v, got %v", expected, tags)
+	}
+	if tags, err := q.Tags(); err != nil || len(tags) != 0 {
+		t.Errorf("expected the queue itself not to be tagged, got %v and %v", tags, err)
+	}
+}
+
+func TestDeadLetterQueueTagsWithoutDeadLetterQueue(t *testing.T) {
+	q, err := memqueue.New("untagged", queue.WithoutDeadLetterQueue())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.TagDeadLetterQueue(map[string]string{"team": "payments"}); err != queue.ErrNoDeadLetterQueue {
+		t.Errorf("expected ErrNoDeadLetterQueue when tagging, got %v", err)
+	}
+	if tags, err := q.GetDeadLetterQueueTags(); err != queue.ErrNoDeadLetterQueue || tags != nil {
+		t.Errorf("expected ErrNoDeadLetterQueue when listing the tags, got %v and %v", tags, err)
+	}
+}
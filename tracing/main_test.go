@@ -0,0 +1,16 @@
+package tracing_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TestMain silences the logs of the queues and processors under test.
+func TestMain(m *testing.M) {
+	log.SetOutput(ioutil.Discard)
+
+	os.Exit(m.Run())
+}
@@ -0,0 +1,95 @@
+// Package tracing propagates OpenTelemetry trace context through queue messages.
+//
+// Queues created with WithTracePropagation add the span context of the sending context to the message attributes,
+// processors using Middleware continue the trace in a consumer span around the handler.
+//
+//	q, err := queue.New("your-queue-name", tracing.WithTracePropagation())
+//	processor := queue.Processor{Queue: q, HandleMessageBody: handleMessageBody}
+//	processor.Use(tracing.Middleware(nil))
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttributePrefix prefixes the message attributes holding the trace context, so they don't collide with user attributes.
+const AttributePrefix = "otel."
+
+// Name of the tracer used when Middleware gets none.
+const tracerName = "github.com/Indivizo/sqs/tracing"
+
+// WithTracePropagation adds the trace context of the sending context to the attributes of the sent messages.
+// The propagator registered with otel.SetTextMapPropagator is used, e.g. W3C traceparent.
+func WithTracePropagation() queue.Option {
+	return queue.WithSendHook(func(ctx context.Context, attributes map[string]*sqs.MessageAttributeValue) {
+		otel.GetTextMapPropagator().Inject(ctx, attributeCarrier(attributes))
+	})
+}
+
+// Middleware continues the trace of each message in a consumer span around the handler, handler errors are recorded on it.
+// The global tracer provider is used when tracer is nil.
+func Middleware(tracer trace.Tracer) queue.Middleware {
+	if tracer == nil {
+		tracer = otel.Tracer(tracerName)
+	}
+
+	return func(next queue.MessageHandlerFunc) queue.MessageHandlerFunc {
+		return func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, attributeCarrier(message.MessageAttributes))
+			ctx, span := tracer.Start(ctx, "sqs process",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					attribute.String("messaging.system", "aws_sqs"),
+					attribute.String("messaging.message.id", aws.StringValue(message.MessageId)),
+				),
+			)
+			defer span.End()
+
+			err := next(ctx, body, message)
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return err
+		}
+	}
+}
+
+// attributeCarrier is a propagation.TextMapCarrier over message attributes.
+type attributeCarrier map[string]*sqs.MessageAttributeValue
+
+// Get returns the value of the prefixed attribute.
+func (carrier attributeCarrier) Get(key string) string {
+	value, ok := carrier[AttributePrefix+key]
+	if !ok {
+		return ""
+	}
+
+	return aws.StringValue(value.StringValue)
+}
+
+// Set sets the prefixed attribute.
+func (carrier attributeCarrier) Set(key string, value string) {
+	carrier[AttributePrefix+key] = queue.StringAttribute(value)
+}
+
+// Keys returns the keys of the trace context attributes without the prefix.
+func (carrier attributeCarrier) Keys() (keys []string) {
+	for name := range carrier {
+		if strings.HasPrefix(name, AttributePrefix) {
+			keys = append(keys, strings.TrimPrefix(name, AttributePrefix))
+		}
+	}
+
+	return
+}
@@ -0,0 +1,155 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/Indivizo/sqs/tracing"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordedSpan is a span of recordingTracer, it keeps what the middleware set on it.
+type recordedSpan struct {
+	trace.Span
+	name        string
+	kind        trace.SpanKind
+	parent      trace.SpanContext
+	spanContext trace.SpanContext
+	status      codes.Code
+	errs        []error
+	ended       bool
+}
+
+func (span *recordedSpan) SpanContext() trace.SpanContext { return span.spanContext }
+
+func (span *recordedSpan) End(options ...trace.SpanEndOption) { span.ended = true }
+
+func (span *recordedSpan) RecordError(err error, options ...trace.EventOption) {
+	span.errs = append(span.errs, err)
+}
+
+func (span *recordedSpan) SetStatus(code codes.Code, description string) { span.status = code }
+
+// recordingTracer is an in-memory tracer recording the started spans.
+type recordingTracer struct {
+	mutex sync.Mutex
+	spans []*recordedSpan
+}
+
+func (tracer *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	tracer.mutex.Lock()
+	defer tracer.mutex.Unlock()
+
+	config := trace.NewSpanStartConfig(opts...)
+	parent := trace.SpanContextFromContext(ctx)
+	span := &recordedSpan{
+		Span:   trace.SpanFromContext(context.Background()),
+		name:   name,
+		kind:   config.SpanKind(),
+		parent: parent,
+		spanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    parent.TraceID(),
+			SpanID:     trace.SpanID{0, 0, 0, 0, 0, 0, 0, byte(len(tracer.spans) + 1)},
+			TraceFlags: parent.TraceFlags(),
+		}),
+	}
+	tracer.spans = append(tracer.spans, span)
+
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// producerContext returns a context with the span context of a producer, and sets the W3C propagator until the test ends.
+func producerContext(t *testing.T) (context.Context, trace.SpanContext) {
+	t.Helper()
+
+	propagator := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(propagator) })
+
+	producer := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+		SpanID:     trace.SpanID{1, 2, 3, 4, 5, 6, 7, 8},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	return trace.ContextWithSpanContext(context.Background(), producer), producer
+}
+
+func TestTracePropagation(t *testing.T) {
+	ctx, producer := producerContext(t)
+	q, err := memqueue.New("traced", queue.WithReceiveWaitTime(0), tracing.WithTracePropagation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessageContext(ctx, "traced"); err != nil {
+		t.Fatal(err)
+	}
+
+	tracer := &recordingTracer{}
+	var handled trace.SpanContext
+	var attributes map[string]*sqs.MessageAttributeValue
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			handled = trace.SpanContextFromContext(ctx)
+			attributes = message.MessageAttributes
+			return nil
+		},
+	}).Use(tracing.Middleware(tracer))
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := attributes[tracing.AttributePrefix+"traceparent"]; !ok {
+		t.Errorf("expected the trace context in the message attributes, got %v", attributes)
+	}
+	if len(tracer.spans) != 1 {
+		t.Fatalf("expected 1 consumer span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.name != "sqs process" || span.kind != trace.SpanKindConsumer || !span.ended {
+		t.Errorf("expected an ended consumer span, got %q of kind %s", span.name, span.kind)
+	}
+	if span.parent.TraceID() != producer.TraceID() || span.parent.SpanID() != producer.SpanID() || !span.parent.IsRemote() {
+		t.Errorf("expected the producer span as the remote parent, got %v", span.parent)
+	}
+	if !handled.Equal(span.spanContext) {
+		t.Errorf("expected the handler to run within the consumer span, got %v", handled)
+	}
+}
+
+func TestMiddlewareRecordsHandlerError(t *testing.T) {
+	q, err := memqueue.New("failing", queue.WithReceiveWaitTime(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("failing")
+
+	tracer := &recordingTracer{}
+	failure := errors.New("failed")
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			return failure
+		},
+	}).Use(tracing.Middleware(tracer))
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 1 {
+		t.Fatalf("expected 1 consumer span, got %d", len(tracer.spans))
+	}
+	span := tracer.spans[0]
+	if span.status != codes.Error || len(span.errs) != 1 || span.errs[0] != failure {
+		t.Errorf("expected the handler error on the span, got status %v and errors %v", span.status, span.errs)
+	}
+}
@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// A Validator checks the message bodies sent to and received from a queue.
+type Validator interface {
+	// ValidateOutgoing checks the body before it is marshalled, an error rejects the send.
+	ValidateOutgoing(body interface{}) error
+	// ValidateIncoming checks the decoded body before it is handled, an error dead-letters the message.
+	ValidateIncoming(decoded interface{}, raw *sqs.Message) error
+}
+
+// WithValidator validates the bodies of the messages sent to the queue and handled by it's processors.
+func WithValidator(validator Validator) Option {
+	return func(queue *Queue) error {
+		queue.validator = validator
+		return nil
+	}
+}
+
+// validateIncoming checks the decoded body with the validator of the queue, failures are permanent errors.
+func (queue *Queue) validateIncoming(decoded interface{}, message *sqs.Message) error {
+	if queue.validator == nil {
+		return nil
+	}
+	if err := queue.validator.ValidateIncoming(decoded, message); err != nil {
+		return PermanentError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// errInvalidBody is the error of the bodies rejected by bodyValidator.
+var errInvalidBody = errors.New("invalid body")
+
+// bodyValidator rejects the "invalid" bodies.
+type bodyValidator struct{}
+
+func (bodyValidator) ValidateOutgoing(body interface{}) error {
+	if body == "invalid" {
+		return errInvalidBody
+	}
+	return nil
+}
+
+func (bodyValidator) ValidateIncoming(decoded interface{}, raw *sqs.Message) error {
+	if decoded == "invalid" {
+		return errInvalidBody
+	}
+	return nil
+}
+
+func TestValidatorRejectsSendWithoutCallingSQS(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("validated", queue.WithClient(client), queue.WithValidator(bodyValidator{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := q.SendMessage("invalid"); !errors.Is(err, errInvalidBody) {
+		t.Errorf("expected the validation error, got %v", err)
+	}
+	if len(client.sendInputs) != 0 {
+		t.Errorf("expected no message to be sent, got %d", len(client.sendInputs))
+	}
+
+	if _, err := q.SendMessage("valid"); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.sendInputs) != 1 {
+		t.Errorf("expected the valid message to be sent, got %d", len(client.sendInputs))
+	}
+}
+
+func TestValidatorDeadLettersInvalidMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor func(q *queue.Queue, handled *[]interface{}) *queue.Processor
+	}{
+		{
+			name: "message",
+			processor: func(q *queue.Queue, handled *[]interface{}) *queue.Processor {
+				return &queue.Processor{
+					Queue: q,
+					HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+						*handled = append(*handled, body)
+						return nil
+					},
+				}
+			},
+		},
+		{
+			name: "decoded batch",
+			processor: func(q *queue.Queue, handled *[]interface{}) *queue.Processor {
+				return &queue.Processor{
+					Queue: q,
+					HandleDecodedBatch: func(ctx context.Context, messages []queue.DecodedMessage) ([]queue.Failed, error) {
+						for _, message := range messages {
+							*handled = append(*handled, message.Body)
+						}
+						return nil, nil
+					},
+				}
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := memqueue.NewClient()
+			// The messages are sent without validation, as by another producer.
+			producer, err := memqueue.NewWithClient(client, "validated", queue.WithReceiveWaitTime(0))
+			if err != nil {
+				t.Fatal(err)
+			}
+			producer.SendMessage("invalid")
+			producer.SendMessage("valid")
+			q, err := memqueue.NewWithClient(client, "validated", queue.WithReceiveWaitTime(0), queue.WithValidator(bodyValidator{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var handled []interface{}
+			summary, err := test.processor(q, &handled).ProcessN(context.Background(), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(handled) != 1 || handled[0] != "valid" {
+				t.Errorf("expected only the valid message to be handled, got %v", handled)
+			}
+			if summary != (queue.ProcessSummary{Processed: 1, Failed: 1}) {
+				t.Errorf("expected 1 processed and 1 failed message, got %+v", summary)
+			}
+			if remaining := len(client.Messages(q.URL)); remaining != 0 {
+				t.Errorf("expected the invalid message to be removed from the queue, got %d messages", remaining)
+			}
+			if deadLetters := client.Messages(q.DeadLetterQueueURL); len(deadLetters) != 1 || deadLetters[0] != `"invalid"` {
+				t.Errorf("expected the invalid message in the dead letter queue, got %v", deadLetters)
+			}
+		})
+	}
+}
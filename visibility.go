@@ -0,0 +1,71 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// ChangeMessageVisibility sets how long the message stays invisible, counted from now.
+// ErrInvalidVisibilityTimeout is returned when the timeout is not between 0 and 12 hours.
+func (queue *Queue) ChangeMessageVisibility(message *sqs.Message, timeout time.Duration) error {
+	return queue.ChangeMessageVisibilityContext(aws.BackgroundContext(), message, timeout)
+}
+
+// ChangeMessageVisibilityContext sets how long the message stays invisible within the context.
+func (queue *Queue) ChangeMessageVisibilityContext(ctx context.Context, message *sqs.Message, timeout time.Duration) error {
+	return queue.ChangeMessageVisibilityByReceiptHandleContext(ctx, message.ReceiptHandle, timeout)
+}
+
+// ChangeMessageVisibilityByReceiptHandle sets how long a message stays invisible by it's receiptHandle.
+func (queue *Queue) ChangeMessageVisibilityByReceiptHandle(receiptHandle *string, timeout time.Duration) error {
+	return queue.ChangeMessageVisibilityByReceiptHandleContext(aws.BackgroundContext(), receiptHandle, timeout)
+}
+
+// ChangeMessageVisibilityByReceiptHandleContext sets how long a message stays invisible by it's receiptHandle within the context.
+// The AWS error is returned as it is when the receipt handle has expired, see IsReceiptHandleInvalid.
+func (queue *Queue) ChangeMessageVisibilityByReceiptHandleContext(ctx context.Context, receiptHandle *string, timeout time.Duration) (err error) {
+	if timeout < 0 || timeout > maxVisibilityTimeout {
+		return ErrInvalidVisibilityTimeout
+	}
+
+	client := queue.GetClient()
+	params := &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          aws.String(queue.URL),
+		ReceiptHandle:     aws.String(aws.StringValue(receiptHandle)),
+		VisibilityTimeout: aws.Int64(int64(timeout / time.Second)),
+	}
+	_, err = client.ChangeMessageVisibilityWithContext(ctx, params)
+
+	if err != nil {
+		queue.GetLogger().Error("Changing message visibility", Fields{
+			"queueName": queue.Name,
+			"error":     err,
+		})
+	}
+
+	return
+}
+
+// IsReceiptHandleInvalid reports whether the AWS error is about an expired or invalid receipt handle,
+// e.g. because the visibility timeout of the message is over.
+func IsReceiptHandleInvalid(err error) bool {
+	var aerr awserr.Error
+	if !errors.As(err, &aerr) {
+		return false
+	}
+
+	switch aerr.Code() {
+	case sqs.ErrCodeReceiptHandleIsInvalid, sqs.ErrCodeMessageNotInflight:
+		return true
+	case "InvalidParameterValue":
+		return strings.Contains(aerr.Message(), "ReceiptHandle")
+	}
+
+	return false
+}
@@ -0,0 +1,92 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// WithAutoExtendVisibility keeps messages invisible while their handler runs.
+// The visibility timeout is renewed extensionBuffer before it would expire, so handlers may run longer than the timeout.
+func (processor *Processor) WithAutoExtendVisibility(extensionBuffer time.Duration) *Processor {
+	processor.visibilityExtensionBuffer = extensionBuffer
+
+	return processor
+}
+
+// WithVisibilityHeartbeat extends the visibility timeout of messages by extension every interval while their handler runs,
+// for at most maxTotal in total (unlimited when not positive).
+// When an extension fails, the context of the handler is cancelled, as the message may be redelivered to another worker.
+func (processor *Processor) WithVisibilityHeartbeat(interval time.Duration, extension time.Duration, maxTotal time.Duration) *Processor {
+	processor.heartbeatInterval = interval
+	processor.heartbeatExtension = extension
+	processor.heartbeatMaxTotal = maxTotal
+
+	return processor
+}
+
+// visibilityExtension returns how often and by how much the visibility timeout of the messages is extended.
+func (processor *Processor) visibilityExtension() (interval time.Duration, extension time.Duration) {
+	if processor.heartbeatInterval > 0 && processor.heartbeatExtension > 0 {
+		return processor.heartbeatInterval, processor.heartbeatExtension
+	}
+	if processor.visibilityExtensionBuffer <= 0 {
+		return
+	}
+
+	extension = time.Duration(processor.Queue.getVisibilityTimeoutSeconds()) * time.Second
+	interval = extension - processor.visibilityExtensionBuffer
+	if interval <= 0 {
+		interval = extension / 2
+	}
+
+	return
+}
+
+// startVisibilityExtension renews the visibility timeout of the message until the returned stop function is called.
+// The returned context is cancelled when renewing fails.
+func (processor *Processor) startVisibilityExtension(ctx context.Context, message *sqs.Message) (handlerCtx context.Context, stop func()) {
+	interval, extension := processor.visibilityExtension()
+	if interval <= 0 {
+		return ctx, func() {}
+	}
+
+	handlerCtx, cancel := context.WithCancel(ctx)
+	started := time.Now()
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-handlerCtx.Done():
+				return
+			case <-ticker.C:
+				if processor.heartbeatMaxTotal > 0 && time.Since(started) >= processor.heartbeatMaxTotal {
+					return
+				}
+				if err := processor.Queue.ChangeMessageVisibilityContext(handlerCtx, message, extension); err != nil {
+					processor.getLogger().Warn("Error extending message visibility", Fields{
+						"queueName": processor.Queue.Name,
+						"messageID": message.MessageId,
+						"error":     err,
+					})
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	return handlerCtx, func() {
+		close(done)
+		<-finished
+		cancel()
+	}
+}
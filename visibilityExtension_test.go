@@ -0,0 +1,149 @@
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// visibilityChanges returns the visibility changes made so far.
+func visibilityChanges(client *fakeClient) []*sqs.ChangeMessageVisibilityInput {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	return append([]*sqs.ChangeMessageVisibilityInput(nil), client.visibilityInputs...)
+}
+
+func TestVisibilityHeartbeat(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("heartbeat", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			time.Sleep(175 * time.Millisecond)
+			return nil
+		},
+	}).WithVisibilityHeartbeat(50*time.Millisecond, time.Minute, 0)
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := visibilityChanges(client)
+	if len(changes) < 2 || len(changes) > 3 {
+		t.Fatalf("expected an extension every 50ms while the handler ran, got %d", len(changes))
+	}
+	for _, change := range changes {
+		if *change.VisibilityTimeout != 60 || *change.ReceiptHandle != "receipt-message-1" {
+			t.Errorf("expected the message to be extended by 60 seconds, got %v", change)
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if after := visibilityChanges(client); len(after) != len(changes) {
+		t.Errorf("expected the extensions to stop with the handler, got %d more", len(after)-len(changes))
+	}
+}
+
+func TestVisibilityHeartbeatMaxTotal(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("heartbeat", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			time.Sleep(300 * time.Millisecond)
+			return nil
+		},
+	}).WithVisibilityHeartbeat(50*time.Millisecond, time.Minute, 120*time.Millisecond)
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := visibilityChanges(client); len(changes) < 1 || len(changes) > 2 {
+		t.Errorf("expected the extensions to stop after 120ms, got %d", len(changes))
+	}
+}
+
+func TestVisibilityHeartbeatFailureCancelsHandler(t *testing.T) {
+	q, err := queue.New("heartbeat", queue.WithClient(&expiredReceiptClient{fakeClient: newFakeClient()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	cancelled := false
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			select {
+			case <-ctx.Done():
+				cancelled = true
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		},
+	}).WithVisibilityHeartbeat(20*time.Millisecond, time.Minute, 0)
+	summary, err := processor.ProcessN(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cancelled || summary.Failed != 1 {
+		t.Errorf("expected the failed extension to cancel the handler, got %+v", summary)
+	}
+}
+
+func TestAutoExtendVisibility(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("extended", queue.WithClient(client), queue.WithReceiveVisibilityTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SendMessage("body")
+
+	// The visibility is renewed 800ms before the 1 second timeout expires, every 200ms.
+	var changes []*sqs.ChangeMessageVisibilityInput
+	started := time.Now()
+	processor := (&queue.Processor{
+		Queue: q,
+		HandleMessage: func(ctx context.Context, body interface{}, message *sqs.Message) error {
+			time.Sleep(150 * time.Millisecond)
+			if changes := visibilityChanges(client); len(changes) != 0 {
+				t.Errorf("expected no renewal within the first 200ms, got %d after %s", len(changes), time.Since(started))
+			}
+			time.Sleep(350 * time.Millisecond)
+			changes = visibilityChanges(client)
+			return nil
+		},
+	}).WithAutoExtendVisibility(800 * time.Millisecond)
+	if _, err := processor.ProcessN(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(changes) != 2 {
+		t.Fatalf("expected a renewal every 200ms while the handler ran, got %d", len(changes))
+	}
+	for _, change := range changes {
+		if *change.VisibilityTimeout != 1 || *change.ReceiptHandle != "receipt-message-1" {
+			t.Errorf("expected the message to be renewed for the visibility timeout, got %v", change)
+		}
+	}
+
+	time.Sleep(300 * time.Millisecond)
+	if after := visibilityChanges(client); len(after) != len(changes) {
+		t.Errorf("expected the renewals to stop with the handler, got %d more", len(after)-len(changes))
+	}
+}
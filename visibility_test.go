@@ -0,0 +1,83 @@
+package queue_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// expiredReceiptClient fails the visibility changes like an expired receipt handle.
+type expiredReceiptClient struct {
+	*fakeClient
+}
+
+func (client *expiredReceiptClient) ChangeMessageVisibilityWithContext(ctx aws.Context, input *sqs.ChangeMessageVisibilityInput, opts ...request.Option) (*sqs.ChangeMessageVisibilityOutput, error) {
+	return nil, awserr.New(sqs.ErrCodeReceiptHandleIsInvalid, "The receipt handle has expired", nil)
+}
+
+func TestChangeMessageVisibility(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("visibility", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := &sqs.Message{ReceiptHandle: aws.String("receipt")}
+	if err := q.ChangeMessageVisibility(message, 5*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.ChangeMessageVisibilityByReceiptHandle(aws.String("other"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*sqs.ChangeMessageVisibilityInput{
+		{QueueUrl: aws.String(q.URL), ReceiptHandle: aws.String("receipt"), VisibilityTimeout: aws.Int64(300)},
+		{QueueUrl: aws.String(q.URL), ReceiptHandle: aws.String("other"), VisibilityTimeout: aws.Int64(0)},
+	}
+	if !reflect.DeepEqual(client.visibilityInputs, expected) {
+		t.Errorf("expected the inputs %v, got %v", expected, client.visibilityInputs)
+	}
+}
+
+func TestChangeMessageVisibilityInvalidTimeout(t *testing.T) {
+	client := newFakeClient()
+	q, err := queue.New("visibility", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := &sqs.Message{ReceiptHandle: aws.String("receipt")}
+	for _, timeout := range []time.Duration{-time.Second, 12*time.Hour + time.Second} {
+		if err := q.ChangeMessageVisibility(message, timeout); err != queue.ErrInvalidVisibilityTimeout {
+			t.Errorf("expected ErrInvalidVisibilityTimeout for %s, got %v", timeout, err)
+		}
+	}
+	if err := q.ChangeMessageVisibility(message, 12*time.Hour); err != nil {
+		t.Errorf("expected the maximum of 12 hours to be accepted, got %v", err)
+	}
+	if len(client.visibilityInputs) != 1 {
+		t.Errorf("expected only the valid timeout to be sent, got %d calls", len(client.visibilityInputs))
+	}
+}
+
+func TestChangeMessageVisibilityExpiredReceiptHandle(t *testing.T) {
+	q, err := queue.New("visibility", queue.WithClient(&expiredReceiptClient{fakeClient: newFakeClient()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = q.ChangeMessageVisibility(&sqs.Message{ReceiptHandle: aws.String("expired")}, time.Minute)
+	aerr, ok := err.(awserr.Error)
+	if !ok || aerr.Code() != sqs.ErrCodeReceiptHandleIsInvalid {
+		t.Fatalf("expected the AWS error, got %v", err)
+	}
+	if !queue.IsReceiptHandleInvalid(err) {
+		t.Error("expected IsReceiptHandleInvalid to report the expired receipt handle")
+	}
+}
@@ -0,0 +1,127 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrInvalidWatchInterval is returned by WatchDepth for intervals that are not positive.
+var ErrInvalidWatchInterval = errors.New("watch interval must be positive")
+
+// Backoff limit of WatchDepth after repeated fetch errors, as a multiple of the interval.
+const maxWatchBackoffFactor = 16
+
+// A WatchOption configures WatchDepth.
+type WatchOption func(watcher *depthWatcher)
+
+// depthWatcher is the configuration and state of WatchDepth.
+type depthWatcher struct {
+	thresholds []*depthThreshold
+	onError    func(err error)
+}
+
+// depthThreshold calls fn when the count returned by count goes above n.
+type depthThreshold struct {
+	n     int64
+	count func(stats QueueStats) int64
+	fn    func()
+	above bool
+}
+
+// OnThreshold calls fn when the visible messages of the queue go above n, once until they drop to n or below again.
+func OnThreshold(n int64, fn func()) WatchOption {
+	return func(watcher *depthWatcher) {
+		watcher.thresholds = append(watcher.thresholds, &depthThreshold{
+			n:     n,
+			count: func(stats QueueStats) int64 { return stats.ApproximateNumberOfMessages },
+			fn:    fn,
+		})
+	}
+}
+
+// OnDeadLetterThreshold calls fn when the visible messages of the dead letter queue go above n,
+// e.g. OnDeadLetterThreshold(0, alert) alerts on the first dead letter.
+func OnDeadLetterThreshold(n int64, fn func()) WatchOption {
+	return func(watcher *depthWatcher) {
+		watcher.thresholds = append(watcher.thresholds, &depthThreshold{
+			n:     n,
+			count: func(stats QueueStats) int64 { return stats.DeadLetterApproximateNumberOfMessages },
+			fn:    fn,
+		})
+	}
+}
+
+// OnWatchError calls fn with the errors of fetching the queue depth. Without it they are logged.
+func OnWatchError(fn func(err error)) WatchOption {
+	return func(watcher *depthWatcher) {
+		watcher.onError = fn
+	}
+}
+
+// WatchDepth fetches the Stats of the queue and it's dead letter queue every interval and calls fn with them,
+// until the context is done, then returns the context error. Fetch errors don't stop the watcher, it backs off
+// doubling the interval, up to 16 times. The fetches are cancelled with the context, and their errors are not reported then.
+// ErrInvalidWatchInterval is returned immediately when the interval is not positive.
+func (queue *Queue) WatchDepth(ctx context.Context, interval time.Duration, fn func(stats QueueStats), opts ...WatchOption) error {
+	if interval <= 0 {
+		return ErrInvalidWatchInterval
+	}
+	watcher := &depthWatcher{}
+	for _, opt := range opts {
+		opt(watcher)
+	}
+
+	failures := 0
+	for {
+		stats, err := queue.StatsContext(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			failures++
+			queue.reportWatchError(watcher, err, failures)
+		} else {
+			failures = 0
+			if fn != nil {
+				fn(stats)
+			}
+			watcher.checkThresholds(stats)
+		}
+
+		wait := interval
+		for i := 0; i < failures && wait < interval*maxWatchBackoffFactor; i++ {
+			wait *= 2
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// reportWatchError passes the fetch error to the error hook of the watcher, or logs it.
+func (queue *Queue) reportWatchError(watcher *depthWatcher, err error, failures int) {
+	if watcher.onError != nil {
+		watcher.onError(err)
+		return
+	}
+
+	queue.GetLogger().Warn("Fetching the queue depth", Fields{
+		"queueName": queue.Name,
+		"failures":  failures,
+		"error":     err,
+	})
+}
+
+// checkThresholds calls the thresholds crossed since the previous stats.
+func (watcher *depthWatcher) checkThresholds(stats QueueStats) {
+	for _, threshold := range watcher.thresholds {
+		above := threshold.count(stats) > threshold.n
+		if above && !threshold.above {
+			threshold.fn()
+		}
+		threshold.above = above
+	}
+}
@@ -0,0 +1,152 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	queue "github.com/Indivizo/sqs"
+	"github.com/Indivizo/sqs/memqueue"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+// attributesClient fails the queue attribute calls while failing is set, and blocks them until the context is done while blocking is.
+type attributesClient struct {
+	*memqueue.Client
+	failing  atomic.Bool
+	blocking atomic.Bool
+}
+
+func (client *attributesClient) GetQueueAttributesWithContext(ctx aws.Context, input *sqs.GetQueueAttributesInput, opts ...request.Option) (*sqs.GetQueueAttributesOutput, error) {
+	if client.blocking.Load() {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if client.failing.Load() {
+		return nil, errors.New("throttled")
+	}
+
+	return client.Client.GetQueueAttributesWithContext(ctx, input, opts...)
+}
+
+func TestWatchDepthInvalidInterval(t *testing.T) {
+	q, err := memqueue.New("watched")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := q.WatchDepth(context.Background(), interval, nil); err != queue.ErrInvalidWatchInterval {
+			t.Errorf("interval %s: expected ErrInvalidWatchInterval, got %v", interval, err)
+		}
+	}
+}
+
+func TestWatchDepthThresholds(t *testing.T) {
+	q, err := memqueue.New("watched", queue.WithReceiveWaitTime(0), queue.WithReceiveVisibilityTimeout(0), queue.WithMaxReceiveCount(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.SendMessage("body"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stats := make(chan queue.QueueStats, 100)
+	var aboveOne, deadLetters atomic.Int64
+	done := make(chan error, 1)
+	go func() {
+		done <- q.WatchDepth(ctx, 10*time.Millisecond, func(s queue.QueueStats) { stats <- s },
+			queue.OnThreshold(0, func() { aboveOne.Add(1) }),
+			queue.OnDeadLetterThreshold(0, func() { deadLetters.Add(1) }),
+		)
+	}()
+
+	if first := <-stats; first.ApproximateNumberOfMessages != 1 || first.DeadLetterApproximateNumberOfMessages != 0 {
+		t.Fatalf("expected one message in the queue, got %+v", first)
+	}
+	deadLetter(t, q)
+	deadline := time.After(time.Second)
+	for {
+		var s queue.QueueStats
+		select {
+		case s = <-stats:
+		case <-deadline:
+			t.Fatal("the dead letter was not reported")
+		}
+		if s.DeadLetterApproximateNumberOfMessages == 1 {
+			break
+		}
+	}
+	<-stats
+
+	if aboveOne.Load() != 1 {
+		t.Errorf("expected the queue threshold to be called once, got %d", aboveOne.Load())
+	}
+	if deadLetters.Load() != 1 {
+		t.Errorf("expected the dead letter threshold to be called once, got %d", deadLetters.Load())
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWatchDepthErrors(t *testing.T) {
+	client := &attributesClient{Client: memqueue.NewClient()}
+	q, err := queue.New("watched", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.failing.Store(true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errs := make(chan error, 100)
+	stats := make(chan queue.QueueStats, 100)
+	go q.WatchDepth(ctx, 5*time.Millisecond, func(s queue.QueueStats) { stats <- s }, queue.OnWatchError(func(err error) { errs <- err }))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-errs:
+		case <-time.After(time.Second):
+			t.Fatalf("expected the watcher to keep fetching after %d errors", i)
+		}
+	}
+	client.failing.Store(false)
+	select {
+	case <-stats:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the watcher to recover")
+	}
+}
+
+func TestWatchDepthCancelsFetch(t *testing.T) {
+	client := &attributesClient{Client: memqueue.NewClient()}
+	q, err := queue.New("watched", queue.WithClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.blocking.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	reported := false
+	started := time.Now()
+	err = q.WatchDepth(ctx, time.Minute, nil, queue.OnWatchError(func(err error) { reported = true }))
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("expected the fetch to be cancelled, took %s", elapsed)
+	}
+	if reported {
+		t.Error("expected the cancelled fetch not to be reported")
+	}
+}